@@ -3,13 +3,14 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
-	"math"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -17,9 +18,11 @@ import (
 
 	"github.com/switchboard-xyz/defillama-extract/internal/aggregator"
 	"github.com/switchboard-xyz/defillama-extract/internal/api"
+	"github.com/switchboard-xyz/defillama-extract/internal/chainregistry"
 	"github.com/switchboard-xyz/defillama-extract/internal/config"
 	"github.com/switchboard-xyz/defillama-extract/internal/logging"
 	"github.com/switchboard-xyz/defillama-extract/internal/models"
+	"github.com/switchboard-xyz/defillama-extract/internal/quality"
 	"github.com/switchboard-xyz/defillama-extract/internal/storage"
 	"github.com/switchboard-xyz/defillama-extract/internal/tvl"
 )
@@ -31,6 +34,8 @@ type CLIOptions struct {
 	ConfigPath string
 	DryRun     bool
 	Version    bool
+	RecordDir  string
+	ReplayDir  string
 }
 
 // ParseCLI parses command-line flags into CLIOptions using the stdlib flag package.
@@ -45,11 +50,29 @@ func ParseCLI(args []string) (CLIOptions, string, error) {
 	fs.StringVar(&opts.ConfigPath, "config", "config.yaml", "Path to config file")
 	fs.BoolVar(&opts.DryRun, "dry-run", false, "Fetch and process but do not write files")
 	fs.BoolVar(&opts.Version, "version", false, "Print version and exit")
+	fs.StringVar(&opts.RecordDir, "record", "", "Save raw DefiLlama responses into this cassette directory")
+	fs.StringVar(&opts.ReplayDir, "replay", "", "Serve DefiLlama responses only from this cassette directory (no network)")
 
 	err := fs.Parse(args)
+	if err == nil && opts.RecordDir != "" && opts.ReplayDir != "" {
+		err = errors.New("--record and --replay are mutually exclusive")
+		fs.Usage()
+	}
 	return opts, strings.TrimSpace(usage.String()), err
 }
 
+// applyCLIOverrides copies CLI options that shadow configuration values into cfg.
+func applyCLIOverrides(cfg *config.Config, opts CLIOptions) {
+	if opts.RecordDir != "" {
+		cfg.API.RecordDir = opts.RecordDir
+		cfg.API.ReplayDir = ""
+	}
+	if opts.ReplayDir != "" {
+		cfg.API.ReplayDir = opts.ReplayDir
+		cfg.API.RecordDir = ""
+	}
+}
+
 type apiClient interface {
 	FetchAll(ctx context.Context) (*api.FetchResult, error)
 }
@@ -70,6 +93,7 @@ type stateManager interface {
 type runDeps struct {
 	client          apiClient
 	tvlClient       tvl.TVLClient
+	chainSource     api.ChainTVLSource
 	agg             aggregationPipeline
 	sm              stateManager
 	generateFull    func(*aggregator.AggregationResult, []aggregator.Snapshot, []aggregator.ChartDataPoint, *config.Config) *models.FullOutput
@@ -78,14 +102,378 @@ type runDeps struct {
 	now             func() time.Time
 	logger          *slog.Logger
 	tvlRunner       func(context.Context, *config.Config, []api.Protocol, time.Time, CLIOptions, tvl.TVLClient, *slog.Logger) error
+	// chains canonicalizes chain names in stored history and collects the
+	// names it does not recognize; it should be the registry agg uses.
+	chains *chainregistry.Registry
+}
+
+// cacheDataSource marks outputs built from the local response cache.
+const cacheDataSource = "cache"
+
+// applyCacheProvenance records in output metadata that cached data was used
+// and how old the oldest cached dataset was.
+func applyCacheProvenance(full *models.FullOutput, summary *models.SummaryOutput, entries []api.CacheEntry) {
+	var oldest time.Duration
+	for _, e := range entries {
+		if e.Age > oldest {
+			oldest = e.Age
+		}
+	}
+	age := int64(oldest.Seconds())
+
+	if full != nil {
+		full.Metadata.DataSource = cacheDataSource
+		full.Metadata.CacheAgeSeconds = age
+	}
+	if summary != nil {
+		summary.Metadata.DataSource = cacheDataSource
+		summary.Metadata.CacheAgeSeconds = age
+	}
+}
+
+// fetchChainTVL returns the current total TVL of each chain in chains and its
+// daily history, keyed by the chain names given. Chains are matched to
+// DefiLlama's chain list case-insensitively, and the current total is added as
+// a point at ts so share series reach the latest aggregation. Failures are
+// logged and leave the affected chains out.
+func fetchChainTVL(ctx context.Context, source api.ChainTVLSource, chains []string, ts int64, logger *slog.Logger) (map[string]float64, map[string][]api.ChainTVLPoint) {
+	listing, err := source.FetchChains(ctx)
+	if err != nil {
+		logger.Warn("chain_tvl_fetch_failed", "error", err)
+		return nil, nil
+	}
+
+	byName := make(map[string]api.ChainInfo, len(listing))
+	for _, info := range listing {
+		byName[strings.ToLower(info.Name)] = info
+	}
+
+	current := make(map[string]float64, len(chains))
+	series := make(map[string][]api.ChainTVLPoint, len(chains))
+	for _, chain := range chains {
+		info, ok := byName[strings.ToLower(chain)]
+		if !ok {
+			logger.Debug("chain_tvl_not_listed", "chain", chain)
+			continue
+		}
+		current[chain] = info.TVL
+
+		points, err := source.FetchHistoricalChainTVL(ctx, info.Name)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			logger.Warn("chain_tvl_history_fetch_failed", "chain", chain, "error", err)
+		}
+		series[chain] = append(points, api.ChainTVLPoint{Date: ts, TVL: info.TVL})
+	}
+
+	return current, series
+}
+
+// logUnrecognizedChains warns about chain names missing from the chain
+// registry; they are grouped as reported and may split one chain in two.
+func logUnrecognizedChains(logger *slog.Logger, chains *chainregistry.Registry) {
+	if names := chains.Unrecognized(); len(names) > 0 {
+		logger.Warn("chains_unrecognized", "count", len(names), "chains", names)
+	}
+}
+
+// logUnmappedCategories warns about categories the taxonomy does not map;
+// their protocols fall back to the raw category in the mapped breakdown.
+func logUnmappedCategories(logger *slog.Logger, result *aggregator.AggregationResult) {
+	if result == nil || len(result.UnmappedCategories) == 0 {
+		return
+	}
+	names := make([]string, 0, len(result.UnmappedCategories))
+	for _, u := range result.UnmappedCategories {
+		names = append(names, u.Category)
+	}
+	logger.Warn("categories_unmapped", "count", len(names), "categories", names)
+}
+
+// logProtocolMatches warns about protocols left without TVS because no
+// oraclesTVS key, or several, matched them, and about the TVS under keys no
+// protocol matched.
+func logProtocolMatches(logger *slog.Logger, result *aggregator.AggregationResult) {
+	if result == nil || result.ProtocolMatches == nil {
+		return
+	}
+	report := result.ProtocolMatches
+	if len(report.Unmatched) == 0 && len(report.Ambiguous) == 0 {
+		return
+	}
+	var unclaimedTVS float64
+	for _, k := range report.UnclaimedKeys {
+		unclaimedTVS += k.TVS
+	}
+	logger.Warn("protocols_unresolved",
+		"unmatched", len(report.Unmatched),
+		"ambiguous", len(report.Ambiguous),
+		"unclaimed_keys", len(report.UnclaimedKeys),
+		"unclaimed_tvs", unclaimedTVS,
+	)
+}
+
+// logAnomalies warns about each TVS move flagged this cycle, noting whether
+// the previous value was published in its place.
+func logAnomalies(logger *slog.Logger, result *aggregator.AggregationResult) {
+	if result == nil {
+		return
+	}
+	for _, a := range result.Anomalies {
+		logger.Warn("tvs_anomaly",
+			"scope", a.Scope,
+			"protocol", a.Slug,
+			"value", a.Value,
+			"previous", a.Previous,
+			"change_pct", a.ChangePct,
+			"score", a.Score,
+			"quarantined", a.Quarantined,
+			"cycles", a.Cycles,
+		)
+	}
+}
+
+// recordQuality writes the quality report and, when the cycle will not
+// publish, persists the evaluated TVS and timestamp without advancing
+// LastUpdated. The next cycle still reprocesses the same data, and a move that
+// newer upstream data shows again is not held back by the last published
+// snapshot.
+func recordQuality(sm stateManager, cfg *config.Config, report *quality.Report, state *storage.State, tvs float64) error {
+	if cfg.Output.QualityReportFile != "" {
+		path := filepath.Join(cfg.Output.Directory, cfg.Output.QualityReportFile)
+		if err := storage.WriteJSON(path, report, true); err != nil {
+			return err
+		}
+	}
+	if report.Outcome == quality.OutcomePublish {
+		return nil
+	}
+	evaluated := *state
+	evaluated.LastEvaluatedTVS = tvs
+	evaluated.LastEvaluatedAt = report.Timestamp
+	return sm.SaveState(&evaluated)
+}
+
+// logQualityReport logs each data-quality rule result, warning on failures.
+func logQualityReport(logger *slog.Logger, report *quality.Report) {
+	if report == nil {
+		return
+	}
+	for _, r := range report.Results {
+		level := slog.LevelInfo
+		if r.Status == quality.StatusFail {
+			level = slog.LevelWarn
+		}
+		logger.Log(context.Background(), level, "quality_rule",
+			"rule", r.Rule,
+			"status", r.Status,
+			"severity", r.Severity,
+			"value", r.Value,
+			"threshold", r.Threshold,
+			"message", r.Message,
+		)
+	}
+}
+
+// ruleNames joins the rule names of results for logs and errors.
+func ruleNames(results []quality.Result) string {
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = r.Rule
+	}
+	return strings.Join(names, ",")
+}
+
+// logLandscape logs the configured oracle's overall standing and its 7d rank
+// movement, or that it secures no value in the landscape.
+func logLandscape(logger *slog.Logger, landscape *aggregator.OracleLandscape) {
+	if landscape == nil {
+		return
+	}
+	if landscape.Position == nil {
+		logger.Warn("oracle_landscape_position_missing", "oracle", landscape.Oracle, "oracles", len(landscape.Overall))
+		return
+	}
+
+	attrs := []any{
+		"rank", landscape.Position.Rank,
+		"oracles", len(landscape.Overall),
+		"share", landscape.Position.Share,
+	}
+	for _, m := range landscape.Movement {
+		if m.Period == "7d" {
+			attrs = append(attrs, "rank_change_7d", m.RankChange, "share_change_7d", m.ShareChange)
+		}
+	}
+	logger.Info("oracle_landscape", attrs...)
+}
+
+// fetchReporter is implemented by sources that record per-endpoint request
+// metrics.
+type fetchReporter interface {
+	FetchReport() *api.FetchReport
+}
+
+// logFetchReport logs one fetch_report line per endpoint class requested by
+// the sources used this cycle, so slow cycles can be traced to a phase.
+func logFetchReport(logger *slog.Logger, sources ...any) {
+	seen := make(map[fetchReporter]bool, len(sources))
+	for _, source := range sources {
+		reporter, ok := source.(fetchReporter)
+		if !ok || seen[reporter] {
+			continue
+		}
+		seen[reporter] = true
+
+		report := reporter.FetchReport()
+		if report == nil {
+			continue
+		}
+		for _, e := range report.Endpoints {
+			logger.Info("fetch_report",
+				"endpoint", e.Endpoint,
+				"requests", e.Requests,
+				"errors", e.Errors,
+				"retries", e.Retries,
+				"cache_fallbacks", e.CacheFallbacks,
+				"bytes_received", e.BytesReceived,
+				"p50_ms", e.P50Ms,
+				"p95_ms", e.P95Ms,
+				"avg_dns_ms", e.AvgDNSMs,
+				"avg_connect_ms", e.AvgConnectMs,
+				"avg_tls_ms", e.AvgTLSMs,
+				"avg_ttfb_ms", e.AvgTTFBMs,
+				"avg_body_read_ms", e.AvgBodyReadMs,
+				"avg_decode_ms", e.AvgDecodeMs,
+			)
+		}
+	}
+}
+
+// schemaDriftReporter is implemented by sources that check upstream payloads
+// against the schema manifest.
+type schemaDriftReporter interface {
+	SchemaDrift() *api.SchemaDriftReport
+}
+
+// errSchemaDrift fails a cycle in strict schema mode.
+var errSchemaDrift = errors.New("upstream schema drift detected")
+
+// schemaDriftTracker merges the drift reports seen during one cycle (the
+// main fetch and, with a separate source, the TVL fetch) so the report file
+// always covers every dataset checked so far.
+type schemaDriftTracker struct {
+	cfg    *config.Config
+	opts   CLIOptions
+	report *api.SchemaDriftReport
+	logged map[string]bool
+}
+
+func newSchemaDriftTracker(cfg *config.Config, opts CLIOptions) *schemaDriftTracker {
+	return &schemaDriftTracker{cfg: cfg, opts: opts, logged: map[string]bool{}}
+}
+
+// check merges report, writes the result to the configured drift report file
+// and logs datasets not yet logged this cycle. In strict mode it returns
+// errSchemaDrift when one of them lost or retyped a field. Added fields are
+// logged at info level and never fail the cycle.
+func (t *schemaDriftTracker) check(report *api.SchemaDriftReport, logger *slog.Logger) error {
+	if report == nil || t.cfg == nil || !t.cfg.API.Schema.Enabled {
+		return nil
+	}
+	t.merge(report)
+
+	if !t.opts.DryRun {
+		path := filepath.Join(t.cfg.Output.Directory, t.cfg.API.Schema.ReportFile)
+		if err := storage.WriteJSON(path, t.report, true); err != nil {
+			logger.Warn("schema_drift_report_write_failed", "path", path, "error", err)
+		}
+	}
+
+	breaking := false
+	for _, d := range report.Datasets {
+		if t.logged[d.Dataset] {
+			continue
+		}
+		t.logged[d.Dataset] = true
+
+		switch {
+		case d.Breaking():
+			breaking = true
+			logger.Warn("schema_drift_detected",
+				"dataset", d.Dataset,
+				"samples", d.Samples,
+				"removed", driftFields(d.Removed),
+				"type_changed", driftFields(d.TypeChanged),
+				"added", driftFields(d.Added),
+			)
+		case len(d.Added) > 0:
+			logger.Info("schema_fields_added",
+				"dataset", d.Dataset,
+				"samples", d.Samples,
+				"added", driftFields(d.Added),
+			)
+		}
+	}
+
+	if breaking && t.cfg.API.Schema.Strict {
+		return errSchemaDrift
+	}
+	return nil
+}
+
+// merge folds report into the cycle report; later observations of a dataset
+// replace earlier ones.
+func (t *schemaDriftTracker) merge(report *api.SchemaDriftReport) {
+	if t.report == nil {
+		t.report = &api.SchemaDriftReport{Datasets: []api.DatasetDrift{}}
+	}
+	t.report.GeneratedAt = report.GeneratedAt
+
+	for _, d := range report.Datasets {
+		replaced := false
+		for i := range t.report.Datasets {
+			if t.report.Datasets[i].Dataset == d.Dataset {
+				t.report.Datasets[i] = d
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			t.report.Datasets = append(t.report.Datasets, d)
+		}
+	}
+}
+
+func driftFields(fields []api.FieldDrift) []string {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.Field)
+	}
+	return names
 }
 
 // RunOnce executes a single extraction cycle according to Story 5.2.
 func RunOnce(ctx context.Context, cfg *config.Config, opts CLIOptions, logger *slog.Logger) error {
+	if cfg.MultiOracle() {
+		return runMultiOracle(ctx, cfg, opts, logger)
+	}
+
+	source, err := api.NewSource(cfg.Source, &cfg.API, []string{cfg.Oracle.Name}, logger)
+	if err != nil {
+		return fmt.Errorf("create data source: %w", err)
+	}
+	if closer, ok := source.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	chains := chainregistry.New(cfg.Chains.Registry, logger)
 	deps := runDeps{
-		client:          api.NewClient(&cfg.API, logger),
+		client:          source,
 		tvlClient:       nil,
-		agg:             aggregator.NewAggregator(cfg.Oracle.Name),
+		agg:             newAggregator(cfg, cfg.Oracle.Name, chains),
 		sm:              storage.NewStateManager(cfg.Output.Directory, logger),
 		generateFull:    storage.GenerateFullOutput,
 		generateSummary: storage.GenerateSummaryOutput,
@@ -93,11 +481,86 @@ func RunOnce(ctx context.Context, cfg *config.Config, opts CLIOptions, logger *s
 		now:             time.Now,
 		logger:          logger,
 		tvlRunner:       nil,
+		chains:          chains,
 	}
 
 	return runOnceWithDeps(ctx, cfg, opts, deps)
 }
 
+// newAggregator builds the aggregator for oracleName from cfg's TVS policy,
+// protocol aliases, category taxonomy and anomaly settings, grouping chains
+// with chains.
+func newAggregator(cfg *config.Config, oracleName string, chains *chainregistry.Registry) *aggregator.Aggregator {
+	opts := aggregator.Options{
+		TVSPolicy:       aggregator.NewTVSPolicy(cfg.Aggregation.TVSPolicy),
+		Chains:          chains,
+		Categories:      aggregator.NewCategoryTaxonomy(cfg.Categories.Mapping, cfg.Categories.Overrides),
+		ProtocolAliases: cfg.Aggregation.ProtocolAliases,
+	}
+	if a := cfg.Anomalies; a.Enabled {
+		opts.Anomalies = &aggregator.AnomalyOptions{
+			Method:       a.Method,
+			Window:       a.Window,
+			Threshold:    a.Threshold,
+			MinChangePct: a.MinChangePct,
+		}
+		if a.Quarantine {
+			opts.Anomalies.QuarantineCycles = a.QuarantineCycles
+		}
+	}
+	return aggregator.NewAggregator(oracleName, opts)
+}
+
+// runMultiOracle runs one extraction cycle per configured oracle profile over
+// a single shared fetch. Each oracle aggregates, writes outputs and keeps state
+// in its own subdirectory; a failing oracle does not stop the others.
+func runMultiOracle(ctx context.Context, cfg *config.Config, opts CLIOptions, logger *slog.Logger) error {
+	profiles := cfg.OracleProfiles()
+	names := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		names = append(names, p.Name)
+	}
+
+	source, err := api.NewSource(cfg.Source, &cfg.API, names, logger)
+	if err != nil {
+		return fmt.Errorf("create data source: %w", err)
+	}
+	if closer, ok := source.(io.Closer); ok {
+		defer closer.Close()
+	}
+	shared := api.NewSharedSource(source)
+
+	var errs []error
+	for _, p := range profiles {
+		oracleCfg := cfg.ForOracle(p)
+		oracleLogger := logger.With("oracle", p.Name)
+		chains := chainregistry.New(oracleCfg.Chains.Registry, oracleLogger)
+
+		deps := runDeps{
+			client:          shared,
+			agg:             newAggregator(oracleCfg, p.Name, chains),
+			sm:              storage.NewStateManager(oracleCfg.Output.Directory, oracleLogger),
+			generateFull:    storage.GenerateFullOutput,
+			generateSummary: storage.GenerateSummaryOutput,
+			writeOutputs:    storage.WriteAllOutputs,
+			now:             time.Now,
+			logger:          oracleLogger,
+			chains:          chains,
+		}
+		if err := runOnceWithDeps(ctx, oracleCfg, opts, deps); err != nil {
+			errs = append(errs, fmt.Errorf("oracle %s: %w", p.Name, err))
+			if ctx != nil && ctx.Err() != nil {
+				break
+			}
+		}
+	}
+
+	logFetchReport(logger, source)
+	logger.Info("multi_oracle_cycle_complete", "oracles", len(profiles), "failed", len(errs))
+
+	return errors.Join(errs...)
+}
+
 func runOnceWithDeps(ctx context.Context, cfg *config.Config, opts CLIOptions, d runDeps) error {
 	if ctx == nil {
 		ctx = context.Background()
@@ -110,6 +573,7 @@ func runOnceWithDeps(ctx context.Context, cfg *config.Config, opts CLIOptions, d
 
 	mainLogger := logger.With("pipeline", "main")
 	tvlLogger := logger.With("pipeline", "tvl")
+	tvsPolicy := aggregator.NewTVSPolicy(cfg.Aggregation.TVSPolicy)
 
 	checkCtx := func(stage string) error {
 		if err := ctx.Err(); err != nil {
@@ -131,8 +595,19 @@ func runOnceWithDeps(ctx context.Context, cfg *config.Config, opts CLIOptions, d
 		mainStatus = "success"
 		protocols  []api.Protocol
 		aggResult  *aggregator.AggregationResult
+
+		upstreamUnchanged bool
+		drift             = newSchemaDriftTracker(cfg, opts)
+		chainSource       api.ChainTVLSource
 	)
 
+	if cfg != nil && cfg.Aggregation.ChainShare {
+		chainSource = d.chainSource
+		if chainSource == nil {
+			chainSource, _ = d.client.(api.ChainTVLSource)
+		}
+	}
+
 	for {
 		state, err := d.sm.LoadState()
 		if err != nil {
@@ -150,12 +625,31 @@ func runOnceWithDeps(ctx context.Context, cfg *config.Config, opts CLIOptions, d
 
 		result, err := d.client.FetchAll(ctx)
 		if err != nil {
+			// A retyped field often surfaces as a decode error; still report
+			// what the source saw so the failure can be traced to drift.
+			if reporter, ok := d.client.(schemaDriftReporter); ok {
+				_ = drift.check(reporter.SchemaDrift(), mainLogger)
+			}
 			mainLogger.Error("extraction failed", "error", err, "duration_ms", d.now().Sub(start).Milliseconds())
 			mainErr = err
 			mainStatus = "failed"
 			break
 		}
 		protocols = result.Protocols
+		upstreamUnchanged = result.OraclesUnchanged && result.ProtocolsUnchanged
+		if result.OraclesUnchanged || result.ProtocolsUnchanged {
+			mainLogger.Info("upstream_unchanged",
+				"oracles_unchanged", result.OraclesUnchanged,
+				"protocols_unchanged", result.ProtocolsUnchanged,
+			)
+		}
+
+		if err := drift.check(result.SchemaDrift, mainLogger); err != nil {
+			mainLogger.Error("extraction failed", "error", err, "duration_ms", d.now().Sub(start).Milliseconds())
+			mainErr = err
+			mainStatus = "failed"
+			break
+		}
 
 		if err := checkCtx("after_fetch"); err != nil {
 			mainErr = err
@@ -177,48 +671,14 @@ func runOnceWithDeps(ctx context.Context, cfg *config.Config, opts CLIOptions, d
 			break
 		}
 
-		chartHistory := aggregator.ExtractChartHistory(result.OracleResponse, cfg.Oracle.Name)
+		chartHistory := aggregator.ExtractChartHistory(result.OracleResponse, cfg.Oracle.Name, tvsPolicy)
 
+		aggregator.CanonicalizeSnapshots(history, d.chains)
 		aggResult = d.agg.Aggregate(ctx, result.OracleResponse, result.Protocols, history)
-
-		// AC3: Validate protocol TVS sum vs independent reference total_value_secured (5% tolerance)
-		if aggResult != nil {
-			var protocolSum float64
-			for _, p := range aggResult.Protocols {
-				protocolSum += p.TVS
-			}
-
-			var referenceTVS float64
-			if n := len(chartHistory); n > 0 {
-				referenceTVS = chartHistory[n-1].TVS
-			}
-
-			switch {
-			case referenceTVS <= 0:
-				mainLogger.Info("tvs_sum_validation_skipped",
-					"reason", "no reference total_value_secured",
-					"protocols_with_tvs", aggResult.ProtocolsWithTVS,
-					"protocols_without_tvs", aggResult.ProtocolsWithoutTVS,
-				)
-			default:
-				diffPct := math.Abs(protocolSum-referenceTVS) / referenceTVS * 100
-				if diffPct > 5 {
-					mainLogger.Warn("tvs_sum_validation",
-						"status", "fail",
-						"protocols_total_tvs", protocolSum,
-						"reference_total_value_secured", referenceTVS,
-						"pct_diff", diffPct,
-					)
-				} else {
-					mainLogger.Info("tvs_sum_validation",
-						"status", "pass",
-						"protocols_total_tvs", protocolSum,
-						"reference_total_value_secured", referenceTVS,
-						"pct_diff", diffPct,
-					)
-				}
-			}
-		}
+		logUnrecognizedChains(mainLogger, d.chains)
+		logUnmappedCategories(mainLogger, aggResult)
+		logProtocolMatches(mainLogger, aggResult)
+		logAnomalies(mainLogger, aggResult)
 
 		if aggResult == nil {
 			mainErr = fmt.Errorf("nil aggregation result")
@@ -230,19 +690,73 @@ func runOnceWithDeps(ctx context.Context, cfg *config.Config, opts CLIOptions, d
 			mainLogger.Info("no new data, skipping extraction",
 				"last_updated", state.LastUpdated,
 				"current_ts", aggResult.Timestamp,
+				"upstream_unchanged", upstreamUnchanged,
 			)
 			mainStatus = "skipped"
 			break
 		}
 
+		var chainTVLHistory map[string][]api.ChainTVLPoint
+		if chainSource != nil {
+			var chainTVL map[string]float64
+			chainTVL, chainTVLHistory = fetchChainTVL(ctx, chainSource, aggResult.ActiveChains, aggResult.Timestamp, mainLogger)
+			aggregator.ApplyChainShare(aggResult.ChainBreakdown, chainTVL)
+		}
+
 		if err := checkCtx("before_snapshot"); err != nil {
 			mainErr = err
 			mainStatus = "failed"
 			break
 		}
 
+		var landscape *aggregator.OracleLandscape
+		if cfg.Aggregation.Landscape {
+			landscape = aggregator.CalculateOracleLandscape(result.OracleResponse, result.ProtocolIndex, cfg.Oracle.Name, tvsPolicy)
+			logLandscape(mainLogger, landscape)
+		}
+
 		snapshot := storage.CreateSnapshot(aggResult)
 		history = d.sm.AppendSnapshot(history, snapshot)
+		aggResult.ChainShareHistory = aggregator.CalculateChainShareHistory(history, chainTVLHistory)
+		aggResult.Analytics = aggregator.CalculateAnalytics(chartHistory)
+
+		var referenceTVS float64
+		if n := len(chartHistory); n > 0 {
+			referenceTVS = chartHistory[n-1].TVS
+		}
+		qualityReport := quality.Evaluate(cfg.Quality, quality.Input{
+			Result:           aggResult,
+			ReferenceTVS:     referenceTVS,
+			History:          history,
+			LastEvaluatedTVS: state.LastEvaluatedTVS,
+			LastEvaluatedAt:  state.LastEvaluatedAt,
+		})
+		logQualityReport(mainLogger, qualityReport)
+
+		if !opts.DryRun {
+			if err := recordQuality(d.sm, cfg, qualityReport, state, aggResult.TotalTVS); err != nil {
+				mainLogger.Error("extraction failed", "error", err, "duration_ms", d.now().Sub(start).Milliseconds())
+				mainErr = err
+				mainStatus = "failed"
+				break
+			}
+		}
+
+		if qualityReport.Failed() {
+			mainErr = fmt.Errorf("data quality check failed: %s", ruleNames(qualityReport.Failures(quality.SeverityFail)))
+			mainLogger.Error("extraction failed", "error", mainErr, "duration_ms", d.now().Sub(start).Milliseconds())
+			mainStatus = "failed"
+			break
+		}
+		if qualityReport.Blocked() {
+			mainLogger.Warn("publish_blocked",
+				"rules", ruleNames(qualityReport.Failures(quality.SeverityBlockPublish)),
+				"timestamp", aggResult.Timestamp,
+				"dry_run", opts.DryRun,
+			)
+			mainStatus = "blocked"
+			break
+		}
 
 		if opts.DryRun {
 			mainLogger.Info("dry-run mode, skipping file writes")
@@ -258,6 +772,13 @@ func runOnceWithDeps(ctx context.Context, cfg *config.Config, opts CLIOptions, d
 
 		full := d.generateFull(aggResult, history, chartHistory, cfg)
 		summary := d.generateSummary(aggResult, cfg)
+		if len(result.CacheEntries) > 0 {
+			applyCacheProvenance(full, summary, result.CacheEntries)
+			mainLogger.Warn("outputs_built_from_cache",
+				"datasets", len(result.CacheEntries),
+				"cache_age_seconds", full.Metadata.CacheAgeSeconds,
+			)
+		}
 
 		if err := checkCtx("after_generate_outputs"); err != nil {
 			mainErr = err
@@ -278,6 +799,36 @@ func runOnceWithDeps(ctx context.Context, cfg *config.Config, opts CLIOptions, d
 			break
 		}
 
+		if landscape != nil {
+			path := filepath.Join(cfg.Output.Directory, cfg.Output.LandscapeFile)
+			if err := storage.WriteJSON(path, landscape, true); err != nil {
+				mainLogger.Error("extraction failed", "error", err, "duration_ms", d.now().Sub(start).Milliseconds())
+				mainErr = err
+				mainStatus = "failed"
+				break
+			}
+		}
+
+		if cfg.Categories.Enabled() {
+			path := filepath.Join(cfg.Output.Directory, cfg.Output.CategoryReportFile)
+			if err := storage.WriteJSON(path, aggregator.NewCategoryReport(aggResult), true); err != nil {
+				mainLogger.Error("extraction failed", "error", err, "duration_ms", d.now().Sub(start).Milliseconds())
+				mainErr = err
+				mainStatus = "failed"
+				break
+			}
+		}
+
+		if cfg.Output.ProtocolMatchReportFile != "" && aggResult.ProtocolMatches != nil {
+			path := filepath.Join(cfg.Output.Directory, cfg.Output.ProtocolMatchReportFile)
+			if err := storage.WriteJSON(path, aggResult.ProtocolMatches, true); err != nil {
+				mainLogger.Error("extraction failed", "error", err, "duration_ms", d.now().Sub(start).Milliseconds())
+				mainErr = err
+				mainStatus = "failed"
+				break
+			}
+		}
+
 		if err := checkCtx("after_write_outputs"); err != nil {
 			mainErr = err
 			mainStatus = "failed"
@@ -324,17 +875,32 @@ func runOnceWithDeps(ctx context.Context, cfg *config.Config, opts CLIOptions, d
 	}
 
 	tvlStatus := "skipped"
-	var tvlErr error
+	var (
+		tvlErr    error
+		tvlClient tvl.TVLClient
+	)
 
 	if cfg != nil && cfg.TVL.Enabled {
-		tvlClient := d.tvlClient
+		tvlClient = d.tvlClient
 		if tvlClient == nil {
 			if c, ok := d.client.(tvl.TVLClient); ok {
 				tvlClient = c
 			}
 		}
 		if tvlClient == nil {
-			tvlClient = api.NewClient(&cfg.API, tvlLogger)
+			source, err := api.NewSource(cfg.Source, &cfg.API, []string{cfg.Oracle.Name}, tvlLogger)
+			if err != nil {
+				tvlErr = fmt.Errorf("create tvl data source: %w", err)
+			} else {
+				if closer, ok := source.(io.Closer); ok {
+					defer closer.Close()
+				}
+				tvlClient = source
+			}
+		}
+
+		if tvlErr == nil && errors.Is(mainErr, errSchemaDrift) {
+			tvlErr = mainErr
 		}
 
 		runner := d.tvlRunner
@@ -347,7 +913,14 @@ func runOnceWithDeps(ctx context.Context, cfg *config.Config, opts CLIOptions, d
 			}
 		}
 
-		tvlErr = runner(ctx, cfg, protocols, start, opts, tvlClient, tvlLogger)
+		if tvlErr == nil {
+			tvlErr = runner(ctx, cfg, protocols, start, opts, tvlClient, tvlLogger)
+		}
+		if reporter, ok := tvlClient.(schemaDriftReporter); ok {
+			if err := drift.check(reporter.SchemaDrift(), tvlLogger); err != nil && tvlErr == nil {
+				tvlErr = err
+			}
+		}
 		if tvlErr != nil {
 			tvlStatus = "failed"
 			mainLogger.Warn("tvl_pipeline_failed", "error", tvlErr)
@@ -358,9 +931,12 @@ func runOnceWithDeps(ctx context.Context, cfg *config.Config, opts CLIOptions, d
 		tvlStatus = "disabled"
 	}
 
+	logFetchReport(logger, d.client, tvlClient)
+
 	logger.Info("extraction_cycle_complete",
 		"main_status", mainStatus,
 		"tvl_status", tvlStatus,
+		"upstream_unchanged", upstreamUnchanged,
 		"duration_ms", d.now().Sub(start).Milliseconds(),
 	)
 
@@ -484,9 +1060,18 @@ func run(args []string, stdout, stderr io.Writer) int {
 		return 1
 	}
 
+	applyCLIOverrides(cfg, opts)
+
 	logger := logging.Setup(cfg.Logging)
 	slog.SetDefault(logger)
 
+	if cfg.API.RecordDir != "" {
+		logger.Info("cassette_recording_enabled", "dir", cfg.API.RecordDir)
+	}
+	if cfg.API.ReplayDir != "" {
+		logger.Info("cassette_replay_enabled", "dir", cfg.API.ReplayDir)
+	}
+
 	if !opts.Once {
 		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer stop()
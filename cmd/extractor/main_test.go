@@ -68,7 +68,7 @@ func (s *stubState) AppendSnapshot(history []aggregator.Snapshot, snapshot aggre
 }
 
 func (s *stubState) UpdateState(oracleName string, ts int64, count int, tvs float64, snapshots []aggregator.Snapshot) *storage.State {
-	return &storage.State{LastUpdated: ts, LastProtocolCount: count, LastTVS: tvs}
+	return &storage.State{LastUpdated: ts, LastProtocolCount: count, LastTVS: tvs, LastEvaluatedTVS: tvs, LastEvaluatedAt: ts}
 }
 
 func (s *stubState) SaveState(state *storage.State) error {
@@ -138,6 +138,35 @@ func TestParseCLIRejectsUnknownFlag(t *testing.T) {
 	}
 }
 
+func TestParseCLIRecordReplay(t *testing.T) {
+	got, _, err := ParseCLI([]string{"--record", "cassettes/today"})
+	if err != nil {
+		t.Fatalf("unexpected error parsing flags: %v", err)
+	}
+	if got.RecordDir != "cassettes/today" || got.ReplayDir != "" {
+		t.Fatalf("unexpected record/replay options: %+v", got)
+	}
+
+	_, usage, err := ParseCLI([]string{"--record", "a", "--replay", "b"})
+	if err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Fatalf("expected mutual exclusion error, got %v", err)
+	}
+	if !strings.Contains(usage, "-replay") {
+		t.Fatalf("expected usage output, got: %s", usage)
+	}
+}
+
+func TestApplyCLIOverridesReplay(t *testing.T) {
+	cfg := baseConfig()
+	cfg.API.RecordDir = "from-config"
+
+	applyCLIOverrides(cfg, CLIOptions{ReplayDir: "cassettes/day"})
+
+	if cfg.API.ReplayDir != "cassettes/day" || cfg.API.RecordDir != "" {
+		t.Fatalf("expected replay to override recording, got %+v", cfg.API)
+	}
+}
+
 func TestRunVersionOutput(t *testing.T) {
 	var out bytes.Buffer
 	code := run([]string{"--version"}, &out, &out)
@@ -203,6 +232,7 @@ func TestRunOnceLogsSumValidationWarning(t *testing.T) {
 	buf := &bytes.Buffer{}
 	logger := newLogger(buf)
 	cfg := baseConfig()
+	cfg.Quality.TVSSumVsReference = config.QualityRule{Enabled: true, Severity: "warn", Threshold: 5}
 
 	oracleResp := &api.OracleAPIResponse{
 		Chart: map[string]map[string]map[string]float64{
@@ -243,7 +273,7 @@ func TestRunOnceLogsSumValidationWarning(t *testing.T) {
 		t.Fatalf("runOnceWithDeps returned error: %v", err)
 	}
 
-	if !strings.Contains(buf.String(), "tvs_sum_validation") || !strings.Contains(buf.String(), "status=fail") {
+	if !strings.Contains(buf.String(), "msg=quality_rule pipeline=main rule=tvs_sum_vs_reference status=fail severity=warn") {
 		t.Fatalf("expected sum validation failure log, got %s", buf.String())
 	}
 }
@@ -252,6 +282,7 @@ func TestRunOnceSkipsSumWarningWithinTolerance(t *testing.T) {
 	buf := &bytes.Buffer{}
 	logger := newLogger(buf)
 	cfg := baseConfig()
+	cfg.Quality.TVSSumVsReference = config.QualityRule{Enabled: true, Severity: "warn", Threshold: 5}
 
 	oracleResp := &api.OracleAPIResponse{
 		Chart: map[string]map[string]map[string]float64{
@@ -292,11 +323,189 @@ func TestRunOnceSkipsSumWarningWithinTolerance(t *testing.T) {
 		t.Fatalf("runOnceWithDeps returned error: %v", err)
 	}
 
-	if !strings.Contains(buf.String(), "tvs_sum_validation") || !strings.Contains(buf.String(), "status=pass") {
+	if !strings.Contains(buf.String(), "msg=quality_rule pipeline=main rule=tvs_sum_vs_reference status=pass") {
 		t.Fatalf("expected sum validation pass log, got %s", buf.String())
 	}
 }
 
+func TestRunOnceQualityGate(t *testing.T) {
+	tests := []struct {
+		name       string
+		severity   string
+		wantErr    bool
+		wantLog    string
+		wantStatus string
+	}{
+		{name: "warn publishes", severity: "warn", wantStatus: "main_status=success"},
+		{name: "block_publish skips writes", severity: "block_publish", wantLog: "msg=publish_blocked pipeline=main rules=min_protocol_count", wantStatus: "main_status=blocked"},
+		{name: "fail errors", severity: "fail", wantErr: true, wantStatus: "main_status=failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			dir := t.TempDir()
+			cfg := baseConfig()
+			cfg.Output.Directory = dir
+			cfg.Output.QualityReportFile = "quality-report.json"
+			cfg.Quality.MinProtocolCount = config.QualityRule{Enabled: true, Severity: tt.severity, Threshold: 5}
+
+			var wrote bool
+			sm := &stubState{state: &storage.State{}, shouldProcess: true}
+			deps := runDeps{
+				client: stubClient{res: &api.FetchResult{OracleResponse: &api.OracleAPIResponse{}, Protocols: []api.Protocol{}}},
+				agg:    stubAgg{result: &aggregator.AggregationResult{Timestamp: 100, TotalProtocols: 2}},
+				sm:     sm,
+				generateFull: func(*aggregator.AggregationResult, []aggregator.Snapshot, []aggregator.ChartDataPoint, *config.Config) *models.FullOutput {
+					return &models.FullOutput{}
+				},
+				generateSummary: func(*aggregator.AggregationResult, *config.Config) *models.SummaryOutput {
+					return &models.SummaryOutput{}
+				},
+				writeOutputs: func(context.Context, string, *config.Config, *models.FullOutput, *models.SummaryOutput) error {
+					wrote = true
+					return nil
+				},
+				now:    func() time.Time { return time.Unix(200, 0) },
+				logger: newLogger(buf),
+			}
+
+			err := runOnceWithDeps(context.Background(), cfg, CLIOptions{}, deps)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runOnceWithDeps error = %v, wantErr %v", err, tt.wantErr)
+			}
+			published := tt.severity == "warn"
+			if wrote != published {
+				t.Errorf("wrote outputs = %v, want %v", wrote, published)
+			}
+			// Unpublished cycles still record what was evaluated but must not
+			// advance LastUpdated, or the data would never be retried.
+			if sm.savedState == nil {
+				t.Fatal("expected state to be saved")
+			}
+			if published != (sm.savedState.LastUpdated == 100) {
+				t.Errorf("saved LastUpdated = %d, published %v", sm.savedState.LastUpdated, published)
+			}
+			if tt.wantLog != "" && !strings.Contains(buf.String(), tt.wantLog) {
+				t.Errorf("expected %q in logs, got %s", tt.wantLog, buf.String())
+			}
+			if !strings.Contains(buf.String(), tt.wantStatus) {
+				t.Errorf("expected %q in logs, got %s", tt.wantStatus, buf.String())
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, "quality-report.json"))
+			if err != nil {
+				t.Fatalf("read quality report: %v", err)
+			}
+			if !strings.Contains(string(data), `"rule": "min_protocol_count"`) || !strings.Contains(string(data), `"status": "fail"`) {
+				t.Errorf("unexpected quality report: %s", data)
+			}
+		})
+	}
+}
+
+func TestRunOnceQualityGateDryRun(t *testing.T) {
+	for _, severity := range []string{"block_publish", "fail"} {
+		t.Run(severity, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			dir := t.TempDir()
+			cfg := baseConfig()
+			cfg.Output.Directory = dir
+			cfg.Output.QualityReportFile = "quality-report.json"
+			cfg.Quality.MinProtocolCount = config.QualityRule{Enabled: true, Severity: severity, Threshold: 5}
+
+			sm := &stubState{state: &storage.State{}, shouldProcess: true}
+			deps := runDeps{
+				client: stubClient{res: &api.FetchResult{OracleResponse: &api.OracleAPIResponse{}, Protocols: []api.Protocol{}}},
+				agg:    stubAgg{result: &aggregator.AggregationResult{Timestamp: 100, TotalProtocols: 2}},
+				sm:     sm,
+				now:    func() time.Time { return time.Unix(200, 0) },
+				logger: newLogger(buf),
+			}
+
+			err := runOnceWithDeps(context.Background(), cfg, CLIOptions{DryRun: true}, deps)
+			if (err != nil) != (severity == "fail") {
+				t.Fatalf("runOnceWithDeps error = %v", err)
+			}
+			want := map[string]string{"block_publish": "main_status=blocked", "fail": "main_status=failed"}[severity]
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("expected %q in logs, got %s", want, buf.String())
+			}
+			if sm.savedState != nil {
+				t.Errorf("dry run saved state: %+v", sm.savedState)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "quality-report.json")); !os.IsNotExist(err) {
+				t.Errorf("dry run wrote quality report: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunOnceSustainedTVSMovePublishes(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := baseConfig()
+	cfg.Output.Directory = t.TempDir()
+	cfg.Quality.MaxTVSDeltaPct = config.QualityRule{Enabled: true, Severity: "block_publish", Threshold: 50}
+
+	// History is reloaded from the last published output each cycle, so it
+	// keeps the pre-move snapshot until a cycle publishes.
+	sm := &stubState{
+		state:         &storage.State{},
+		shouldProcess: true,
+		history:       []aggregator.Snapshot{{Timestamp: 50, TVS: 100}},
+	}
+	sm.saveStateHook = func(state *storage.State) error {
+		sm.state = state
+		return nil
+	}
+	var published int
+	deps := runDeps{
+		client: stubClient{res: &api.FetchResult{OracleResponse: &api.OracleAPIResponse{}, Protocols: []api.Protocol{}}},
+		sm:     sm,
+		generateFull: func(*aggregator.AggregationResult, []aggregator.Snapshot, []aggregator.ChartDataPoint, *config.Config) *models.FullOutput {
+			return &models.FullOutput{}
+		},
+		generateSummary: func(*aggregator.AggregationResult, *config.Config) *models.SummaryOutput {
+			return &models.SummaryOutput{}
+		},
+		writeOutputs: func(context.Context, string, *config.Config, *models.FullOutput, *models.SummaryOutput) error {
+			published++
+			sm.history = sm.appendHistory
+			return nil
+		},
+		now:    func() time.Time { return time.Unix(500, 0) },
+		logger: newLogger(buf),
+	}
+
+	// Re-running the same upstream timestamp must not confirm the move; only
+	// newer data showing it again does.
+	cycles := []struct {
+		timestamp int64
+		status    string
+	}{
+		{100, "main_status=blocked"},
+		{100, "main_status=blocked"},
+		{101, "main_status=success"},
+		{102, "main_status=success"},
+	}
+	for i, c := range cycles {
+		buf.Reset()
+		deps.agg = stubAgg{result: &aggregator.AggregationResult{Timestamp: c.timestamp, TotalTVS: 300}}
+		if err := runOnceWithDeps(context.Background(), cfg, CLIOptions{}, deps); err != nil {
+			t.Fatalf("cycle %d: runOnceWithDeps error = %v", i, err)
+		}
+		if !strings.Contains(buf.String(), c.status) {
+			t.Fatalf("cycle %d: expected %q in logs, got %s", i, c.status, buf.String())
+		}
+		if i == 0 && (sm.state.LastEvaluatedTVS != 300 || sm.state.LastEvaluatedAt != 100 || sm.state.LastUpdated != 0) {
+			t.Errorf("blocked cycle state = %+v, want evaluated 300 at 100 and LastUpdated 0", sm.state)
+		}
+	}
+	if published != 2 {
+		t.Errorf("published %d cycles, want 2", published)
+	}
+}
+
 func TestRunOnceSkipsWhenNoNewData(t *testing.T) {
 	buf := &bytes.Buffer{}
 	logger := newLogger(buf)
@@ -1126,3 +1335,535 @@ func TestDaemonIntegration_ErrorRecoveryContinues(t *testing.T) {
 		t.Fatalf("expected next extraction log, got %s", loggerBuf.String())
 	}
 }
+
+func TestRunOnceMarksOutputsBuiltFromCache(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := baseConfig()
+
+	client := stubClient{res: &api.FetchResult{
+		OracleResponse: &api.OracleAPIResponse{},
+		Protocols:      []api.Protocol{},
+		CacheEntries: []api.CacheEntry{
+			{Key: "oracles", Age: 90 * time.Minute},
+			{Key: "lite-protocols2", Age: 3 * time.Hour},
+		},
+	}}
+	state := &stubState{state: &storage.State{}, shouldProcess: true}
+
+	var written *models.FullOutput
+	var writtenSummary *models.SummaryOutput
+	deps := runDeps{
+		client: client,
+		agg:    stubAgg{result: &aggregator.AggregationResult{Timestamp: 100}},
+		sm:     state,
+		generateFull: func(*aggregator.AggregationResult, []aggregator.Snapshot, []aggregator.ChartDataPoint, *config.Config) *models.FullOutput {
+			return &models.FullOutput{Metadata: models.OutputMetadata{DataSource: "DefiLlama API"}}
+		},
+		generateSummary: func(*aggregator.AggregationResult, *config.Config) *models.SummaryOutput {
+			return &models.SummaryOutput{Metadata: models.OutputMetadata{DataSource: "DefiLlama API"}}
+		},
+		writeOutputs: func(_ context.Context, _ string, _ *config.Config, full *models.FullOutput, summary *models.SummaryOutput) error {
+			written, writtenSummary = full, summary
+			return nil
+		},
+		now:    func() time.Time { return time.Unix(200, 0) },
+		logger: newLogger(buf),
+	}
+
+	if err := runOnceWithDeps(context.Background(), cfg, CLIOptions{}, deps); err != nil {
+		t.Fatalf("runOnceWithDeps returned error: %v", err)
+	}
+
+	if written == nil || written.Metadata.DataSource != "cache" || written.Metadata.CacheAgeSeconds != 3*3600 {
+		t.Fatalf("expected full output marked as cache with oldest age, got %+v", written)
+	}
+	if writtenSummary == nil || writtenSummary.Metadata.DataSource != "cache" {
+		t.Fatalf("expected summary output marked as cache, got %+v", writtenSummary)
+	}
+	if !strings.Contains(buf.String(), "outputs_built_from_cache") {
+		t.Fatalf("expected cache provenance warning, got: %s", buf.String())
+	}
+}
+
+func TestRunOnceStrictSchemaDriftFailsCycle(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := baseConfig()
+	cfg.Output.Directory = t.TempDir()
+	cfg.TVL.Enabled = true
+	cfg.API.Schema = config.SchemaConfig{Enabled: true, Strict: true, ReportFile: "schema-drift.json"}
+
+	client := stubClient{res: &api.FetchResult{
+		OracleResponse: &api.OracleAPIResponse{},
+		SchemaDrift: &api.SchemaDriftReport{Datasets: []api.DatasetDrift{{
+			Dataset: api.SchemaDatasetOracles,
+			Samples: 1,
+			Removed: []api.FieldDrift{{Field: "oraclesTVS", Expected: "object"}},
+		}}},
+	}}
+
+	writeCalled, tvlCalled := false, false
+	deps := runDeps{
+		client: client,
+		agg:    stubAgg{result: &aggregator.AggregationResult{Timestamp: 100}},
+		sm:     &stubState{state: &storage.State{}, shouldProcess: true},
+		generateFull: func(*aggregator.AggregationResult, []aggregator.Snapshot, []aggregator.ChartDataPoint, *config.Config) *models.FullOutput {
+			return &models.FullOutput{}
+		},
+		generateSummary: func(*aggregator.AggregationResult, *config.Config) *models.SummaryOutput {
+			return &models.SummaryOutput{}
+		},
+		writeOutputs: func(context.Context, string, *config.Config, *models.FullOutput, *models.SummaryOutput) error {
+			writeCalled = true
+			return nil
+		},
+		tvlRunner: func(context.Context, *config.Config, []api.Protocol, time.Time, CLIOptions, tvl.TVLClient, *slog.Logger) error {
+			tvlCalled = true
+			return nil
+		},
+		now:    func() time.Time { return time.Unix(200, 0) },
+		logger: newLogger(buf),
+	}
+
+	err := runOnceWithDeps(context.Background(), cfg, CLIOptions{}, deps)
+	if !errors.Is(err, errSchemaDrift) {
+		t.Fatalf("expected errSchemaDrift, got %v", err)
+	}
+	if writeCalled || tvlCalled {
+		t.Fatalf("expected strict drift to skip outputs and TVL (write=%v tvl=%v)", writeCalled, tvlCalled)
+	}
+	if !strings.Contains(buf.String(), "schema_drift_detected") || !strings.Contains(buf.String(), "oraclesTVS") {
+		t.Fatalf("expected drift warning naming the field, got: %s", buf.String())
+	}
+
+	data, readErr := os.ReadFile(filepath.Join(cfg.Output.Directory, "schema-drift.json"))
+	if readErr != nil {
+		t.Fatalf("expected drift report file: %v", readErr)
+	}
+	if !strings.Contains(string(data), `"removed"`) {
+		t.Fatalf("expected removed fields in report, got %s", data)
+	}
+}
+
+func TestRunOnceNonStrictSchemaDriftOnlyWarns(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := baseConfig()
+	cfg.Output.Directory = t.TempDir()
+	cfg.API.Schema = config.SchemaConfig{Enabled: true, ReportFile: "schema-drift.json"}
+
+	client := stubClient{res: &api.FetchResult{
+		OracleResponse: &api.OracleAPIResponse{},
+		SchemaDrift: &api.SchemaDriftReport{Datasets: []api.DatasetDrift{{
+			Dataset:     api.SchemaDatasetProtocols,
+			Samples:     10,
+			TypeChanged: []api.FieldDrift{{Field: "tvl", Expected: "number", Observed: "string"}},
+		}}},
+	}}
+
+	writeCalled := false
+	deps := runDeps{
+		client: client,
+		agg:    stubAgg{result: &aggregator.AggregationResult{Timestamp: 100}},
+		sm:     &stubState{state: &storage.State{}, shouldProcess: true},
+		generateFull: func(*aggregator.AggregationResult, []aggregator.Snapshot, []aggregator.ChartDataPoint, *config.Config) *models.FullOutput {
+			return &models.FullOutput{}
+		},
+		generateSummary: func(*aggregator.AggregationResult, *config.Config) *models.SummaryOutput {
+			return &models.SummaryOutput{}
+		},
+		writeOutputs: func(context.Context, string, *config.Config, *models.FullOutput, *models.SummaryOutput) error {
+			writeCalled = true
+			return nil
+		},
+		now:    func() time.Time { return time.Unix(200, 0) },
+		logger: newLogger(buf),
+	}
+
+	if err := runOnceWithDeps(context.Background(), cfg, CLIOptions{}, deps); err != nil {
+		t.Fatalf("runOnceWithDeps returned error: %v", err)
+	}
+	if !writeCalled {
+		t.Fatalf("expected outputs to be written without strict mode")
+	}
+	if !strings.Contains(buf.String(), "schema_drift_detected") {
+		t.Fatalf("expected drift warning, got: %s", buf.String())
+	}
+}
+
+type stubChainSource struct {
+	chains  []api.ChainInfo
+	history map[string][]api.ChainTVLPoint
+	err     error
+}
+
+func (s stubChainSource) FetchChains(ctx context.Context) ([]api.ChainInfo, error) {
+	return s.chains, s.err
+}
+
+func (s stubChainSource) FetchHistoricalChainTVL(ctx context.Context, chain string) ([]api.ChainTVLPoint, error) {
+	return s.history[chain], nil
+}
+
+func TestRunOnceAppliesChainShare(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := baseConfig()
+	cfg.Aggregation.ChainShare = true
+
+	day := int64(86400)
+	aggResult := &aggregator.AggregationResult{
+		Timestamp:    10*day + 3600,
+		TotalTVS:     30,
+		ActiveChains: []string{"sui", "Unlisted"},
+		ChainBreakdown: []aggregator.ChainBreakdown{
+			{Chain: "sui", TVS: 25},
+			{Chain: "Unlisted", TVS: 5},
+		},
+	}
+	state := &stubState{
+		state:         &storage.State{},
+		shouldProcess: true,
+		history: []aggregator.Snapshot{
+			{Timestamp: 9*day + 3600, TVSByChain: map[string]float64{"sui": 20}},
+		},
+	}
+	chains := stubChainSource{
+		chains: []api.ChainInfo{{Name: "Sui", TVL: 1000}, {Name: "Solana", TVL: 9000}},
+		history: map[string][]api.ChainTVLPoint{
+			"Sui": {{Date: 9 * day, TVL: 800}, {Date: 10 * day, TVL: 900}},
+		},
+	}
+
+	var generated *aggregator.AggregationResult
+	deps := runDeps{
+		client:      stubClient{res: &api.FetchResult{OracleResponse: &api.OracleAPIResponse{}}},
+		chainSource: chains,
+		agg:         stubAgg{result: aggResult},
+		sm:          state,
+		generateFull: func(result *aggregator.AggregationResult, _ []aggregator.Snapshot, _ []aggregator.ChartDataPoint, _ *config.Config) *models.FullOutput {
+			generated = result
+			return &models.FullOutput{}
+		},
+		generateSummary: func(*aggregator.AggregationResult, *config.Config) *models.SummaryOutput {
+			return &models.SummaryOutput{}
+		},
+		writeOutputs: func(context.Context, string, *config.Config, *models.FullOutput, *models.SummaryOutput) error {
+			return nil
+		},
+		now:    func() time.Time { return time.Unix(10*day+7200, 0) },
+		logger: newLogger(buf),
+	}
+
+	if err := runOnceWithDeps(context.Background(), cfg, CLIOptions{}, deps); err != nil {
+		t.Fatalf("runOnceWithDeps returned error: %v", err)
+	}
+
+	sui := generated.ChainBreakdown[0]
+	if sui.ChainTVL != 1000 || sui.ChainTVLShare == nil || *sui.ChainTVLShare != 2.5 {
+		t.Fatalf("expected sui share of current chain TVL, got %+v", sui)
+	}
+	if unlisted := generated.ChainBreakdown[1]; unlisted.ChainTVLShare != nil {
+		t.Fatalf("expected no share for unlisted chain, got %+v", unlisted)
+	}
+
+	series := generated.ChainShareHistory["sui"]
+	if len(series) != 2 {
+		t.Fatalf("expected two daily share points, got %+v", generated.ChainShareHistory)
+	}
+	if series[0].Share != 2.5 || series[1].ChainTVL != 1000 || series[1].Share != 2.5 {
+		t.Fatalf("unexpected share series: %+v", series)
+	}
+}
+
+func TestRunOnceChainShareFailureKeepsCycle(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := baseConfig()
+	cfg.Aggregation.ChainShare = true
+
+	aggResult := &aggregator.AggregationResult{
+		Timestamp:      100,
+		ActiveChains:   []string{"sui"},
+		ChainBreakdown: []aggregator.ChainBreakdown{{Chain: "sui", TVS: 25}},
+	}
+	wrote := false
+	deps := runDeps{
+		client:      stubClient{res: &api.FetchResult{OracleResponse: &api.OracleAPIResponse{}}},
+		chainSource: stubChainSource{err: errors.New("upstream down")},
+		agg:         stubAgg{result: aggResult},
+		sm:          &stubState{state: &storage.State{}, shouldProcess: true},
+		generateFull: func(*aggregator.AggregationResult, []aggregator.Snapshot, []aggregator.ChartDataPoint, *config.Config) *models.FullOutput {
+			return &models.FullOutput{}
+		},
+		generateSummary: func(*aggregator.AggregationResult, *config.Config) *models.SummaryOutput {
+			return &models.SummaryOutput{}
+		},
+		writeOutputs: func(context.Context, string, *config.Config, *models.FullOutput, *models.SummaryOutput) error {
+			wrote = true
+			return nil
+		},
+		now:    func() time.Time { return time.Unix(200, 0) },
+		logger: newLogger(buf),
+	}
+
+	if err := runOnceWithDeps(context.Background(), cfg, CLIOptions{}, deps); err != nil {
+		t.Fatalf("runOnceWithDeps returned error: %v", err)
+	}
+	if !wrote {
+		t.Fatalf("expected outputs to be written without chain share")
+	}
+	if aggResult.ChainBreakdown[0].ChainTVLShare != nil || aggResult.ChainShareHistory != nil {
+		t.Fatalf("expected no chain share data, got %+v", aggResult)
+	}
+	if !strings.Contains(buf.String(), "chain_tvl_fetch_failed") {
+		t.Fatalf("expected chain TVL failure warning, got: %s", buf.String())
+	}
+}
+
+type stubReportingClient struct {
+	stubClient
+	report *api.FetchReport
+}
+
+func (s *stubReportingClient) FetchReport() *api.FetchReport { return s.report }
+
+func TestRunOnceLogsFetchReportOnFailedCycle(t *testing.T) {
+	buf := &bytes.Buffer{}
+	client := &stubReportingClient{
+		stubClient: stubClient{err: errors.New("oracles unavailable")},
+		report: &api.FetchReport{Endpoints: []api.EndpointReport{
+			{Endpoint: api.EndpointClassOracles, Requests: 4, Errors: 4, Retries: 3, P50Ms: 120, P95Ms: 900},
+		}},
+	}
+	deps := runDeps{
+		client: client,
+		agg:    stubAgg{},
+		sm:     &stubState{state: &storage.State{}},
+		now:    func() time.Time { return time.Unix(200, 0) },
+		logger: newLogger(buf),
+	}
+
+	if err := runOnceWithDeps(context.Background(), baseConfig(), CLIOptions{}, deps); err == nil {
+		t.Fatalf("expected fetch error")
+	}
+
+	logs := buf.String()
+	for _, want := range []string{"msg=fetch_report", "endpoint=oracles", "errors=4", "retries=3", "p95_ms=900"} {
+		if !strings.Contains(logs, want) {
+			t.Fatalf("expected %s in logs, got: %s", want, logs)
+		}
+	}
+}
+
+func TestRunOnceMultiOracleWritesPerOracleOutputs(t *testing.T) {
+	sourceDir := t.TempDir()
+	cassettes := map[string]string{
+		"oracles.json": `{
+			"oracles": {"Switchboard": ["kamino"], "Pyth": ["drift"]},
+			"oraclesTVS": {"Switchboard": {"kamino": {"Solana": 100}}, "Pyth": {"drift": {"Solana": 300, "Sui": 50}}},
+			"chainsByOracle": {"Switchboard": ["Solana"], "Pyth": ["Solana", "Sui"]},
+			"chart": {}
+		}`,
+		"lite/protocols2.json": `[
+			{"id": "1", "name": "Kamino", "slug": "kamino", "category": "Lending", "tvl": 100, "chains": ["Solana"], "oracles": ["Switchboard"]},
+			{"id": "2", "name": "Drift", "slug": "drift", "category": "Derivatives", "tvl": 350, "chains": ["Solana", "Sui"], "oracles": ["Pyth"]},
+			{"id": "3", "name": "Other", "slug": "other", "category": "Dexs", "tvl": 5, "chains": ["Solana"], "oracles": ["Chainlink"]}
+		]`,
+	}
+	for name, body := range cassettes {
+		path := filepath.Join(sourceDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatalf("write cassette: %v", err)
+		}
+	}
+
+	outDir := t.TempDir()
+	cfg := baseConfig()
+	cfg.Oracle.Names = []string{"Switchboard", "Pyth"}
+	cfg.Source = config.SourceConfig{Type: "directory", Directory: sourceDir}
+	cfg.Output = config.OutputConfig{
+		Directory:   outDir,
+		FullFile:    "oracle-data.json",
+		SummaryFile: "summary.json",
+		StateFile:   "state.json",
+	}
+
+	buf := &bytes.Buffer{}
+	if err := RunOnce(context.Background(), cfg, CLIOptions{}, newLogger(buf)); err != nil {
+		t.Fatalf("RunOnce returned error: %v\n%s", err, buf.String())
+	}
+
+	for dir, want := range map[string]string{"switchboard": `"name": "Kamino"`, "pyth": `"name": "Drift"`} {
+		full, err := os.ReadFile(filepath.Join(outDir, dir, "oracle-data.json"))
+		if err != nil {
+			t.Fatalf("expected full output for %s: %v", dir, err)
+		}
+		if !strings.Contains(string(full), want) || strings.Contains(string(full), `"name": "Other"`) {
+			t.Fatalf("unexpected %s output: %s", dir, full)
+		}
+		if _, err := os.Stat(filepath.Join(outDir, dir, "state.json")); err != nil {
+			t.Fatalf("expected state for %s: %v", dir, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "oracle-data.json")); !os.IsNotExist(err) {
+		t.Fatalf("expected no top-level output in multi-oracle mode, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "oracle=Pyth") {
+		t.Fatalf("expected per-oracle log context, got: %s", buf.String())
+	}
+}
+
+func TestRunOnceWritesOracleLandscape(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := baseConfig()
+	cfg.Output.Directory = t.TempDir()
+	cfg.Output.LandscapeFile = "oracle-landscape.json"
+	cfg.Aggregation.Landscape = true
+
+	resp := &api.OracleAPIResponse{OraclesTVS: map[string]map[string]map[string]float64{
+		"Switchboard": {"kamino": {"Solana": 200}},
+		"Pyth":        {"drift": {"Solana": 500}},
+	}}
+	deps := runDeps{
+		client: stubClient{res: &api.FetchResult{OracleResponse: resp}},
+		agg:    stubAgg{result: &aggregator.AggregationResult{Timestamp: 100}},
+		sm:     &stubState{state: &storage.State{}, shouldProcess: true},
+		generateFull: func(*aggregator.AggregationResult, []aggregator.Snapshot, []aggregator.ChartDataPoint, *config.Config) *models.FullOutput {
+			return &models.FullOutput{}
+		},
+		generateSummary: func(*aggregator.AggregationResult, *config.Config) *models.SummaryOutput {
+			return &models.SummaryOutput{}
+		},
+		writeOutputs: func(context.Context, string, *config.Config, *models.FullOutput, *models.SummaryOutput) error {
+			return nil
+		},
+		now:    func() time.Time { return time.Unix(200, 0) },
+		logger: newLogger(buf),
+	}
+
+	if err := runOnceWithDeps(context.Background(), cfg, CLIOptions{}, deps); err != nil {
+		t.Fatalf("runOnceWithDeps returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.Output.Directory, "oracle-landscape.json"))
+	if err != nil {
+		t.Fatalf("expected landscape output: %v", err)
+	}
+	if !strings.Contains(string(data), `"oracle": "Pyth"`) || !strings.Contains(string(data), `"rank": 2`) {
+		t.Fatalf("unexpected landscape output: %s", data)
+	}
+	if !strings.Contains(buf.String(), "msg=oracle_landscape pipeline=main rank=2 oracles=2") {
+		t.Fatalf("expected landscape log, got: %s", buf.String())
+	}
+}
+
+func TestRunOnceWritesCategoryReport(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := baseConfig()
+	cfg.Output.Directory = t.TempDir()
+	cfg.Output.CategoryReportFile = "category-report.json"
+	cfg.Categories.Mapping = map[string][]string{"Money Markets": {"Lending"}}
+
+	deps := runDeps{
+		client: stubClient{res: &api.FetchResult{OracleResponse: &api.OracleAPIResponse{}}},
+		agg: stubAgg{result: &aggregator.AggregationResult{
+			Timestamp:      100,
+			TotalProtocols: 3,
+			UnmappedCategories: []aggregator.UnmappedCategory{
+				{Category: "Dexs", ProtocolCount: 1, TVS: 50, Protocols: []string{"orca"}},
+			},
+		}},
+		sm: &stubState{state: &storage.State{}, shouldProcess: true},
+		generateFull: func(*aggregator.AggregationResult, []aggregator.Snapshot, []aggregator.ChartDataPoint, *config.Config) *models.FullOutput {
+			return &models.FullOutput{}
+		},
+		generateSummary: func(*aggregator.AggregationResult, *config.Config) *models.SummaryOutput {
+			return &models.SummaryOutput{}
+		},
+		writeOutputs: func(context.Context, string, *config.Config, *models.FullOutput, *models.SummaryOutput) error {
+			return nil
+		},
+		now:    func() time.Time { return time.Unix(200, 0) },
+		logger: newLogger(buf),
+	}
+
+	if err := runOnceWithDeps(context.Background(), cfg, CLIOptions{}, deps); err != nil {
+		t.Fatalf("runOnceWithDeps returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.Output.Directory, "category-report.json"))
+	if err != nil {
+		t.Fatalf("expected category report: %v", err)
+	}
+	if !strings.Contains(string(data), `"mapped_protocols": 2`) || !strings.Contains(string(data), `"category": "Dexs"`) {
+		t.Fatalf("unexpected category report: %s", data)
+	}
+	if !strings.Contains(buf.String(), "msg=categories_unmapped pipeline=main count=1 categories=[Dexs]") {
+		t.Fatalf("expected unmapped categories log, got: %s", buf.String())
+	}
+}
+
+func TestRunOnceWritesProtocolMatchReport(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := baseConfig()
+	cfg.Output.Directory = t.TempDir()
+	cfg.Output.ProtocolMatchReportFile = "protocol-matches.json"
+
+	deps := runDeps{
+		client: stubClient{res: &api.FetchResult{OracleResponse: &api.OracleAPIResponse{}}},
+		agg: stubAgg{result: &aggregator.AggregationResult{
+			Timestamp: 100,
+			ProtocolMatches: &aggregator.ProtocolMatchReport{
+				Oracle:        "Switchboard",
+				ProtocolCount: 2,
+				Matched:       1,
+				ByStrategy:    map[string]int{aggregator.MatchSlug: 1},
+				Unmatched:     []aggregator.UnresolvedProtocol{{Name: "Ghost", Slug: "ghost"}},
+				Ambiguous:     []aggregator.UnresolvedProtocol{},
+				UnclaimedKeys: []aggregator.UnclaimedKey{{Key: "Ghost Finance", TVS: 40}},
+			},
+		}},
+		sm: &stubState{state: &storage.State{}, shouldProcess: true},
+		generateFull: func(*aggregator.AggregationResult, []aggregator.Snapshot, []aggregator.ChartDataPoint, *config.Config) *models.FullOutput {
+			return &models.FullOutput{}
+		},
+		generateSummary: func(*aggregator.AggregationResult, *config.Config) *models.SummaryOutput {
+			return &models.SummaryOutput{}
+		},
+		writeOutputs: func(context.Context, string, *config.Config, *models.FullOutput, *models.SummaryOutput) error {
+			return nil
+		},
+		now:    func() time.Time { return time.Unix(200, 0) },
+		logger: newLogger(buf),
+	}
+
+	if err := runOnceWithDeps(context.Background(), cfg, CLIOptions{}, deps); err != nil {
+		t.Fatalf("runOnceWithDeps returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.Output.Directory, "protocol-matches.json"))
+	if err != nil {
+		t.Fatalf("expected protocol match report: %v", err)
+	}
+	if !strings.Contains(string(data), `"slug": "ghost"`) || !strings.Contains(string(data), `"key": "Ghost Finance"`) {
+		t.Fatalf("unexpected protocol match report: %s", data)
+	}
+	if !strings.Contains(buf.String(), "msg=protocols_unresolved pipeline=main unmatched=1 ambiguous=0 unclaimed_keys=1 unclaimed_tvs=40") {
+		t.Fatalf("expected unresolved protocols log, got: %s", buf.String())
+	}
+}
+
+func TestLogAnomalies(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logAnomalies(newLogger(buf), &aggregator.AggregationResult{
+		Anomalies: []aggregator.Anomaly{{
+			Scope: aggregator.AnomalyScopeProtocol, Slug: "kamino", Value: 1000, Previous: 100,
+			ChangePct: 900, Score: 41.2, Quarantined: true, Cycles: 1,
+		}},
+	})
+
+	want := "msg=tvs_anomaly scope=protocol protocol=kamino value=1000 previous=100 change_pct=900 score=41.2 quarantined=true cycles=1"
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("expected anomaly log, got: %s", buf.String())
+	}
+}
@@ -5,16 +5,32 @@ import (
 	"sort"
 
 	"github.com/switchboard-xyz/defillama-extract/internal/api"
+	"github.com/switchboard-xyz/defillama-extract/internal/chainregistry"
 )
 
 // Aggregator orchestrates the aggregation pipeline for a specific oracle.
 type Aggregator struct {
 	oracleName string
+	opts       Options
 }
 
-// NewAggregator creates an Aggregator configured for the provided oracle name.
-func NewAggregator(oracleName string) *Aggregator {
-	return &Aggregator{oracleName: oracleName}
+// Options tunes how an Aggregator counts and groups TVS. TVSPolicy selects the
+// TVL components counted; Chains groups chains by canonical name and
+// Categories maps categories to the reporting taxonomy. Nil Chains and
+// Categories keep names as reported. ProtocolAliases pins protocol slugs or
+// names to oraclesTVS keys ahead of the other match strategies. Anomalies
+// enables anomaly detection against the snapshot history; nil disables it.
+type Options struct {
+	TVSPolicy       TVSPolicy
+	Chains          *chainregistry.Registry
+	Categories      *CategoryTaxonomy
+	ProtocolAliases map[string]string
+	Anomalies       *AnomalyOptions
+}
+
+// NewAggregator creates an Aggregator for the provided oracle name.
+func NewAggregator(oracleName string, opts Options) *Aggregator {
+	return &Aggregator{oracleName: oracleName, opts: opts}
 }
 
 // Aggregate processes raw API data through the full pipeline and returns an AggregationResult.
@@ -24,19 +40,26 @@ func (a *Aggregator) Aggregate(ctx context.Context, oracleResp *api.OracleAPIRes
 	_ = ctx
 
 	filtered := FilterByOracle(protocols, a.oracleName)
-	aggregated, timestamp, withTVS, withoutTVS := ExtractProtocolData(filtered, oracleResp, a.oracleName)
+	aggregated, timestamp, matches := ExtractProtocolData(filtered, oracleResp, a.oracleName, a.opts.TVSPolicy, a.opts.ProtocolAliases)
+	CanonicalizeProtocolChains(aggregated, a.opts.Chains)
+	MapProtocolCategories(aggregated, a.opts.Categories)
+	anomalies := DetectProtocolAnomalies(aggregated, history, timestamp, a.opts.Anomalies)
 
-	chainBreakdown := CalculateChainBreakdown(aggregated)
+	chainBreakdown := CalculateChainBreakdown(aggregated, a.opts.Chains)
 	categoryBreakdown := CalculateCategoryBreakdown(aggregated)
 	ranked := RankProtocols(aggregated)
+	CalculateProtocolChanges(ranked, history)
 	largest := GetLargestProtocol(aggregated)
 
 	totalTVS := calculateTotalTVS(aggregated)
+	if total := DetectTotalAnomaly(totalTVS, history, timestamp, a.opts.Anomalies); total != nil {
+		anomalies = append([]Anomaly{*total}, anomalies...)
+	}
 	changeMetrics := CalculateChangeMetrics(totalTVS, len(aggregated), history)
 	activeChains := extractActiveChains(chainBreakdown)
 	categories := extractUniqueCategories(aggregated)
 
-	return &AggregationResult{
+	result := &AggregationResult{
 		TotalTVS:            totalTVS,
 		TotalProtocols:      len(aggregated),
 		ActiveChains:        activeChains,
@@ -47,9 +70,17 @@ func (a *Aggregator) Aggregate(ctx context.Context, oracleResp *api.OracleAPIRes
 		LargestProtocol:     largest,
 		ChangeMetrics:       changeMetrics,
 		Timestamp:           timestamp,
-		ProtocolsWithTVS:    withTVS,
-		ProtocolsWithoutTVS: withoutTVS,
+		ProtocolsWithTVS:    matches.Matched,
+		ProtocolsWithoutTVS: matches.ProtocolCount - matches.Matched,
+		ProtocolMatches:     matches,
+		Concentration:       CalculateConcentrationMetrics(aggregated, chainBreakdown, categoryBreakdown),
+		Anomalies:           anomalies,
+	}
+	if a.opts.Categories != nil {
+		result.MappedCategoryBreakdown = CalculateMappedCategoryBreakdown(aggregated)
+		result.UnmappedCategories = FindUnmappedCategories(aggregated, a.opts.Categories)
 	}
+	return result
 }
 
 func calculateTotalTVS(protocols []AggregatedProtocol) float64 {
@@ -81,11 +112,7 @@ func extractUniqueCategories(protocols []AggregatedProtocol) []string {
 
 	seen := make(map[string]struct{}, len(protocols))
 	for _, p := range protocols {
-		category := p.Category
-		if category == "" {
-			category = "Uncategorized"
-		}
-		seen[category] = struct{}{}
+		seen[rawCategory(p.Category)] = struct{}{}
 	}
 
 	categories := make([]string, 0, len(seen))
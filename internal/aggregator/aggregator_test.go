@@ -10,7 +10,7 @@ import (
 )
 
 func TestNewAggregator(t *testing.T) {
-	agg := NewAggregator("Switchboard")
+	agg := NewAggregator("Switchboard", Options{TVSPolicy: testPolicy})
 	if agg.oracleName != "Switchboard" {
 		t.Fatalf("oracleName = %s, want Switchboard", agg.oracleName)
 	}
@@ -72,7 +72,7 @@ func TestAggregate_CompletesPipeline(t *testing.T) {
 		{Timestamp: now - Days7, TVS: 1000, ProtocolCount: 1},
 	}
 
-	agg := NewAggregator("Switchboard")
+	agg := NewAggregator("Switchboard", Options{TVSPolicy: testPolicy})
 	result := agg.Aggregate(ctx, oracleResp, protocols, history)
 
 	if result.TotalProtocols != 2 {
@@ -135,7 +135,7 @@ func TestAggregate_CompletesPipeline(t *testing.T) {
 }
 
 func TestAggregate_GracefulOnEmptyInputs(t *testing.T) {
-	agg := NewAggregator("Switchboard")
+	agg := NewAggregator("Switchboard", Options{TVSPolicy: testPolicy})
 	result := agg.Aggregate(context.Background(), nil, nil, nil)
 
 	if result.TotalTVS != 0 || result.TotalProtocols != 0 {
@@ -0,0 +1,163 @@
+package aggregator
+
+import (
+	"math"
+	"time"
+)
+
+// tradingDaysPerYear annualizes daily volatility; crypto markets never close.
+const tradingDaysPerYear = 365
+
+// secondsPerYear is the average Julian year used to annualize growth.
+const secondsPerYear = 365.25 * 24 * 60 * 60
+
+// Analytics are rolling statistics over the daily chart history. Moving
+// averages are in USD; volatility, drawdown and CAGR are percentages.
+// Volatility is the standard deviation of daily log returns over the window,
+// annualized over 365 days. CAGRYTD is annualized like the others, so it
+// swings widely early in the year. Fields are nil when the history does not
+// cover their window.
+type Analytics struct {
+	AsOf             string   `json:"as_of"`
+	MovingAvg7d      *float64 `json:"moving_avg_7d,omitempty"`
+	MovingAvg30d     *float64 `json:"moving_avg_30d,omitempty"`
+	MovingAvg90d     *float64 `json:"moving_avg_90d,omitempty"`
+	Volatility30d    *float64 `json:"volatility_30d,omitempty"`
+	Volatility90d    *float64 `json:"volatility_90d,omitempty"`
+	AllTimeHigh      float64  `json:"all_time_high"`
+	AllTimeHighDate  string   `json:"all_time_high_date"`
+	DrawdownPct      float64  `json:"drawdown_pct"`
+	CAGR1y           *float64 `json:"cagr_1y,omitempty"`
+	CAGRYTD          *float64 `json:"cagr_ytd,omitempty"`
+	CAGRAllTime      *float64 `json:"cagr_all_time,omitempty"`
+	HistoryDays      int      `json:"history_days"`
+	HistoryStartDate string   `json:"history_start_date"`
+}
+
+// CalculateAnalytics computes Analytics from chart history sorted by
+// timestamp ascending, as returned by ExtractChartHistory. Windows are
+// calendar days ending at the latest point. It returns nil for empty history.
+func CalculateAnalytics(history []ChartDataPoint) *Analytics {
+	if len(history) == 0 {
+		return nil
+	}
+
+	first := history[0]
+	latest := history[len(history)-1]
+	a := &Analytics{
+		AsOf:             latest.Date,
+		MovingAvg7d:      movingAverage(history, 7),
+		MovingAvg30d:     movingAverage(history, 30),
+		MovingAvg90d:     movingAverage(history, 90),
+		Volatility30d:    annualizedVolatility(history, 30),
+		Volatility90d:    annualizedVolatility(history, 90),
+		HistoryDays:      int((latest.Timestamp - first.Timestamp) / Hours24),
+		HistoryStartDate: first.Date,
+	}
+
+	for _, p := range history {
+		if p.TVS > a.AllTimeHigh {
+			a.AllTimeHigh = p.TVS
+			a.AllTimeHighDate = p.Date
+		}
+	}
+	if a.AllTimeHigh > 0 {
+		a.DrawdownPct = CalculatePercentageChange(a.AllTimeHigh, latest.TVS)
+	}
+
+	if start := pointAtOrBefore(history, latest.Timestamp-365*Hours24); start != nil {
+		a.CAGR1y = cagr(*start, latest)
+	}
+	yearStart := time.Date(time.Unix(latest.Timestamp, 0).UTC().Year(), time.January, 1, 0, 0, 0, 0, time.UTC).Unix()
+	if start := pointAtOrBefore(history, yearStart); start != nil {
+		a.CAGRYTD = cagr(*start, latest)
+	}
+	for _, p := range history {
+		if p.TVS > 0 {
+			a.CAGRAllTime = cagr(p, latest)
+			break
+		}
+	}
+
+	return a
+}
+
+// windowPoints returns the points within days calendar days of the latest
+// point, or nil when history does not reach back that far.
+func windowPoints(history []ChartDataPoint, days int64) []ChartDataPoint {
+	latest := history[len(history)-1].Timestamp
+	cutoff := latest - (days-1)*Hours24
+	if history[0].Timestamp > cutoff {
+		return nil
+	}
+	i := len(history)
+	for i > 0 && history[i-1].Timestamp >= cutoff {
+		i--
+	}
+	return history[i:]
+}
+
+func movingAverage(history []ChartDataPoint, days int64) *float64 {
+	window := windowPoints(history, days)
+	if len(window) == 0 {
+		return nil
+	}
+	var sum float64
+	for _, p := range window {
+		sum += p.TVS
+	}
+	avg := roundCents(sum / float64(len(window)))
+	return &avg
+}
+
+func annualizedVolatility(history []ChartDataPoint, days int64) *float64 {
+	window := windowPoints(history, days+1)
+	if len(window) < 3 {
+		return nil
+	}
+
+	returns := make([]float64, 0, len(window)-1)
+	for i := 1; i < len(window); i++ {
+		prev, cur := window[i-1].TVS, window[i].TVS
+		if prev <= 0 || cur <= 0 {
+			continue
+		}
+		returns = append(returns, math.Log(cur/prev))
+	}
+	if len(returns) < 2 {
+		return nil
+	}
+
+	_, std := meanStd(returns)
+	vol := roundCents(std * math.Sqrt(tradingDaysPerYear) * 100)
+	return &vol
+}
+
+// pointAtOrBefore returns the latest point at or before ts, or nil.
+func pointAtOrBefore(history []ChartDataPoint, ts int64) *ChartDataPoint {
+	var found *ChartDataPoint
+	for i := range history {
+		if history[i].Timestamp > ts {
+			break
+		}
+		found = &history[i]
+	}
+	return found
+}
+
+// cagr returns the compound annual growth rate between two points as a
+// percentage, or nil when start has no value or the points are under a day
+// apart.
+func cagr(start, end ChartDataPoint) *float64 {
+	elapsed := end.Timestamp - start.Timestamp
+	if start.TVS <= 0 || end.TVS < 0 || elapsed < Hours24 {
+		return nil
+	}
+	years := float64(elapsed) / secondsPerYear
+	rate := roundCents((math.Pow(end.TVS/start.TVS, 1/years) - 1) * 100)
+	return &rate
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
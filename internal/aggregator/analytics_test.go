@@ -0,0 +1,87 @@
+package aggregator
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func dailyChart(start time.Time, values ...float64) []ChartDataPoint {
+	points := make([]ChartDataPoint, len(values))
+	for i, v := range values {
+		ts := start.AddDate(0, 0, i)
+		points[i] = ChartDataPoint{Timestamp: ts.Unix(), Date: ts.Format("2006-01-02"), TVS: v}
+	}
+	return points
+}
+
+func TestCalculateAnalytics_MovingAveragesAndVolatility(t *testing.T) {
+	values := make([]float64, 120)
+	for i := range values {
+		values[i] = 100
+		if i%2 == 1 {
+			values[i] = 110
+		}
+	}
+	history := dailyChart(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), values...)
+
+	got := CalculateAnalytics(history)
+
+	// The last 7 days hold four 110s and three 100s.
+	assertFloatPtr(t, got.MovingAvg7d, roundCents((4*110+3*100)/7.0))
+	assertFloatPtr(t, got.MovingAvg30d, 105)
+	assertFloatPtr(t, got.MovingAvg90d, 105)
+
+	// 30 returns alternating +ln(1.1) and -ln(1.1) have zero mean.
+	r := math.Log(1.1)
+	wantVol := roundCents(math.Sqrt(30*r*r/29) * math.Sqrt(365) * 100)
+	assertFloatPtr(t, got.Volatility30d, wantVol)
+	if got.Volatility90d == nil {
+		t.Fatalf("expected 90d volatility")
+	}
+
+	if got.AsOf != "2024-06-28" || got.HistoryStartDate != "2024-03-01" || got.HistoryDays != 119 {
+		t.Errorf("unexpected span: as_of %s start %s days %d", got.AsOf, got.HistoryStartDate, got.HistoryDays)
+	}
+}
+
+func TestCalculateAnalytics_HighAndDrawdown(t *testing.T) {
+	history := dailyChart(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), 100, 300, 200, 150)
+
+	got := CalculateAnalytics(history)
+
+	if got.AllTimeHigh != 300 || got.AllTimeHighDate != "2024-05-02" {
+		t.Errorf("ATH = %v on %s, want 300 on 2024-05-02", got.AllTimeHigh, got.AllTimeHighDate)
+	}
+	if got.DrawdownPct != -50 {
+		t.Errorf("DrawdownPct = %v, want -50", got.DrawdownPct)
+	}
+	if got.MovingAvg7d != nil || got.Volatility30d != nil || got.CAGR1y != nil || got.CAGRYTD != nil {
+		t.Errorf("expected nil windows for short history, got %+v", got)
+	}
+	if got.CAGRAllTime == nil {
+		t.Errorf("expected all-time CAGR")
+	}
+}
+
+func TestCalculateAnalytics_CAGR(t *testing.T) {
+	start := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC).Unix()
+	end := start + int64(2*secondsPerYear)
+	history := []ChartDataPoint{
+		{Timestamp: start - Hours24, TVS: 0},
+		{Timestamp: start, TVS: 100},
+		{Timestamp: end, TVS: 400},
+	}
+
+	got := CalculateAnalytics(history)
+
+	assertFloatPtr(t, got.CAGRAllTime, 100)
+	assertFloatPtr(t, got.CAGR1y, 100)
+	assertFloatPtr(t, got.CAGRYTD, 100)
+}
+
+func TestCalculateAnalytics_Empty(t *testing.T) {
+	if got := CalculateAnalytics(nil); got != nil {
+		t.Fatalf("expected nil analytics, got %+v", got)
+	}
+}
@@ -0,0 +1,244 @@
+package aggregator
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Anomaly scopes and scoring methods.
+const (
+	AnomalyScopeTotal    = "total"
+	AnomalyScopeProtocol = "protocol"
+
+	AnomalyMethodMAD    = "mad"
+	AnomalyMethodZScore = "zscore"
+)
+
+// minAnomalyMoves is the fewest past moves a move is scored against.
+const minAnomalyMoves = 5
+
+// minMoveDispersion floors the dispersion of past moves (in log return) so a
+// perfectly flat series still yields a finite score.
+const minMoveDispersion = 0.001
+
+// madScale converts a deviation over the MAD to a modified z-score
+// (Iglewicz and Hoaglin).
+const madScale = 0.6745
+
+// AnomalyOptions tunes anomaly detection. Moves are log returns between
+// cycles; the current move is scored against the moves over the last Window
+// snapshots and flagged when the score exceeds Threshold and the move is at
+// least MinChangePct percent. QuarantineCycles above zero holds a flagged
+// protocol at its previously published TVS for that many consecutive cycles;
+// a move still flagged on the cycle after is published.
+type AnomalyOptions struct {
+	Method           string
+	Window           int
+	Threshold        float64
+	MinChangePct     float64
+	QuarantineCycles int
+}
+
+// Anomaly is a flagged TVS move. Value is the reported TVS and Previous the
+// last published one. Quarantined is set when Previous was published again
+// in place of Value; Cycles counts the consecutive cycles the move has been
+// flagged.
+type Anomaly struct {
+	Scope       string  `json:"scope"`
+	Slug        string  `json:"slug,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	Timestamp   int64   `json:"timestamp"`
+	Date        string  `json:"date"`
+	Value       float64 `json:"value"`
+	Previous    float64 `json:"previous"`
+	ChangePct   float64 `json:"change_pct"`
+	Score       float64 `json:"score"`
+	Method      string  `json:"method"`
+	Quarantined bool    `json:"quarantined"`
+	Cycles      int     `json:"cycles"`
+}
+
+// DetectProtocolAnomalies flags protocols whose TVS moved anomalously since
+// the last snapshot before timestamp. With quarantine on, flagged protocols
+// are held in place at their previously published TVS, with TVSByChain scaled
+// to match, and QuarantineCycles set so the snapshot carries the count
+// forward. A nil opts detects nothing.
+func DetectProtocolAnomalies(protocols []AggregatedProtocol, history []Snapshot, timestamp int64, opts *AnomalyOptions) []Anomaly {
+	if opts == nil {
+		return nil
+	}
+	past := pastSnapshots(history, timestamp, opts.Window)
+	if len(past) == 0 {
+		return nil
+	}
+	last := past[len(past)-1]
+
+	var anomalies []Anomaly
+	for i := range protocols {
+		p := &protocols[i]
+		prev, ok := last.Protocols[p.Slug]
+		if !ok || p.Slug == "" {
+			continue
+		}
+
+		series := make([]float64, 0, len(past))
+		for _, s := range past {
+			if v, ok := s.Protocols[p.Slug]; ok {
+				series = append(series, v.TVS)
+			}
+		}
+		anomaly, flagged := scoreMove(series, p.TVS, opts)
+		if !flagged {
+			continue
+		}
+
+		anomaly.Scope = AnomalyScopeProtocol
+		anomaly.Slug = p.Slug
+		anomaly.Name = p.Name
+		anomaly.Cycles = prev.QuarantineCycles + 1
+		if anomaly.Cycles <= opts.QuarantineCycles {
+			holdProtocolTVS(p, prev.TVS)
+			p.QuarantineCycles = anomaly.Cycles
+			anomaly.Quarantined = true
+		}
+		anomalies = append(anomalies, stampAnomaly(anomaly, timestamp))
+	}
+
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return math.Abs(anomalies[i].Score) > math.Abs(anomalies[j].Score)
+	})
+	return anomalies
+}
+
+// DetectTotalAnomaly flags an anomalous move in total TVS since the last
+// snapshot before timestamp. The total is never held; it follows the
+// published protocol TVS. It returns nil when nothing is flagged.
+func DetectTotalAnomaly(totalTVS float64, history []Snapshot, timestamp int64, opts *AnomalyOptions) *Anomaly {
+	if opts == nil {
+		return nil
+	}
+	past := pastSnapshots(history, timestamp, opts.Window)
+	series := make([]float64, 0, len(past))
+	for _, s := range past {
+		series = append(series, s.TVS)
+	}
+	anomaly, flagged := scoreMove(series, totalTVS, opts)
+	if !flagged {
+		return nil
+	}
+	anomaly.Scope = AnomalyScopeTotal
+	anomaly.Cycles = 1
+	anomaly = stampAnomaly(anomaly, timestamp)
+	return &anomaly
+}
+
+// pastSnapshots returns up to window+1 snapshots taken before timestamp,
+// oldest first, giving window moves to score against.
+func pastSnapshots(history []Snapshot, timestamp int64, window int) []Snapshot {
+	past := make([]Snapshot, 0, len(history))
+	for _, s := range history {
+		if s.Timestamp < timestamp {
+			past = append(past, s)
+		}
+	}
+	sort.SliceStable(past, func(i, j int) bool { return past[i].Timestamp < past[j].Timestamp })
+	if len(past) > window+1 {
+		past = past[len(past)-window-1:]
+	}
+	return past
+}
+
+// scoreMove scores the move from the last value of series to current against
+// the moves within series.
+func scoreMove(series []float64, current float64, opts *AnomalyOptions) (Anomaly, bool) {
+	if len(series) == 0 {
+		return Anomaly{}, false
+	}
+	previous := series[len(series)-1]
+	if previous <= 0 || current <= 0 {
+		return Anomaly{}, false
+	}
+
+	var moves []float64
+	for i := 1; i < len(series); i++ {
+		if series[i-1] > 0 && series[i] > 0 {
+			moves = append(moves, math.Log(series[i]/series[i-1]))
+		}
+	}
+	if len(moves) < minAnomalyMoves {
+		return Anomaly{}, false
+	}
+
+	move := math.Log(current / previous)
+	method := strings.ToLower(strings.TrimSpace(opts.Method))
+	var score float64
+	switch method {
+	case AnomalyMethodZScore:
+		mean, std := meanStd(moves)
+		score = (move - mean) / math.Max(std, minMoveDispersion)
+	default:
+		method = AnomalyMethodMAD
+		center := median(moves)
+		deviations := make([]float64, len(moves))
+		for i, m := range moves {
+			deviations[i] = math.Abs(m - center)
+		}
+		score = madScale * (move - center) / math.Max(median(deviations), minMoveDispersion)
+	}
+
+	changePct := CalculatePercentageChange(previous, current)
+	if math.Abs(score) <= opts.Threshold || math.Abs(changePct) < opts.MinChangePct {
+		return Anomaly{}, false
+	}
+	return Anomaly{
+		Value:     current,
+		Previous:  previous,
+		ChangePct: changePct,
+		Score:     roundCents(score),
+		Method:    method,
+	}, true
+}
+
+// holdProtocolTVS publishes tvs in place of a protocol's reported TVS,
+// scaling its per-chain TVS to match.
+func holdProtocolTVS(p *AggregatedProtocol, tvs float64) {
+	if p.TVS > 0 {
+		scale := tvs / p.TVS
+		for chain, v := range p.TVSByChain {
+			p.TVSByChain[chain] = v * scale
+		}
+	}
+	p.TVS = tvs
+}
+
+func stampAnomaly(a Anomaly, timestamp int64) Anomaly {
+	a.Timestamp = timestamp
+	a.Date = time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+	return a
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func meanStd(values []float64) (float64, float64) {
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values) - 1)
+	return mean, math.Sqrt(variance)
+}
@@ -0,0 +1,147 @@
+package aggregator
+
+import (
+	"testing"
+)
+
+var testAnomalyOptions = AnomalyOptions{Method: AnomalyMethodMAD, Window: 24, Threshold: 3.5, MinChangePct: 20}
+
+// anomalyHistory returns snapshots two hours apart ending before ts, with
+// kamino and the total following values.
+func anomalyHistory(ts int64, values ...float64) []Snapshot {
+	history := make([]Snapshot, len(values))
+	for i, v := range values {
+		history[i] = Snapshot{
+			Timestamp: ts - int64(len(values)-i)*2*60*60,
+			TVS:       v,
+			Protocols: map[string]ProtocolSnapshot{"kamino": {TVS: v, Rank: 1}},
+		}
+	}
+	return history
+}
+
+func TestDetectProtocolAnomalies_FlagsSpike(t *testing.T) {
+	const ts = 1_733_000_000
+	history := anomalyHistory(ts, 100, 101, 99, 100, 102, 98, 100, 101)
+	protocols := []AggregatedProtocol{{Slug: "kamino", Name: "Kamino", TVS: 1_010, TVSByChain: map[string]float64{"Solana": 1_010}}}
+
+	got := DetectProtocolAnomalies(protocols, history, ts, &testAnomalyOptions)
+
+	if len(got) != 1 {
+		t.Fatalf("expected one anomaly, got %+v", got)
+	}
+	a := got[0]
+	if a.Scope != AnomalyScopeProtocol || a.Slug != "kamino" || a.Value != 1_010 || a.Previous != 101 || a.ChangePct != 900 {
+		t.Errorf("unexpected anomaly: %+v", a)
+	}
+	if a.Method != AnomalyMethodMAD || a.Score <= 3.5 || a.Quarantined || a.Cycles != 1 {
+		t.Errorf("unexpected scoring: %+v", a)
+	}
+	if protocols[0].TVS != 1_010 {
+		t.Errorf("TVS changed without quarantine: %v", protocols[0].TVS)
+	}
+
+	total := DetectTotalAnomaly(1_010, history, ts, &testAnomalyOptions)
+	if total == nil || total.Scope != AnomalyScopeTotal || total.Date != "2024-11-30" {
+		t.Errorf("expected total anomaly, got %+v", total)
+	}
+}
+
+func TestDetectProtocolAnomalies_IgnoresOrdinaryMoves(t *testing.T) {
+	const ts = 1_733_000_000
+	history := anomalyHistory(ts, 100, 101, 99, 100, 102, 98, 100, 101)
+
+	tests := []struct {
+		name    string
+		history []Snapshot
+		tvs     float64
+		opts    *AnomalyOptions
+	}{
+		{name: "within range", history: history, tvs: 103, opts: &testAnomalyOptions},
+		{name: "below min change", history: history, tvs: 115, opts: &testAnomalyOptions},
+		{name: "short history", history: history[len(history)-4:], tvs: 1_010, opts: &testAnomalyOptions},
+		{name: "disabled", history: history, tvs: 1_010, opts: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protocols := []AggregatedProtocol{{Slug: "kamino", TVS: tt.tvs}}
+			if got := DetectProtocolAnomalies(protocols, tt.history, ts, tt.opts); len(got) != 0 {
+				t.Fatalf("expected no anomalies, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestDetectProtocolAnomalies_ZScore(t *testing.T) {
+	const ts = 1_733_000_000
+	history := anomalyHistory(ts, 100, 101, 99, 100, 102, 98, 100, 101)
+	opts := AnomalyOptions{Method: AnomalyMethodZScore, Window: 24, Threshold: 3, MinChangePct: 20}
+
+	got := DetectProtocolAnomalies([]AggregatedProtocol{{Slug: "kamino", TVS: 10}}, history, ts, &opts)
+	if len(got) != 1 || got[0].Method != AnomalyMethodZScore || got[0].Score >= -3 {
+		t.Fatalf("expected a negative z-score anomaly, got %+v", got)
+	}
+}
+
+func TestDetectProtocolAnomalies_Quarantine(t *testing.T) {
+	const ts = 1_733_000_000
+	opts := testAnomalyOptions
+	opts.QuarantineCycles = 3
+
+	history := anomalyHistory(ts, 100, 101, 99, 100, 102, 98, 100, 100)
+	protocols := []AggregatedProtocol{{Slug: "kamino", TVS: 1_000, TVSByChain: map[string]float64{"Solana": 800, "Sui": 200}}}
+
+	got := DetectProtocolAnomalies(protocols, history, ts, &opts)
+	if len(got) != 1 || !got[0].Quarantined || got[0].Cycles != 1 || got[0].Value != 1_000 {
+		t.Fatalf("expected quarantined anomaly, got %+v", got)
+	}
+	p := protocols[0]
+	if p.TVS != 100 || p.TVSByChain["Solana"] != 80 || p.TVSByChain["Sui"] != 20 || p.QuarantineCycles != 1 {
+		t.Fatalf("expected TVS held at 100, got %+v", p)
+	}
+
+	// The move has now been held for three cycles; the fourth publishes it.
+	last := &history[len(history)-1]
+	last.Protocols["kamino"] = ProtocolSnapshot{TVS: 100, Rank: 1, QuarantineCycles: 3}
+	protocols = []AggregatedProtocol{{Slug: "kamino", TVS: 1_000}}
+
+	got = DetectProtocolAnomalies(protocols, history, ts, &opts)
+	if len(got) != 1 || got[0].Quarantined || got[0].Cycles != 4 {
+		t.Fatalf("expected released anomaly, got %+v", got)
+	}
+	if protocols[0].TVS != 1_000 || protocols[0].QuarantineCycles != 0 {
+		t.Fatalf("expected raw TVS published, got %+v", protocols[0])
+	}
+}
+
+func TestDetectProtocolAnomalies_QuarantineHoldsForNCycles(t *testing.T) {
+	const ts = 1_733_000_000
+	for _, n := range []int{0, 1, 2} {
+		opts := testAnomalyOptions
+		opts.QuarantineCycles = n
+		history := anomalyHistory(ts, 100, 101, 99, 100, 102, 98, 100, 100)
+
+		var held []bool
+		for cycle := int64(0); cycle <= int64(n); cycle++ {
+			now := ts + cycle*2*60*60
+			protocols := []AggregatedProtocol{{Slug: "kamino", TVS: 1_000}}
+			got := DetectProtocolAnomalies(protocols, history, now, &opts)
+			if len(got) != 1 || got[0].Cycles != int(cycle)+1 {
+				t.Fatalf("n=%d cycle %d: expected the move flagged with cycles=%d, got %+v", n, cycle, cycle+1, got)
+			}
+			held = append(held, got[0].Quarantined)
+			history = append(history, Snapshot{
+				Timestamp: now,
+				TVS:       protocols[0].TVS,
+				Protocols: map[string]ProtocolSnapshot{"kamino": {TVS: protocols[0].TVS, Rank: 1, QuarantineCycles: protocols[0].QuarantineCycles}},
+			})
+		}
+
+		for cycle, quarantined := range held {
+			if want := cycle < n; quarantined != want {
+				t.Errorf("n=%d: cycle %d quarantined = %v, want %v", n, cycle, quarantined, want)
+			}
+		}
+	}
+}
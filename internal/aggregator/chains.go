@@ -0,0 +1,39 @@
+package aggregator
+
+import "github.com/switchboard-xyz/defillama-extract/internal/chainregistry"
+
+// CanonicalChainTVS re-keys a per-chain TVS map by canonical chain name in
+// chains, summing chains that were reported under several names. A nil map
+// stays nil.
+func CanonicalChainTVS(byChain map[string]float64, chains *chainregistry.Registry) map[string]float64 {
+	if byChain == nil {
+		return nil
+	}
+	result := make(map[string]float64, len(byChain))
+	for chain, tvs := range byChain {
+		result[chains.Canonical(chain)] += tvs
+	}
+	return result
+}
+
+// CanonicalizeProtocolChains rewrites each protocol's Chains and TVSByChain in
+// place to canonical chain names.
+func CanonicalizeProtocolChains(protocols []AggregatedProtocol, chains *chainregistry.Registry) {
+	for i := range protocols {
+		protocols[i].Chains = chains.CanonicalList(protocols[i].Chains)
+		protocols[i].TVSByChain = CanonicalChainTVS(protocols[i].TVSByChain, chains)
+	}
+}
+
+// CanonicalizeSnapshots rewrites the TVSByChain of stored snapshots in place
+// to canonical chain names, so history written before a chain gained an alias
+// lines up with new snapshots. ChainCount is updated to match.
+func CanonicalizeSnapshots(history []Snapshot, chains *chainregistry.Registry) {
+	for i := range history {
+		if len(history[i].TVSByChain) == 0 {
+			continue
+		}
+		history[i].TVSByChain = CanonicalChainTVS(history[i].TVSByChain, chains)
+		history[i].ChainCount = len(history[i].TVSByChain)
+	}
+}
@@ -0,0 +1,68 @@
+package aggregator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/chainregistry"
+)
+
+func TestCalculateChainBreakdown_MergesChainAliases(t *testing.T) {
+	chains := chainregistry.New(nil, nil)
+	protocols := []AggregatedProtocol{
+		{TVSByChain: map[string]float64{"BSC": 100, "Binance": 50}},
+		{TVSByChain: map[string]float64{"bnb chain": 150, "Solana": 400}},
+	}
+
+	got := CalculateChainBreakdown(protocols, chains)
+
+	if len(got) != 2 {
+		t.Fatalf("expected BSC and Solana only, got %+v", got)
+	}
+	bsc := got[1]
+	if bsc.Chain != "BSC" || bsc.TVS != 300 || bsc.ProtocolCount != 2 {
+		t.Fatalf("BSC breakdown = %+v", bsc)
+	}
+	if bsc.DisplayName != "BNB Chain" || bsc.ChainID != 56 {
+		t.Fatalf("BSC registry details = %q %d", bsc.DisplayName, bsc.ChainID)
+	}
+	if got[0].Chain != "Solana" || got[0].DisplayName != "Solana" || got[0].ChainID != 0 {
+		t.Fatalf("Solana breakdown = %+v", got[0])
+	}
+}
+
+func TestCanonicalizeProtocolChains(t *testing.T) {
+	chains := chainregistry.New(nil, nil)
+	protocols := []AggregatedProtocol{{
+		Chains:     []string{"SUI", "Sui", "Fogo"},
+		TVSByChain: map[string]float64{"SUI": 10, "sui": 5},
+	}}
+
+	CanonicalizeProtocolChains(protocols, chains)
+
+	if want := []string{"Sui", "Fogo"}; !reflect.DeepEqual(protocols[0].Chains, want) {
+		t.Errorf("Chains = %v, want %v", protocols[0].Chains, want)
+	}
+	if want := map[string]float64{"Sui": 15}; !reflect.DeepEqual(protocols[0].TVSByChain, want) {
+		t.Errorf("TVSByChain = %v, want %v", protocols[0].TVSByChain, want)
+	}
+	if got := chains.Unrecognized(); !reflect.DeepEqual(got, []string{"Fogo"}) {
+		t.Errorf("Unrecognized = %v, want [Fogo]", got)
+	}
+}
+
+func TestCanonicalizeSnapshots(t *testing.T) {
+	history := []Snapshot{
+		{TVS: 30, TVSByChain: map[string]float64{"BSC": 10, "binance": 20}, ChainCount: 2},
+		{TVS: 0},
+	}
+
+	CanonicalizeSnapshots(history, chainregistry.New(nil, nil))
+
+	if want := map[string]float64{"BSC": 30}; !reflect.DeepEqual(history[0].TVSByChain, want) || history[0].ChainCount != 1 {
+		t.Fatalf("snapshot = %+v", history[0])
+	}
+	if history[1].TVSByChain != nil {
+		t.Fatalf("empty snapshot should be left alone, got %+v", history[1])
+	}
+}
@@ -0,0 +1,111 @@
+package aggregator
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/api"
+)
+
+// ApplyChainShare sets ChainTVL and ChainTVLShare on every breakdown entry
+// whose chain has a positive total in chainTVL. Chain names are matched
+// case-insensitively; unmatched entries are left unset.
+func ApplyChainShare(breakdown []ChainBreakdown, chainTVL map[string]float64) {
+	if len(chainTVL) == 0 {
+		return
+	}
+
+	totals := make(map[string]float64, len(chainTVL))
+	for chain, tvl := range chainTVL {
+		totals[strings.ToLower(chain)] = tvl
+	}
+
+	for i := range breakdown {
+		total := totals[strings.ToLower(breakdown[i].Chain)]
+		if total <= 0 {
+			continue
+		}
+
+		share := (breakdown[i].TVS / total) * 100
+		breakdown[i].ChainTVL = total
+		breakdown[i].ChainTVLShare = &share
+	}
+}
+
+// CalculateChainShareHistory builds a daily share series for every chain in
+// chainTVL from the per-chain TVS recorded in history. The latest snapshot and
+// the latest chain TVL point of each UTC day are paired; days missing either,
+// or with no positive chain TVL, are skipped. It returns nil when no chain has
+// a single point.
+func CalculateChainShareHistory(history []Snapshot, chainTVL map[string][]api.ChainTVLPoint) map[string][]ChainSharePoint {
+	if len(history) == 0 || len(chainTVL) == 0 {
+		return nil
+	}
+
+	daily := make(map[string]Snapshot, len(history))
+	for _, snapshot := range history {
+		date := time.Unix(snapshot.Timestamp, 0).UTC().Format("2006-01-02")
+		if existing, ok := daily[date]; !ok || snapshot.Timestamp > existing.Timestamp {
+			daily[date] = snapshot
+		}
+	}
+
+	result := make(map[string][]ChainSharePoint)
+	for chain, points := range chainTVL {
+		totals := make(map[string]api.ChainTVLPoint, len(points))
+		for _, point := range points {
+			date := time.Unix(point.Date, 0).UTC().Format("2006-01-02")
+			if existing, ok := totals[date]; !ok || point.Date > existing.Date {
+				totals[date] = point
+			}
+		}
+
+		series := make([]ChainSharePoint, 0, len(daily))
+		for date, snapshot := range daily {
+			total, ok := totals[date]
+			if !ok || total.TVL <= 0 {
+				continue
+			}
+
+			tvs, ok := chainTVS(snapshot.TVSByChain, chain)
+			if !ok {
+				continue
+			}
+
+			series = append(series, ChainSharePoint{
+				Timestamp: snapshot.Timestamp,
+				Date:      date,
+				TVS:       tvs,
+				ChainTVL:  total.TVL,
+				Share:     (tvs / total.TVL) * 100,
+			})
+		}
+		if len(series) == 0 {
+			continue
+		}
+
+		sort.Slice(series, func(i, j int) bool {
+			return series[i].Timestamp < series[j].Timestamp
+		})
+		result[chain] = series
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// chainTVS looks chain up in byChain, falling back to a case-insensitive match.
+func chainTVS(byChain map[string]float64, chain string) (float64, bool) {
+	if tvs, ok := byChain[chain]; ok {
+		return tvs, true
+	}
+	for name, tvs := range byChain {
+		if strings.EqualFold(name, chain) {
+			return tvs, true
+		}
+	}
+	return 0, false
+}
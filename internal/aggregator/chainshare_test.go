@@ -0,0 +1,71 @@
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/api"
+)
+
+func TestApplyChainShare_MatchesChainsCaseInsensitively(t *testing.T) {
+	breakdown := []ChainBreakdown{
+		{Chain: "Sui", TVS: 50},
+		{Chain: "Aptos", TVS: 10},
+		{Chain: "Eclipse", TVS: 5},
+	}
+
+	ApplyChainShare(breakdown, map[string]float64{"sui": 1000, "Aptos": 0})
+
+	if breakdown[0].ChainTVL != 1000 || breakdown[0].ChainTVLShare == nil || *breakdown[0].ChainTVLShare != 5 {
+		t.Fatalf("unexpected Sui share: %+v", breakdown[0])
+	}
+	if breakdown[1].ChainTVLShare != nil || breakdown[1].ChainTVL != 0 {
+		t.Fatalf("expected no share for zero chain TVL, got %+v", breakdown[1])
+	}
+	if breakdown[2].ChainTVLShare != nil {
+		t.Fatalf("expected no share for unknown chain, got %+v", breakdown[2])
+	}
+}
+
+func TestCalculateChainShareHistory_PairsLatestPointsPerDay(t *testing.T) {
+	day := int64(86400)
+	history := []Snapshot{
+		{Timestamp: 1*day + 100, TVSByChain: map[string]float64{"Sui": 5}},
+		{Timestamp: 1*day + 200, TVSByChain: map[string]float64{"Sui": 10}},
+		{Timestamp: 2*day + 100, TVSByChain: map[string]float64{"sui": 30}},
+		{Timestamp: 3*day + 100, TVSByChain: map[string]float64{"Solana": 1}},
+		{Timestamp: 4*day + 100, TVSByChain: map[string]float64{"Sui": 40}},
+	}
+	chainTVL := map[string][]api.ChainTVLPoint{
+		"Sui": {
+			{Date: 1 * day, TVL: 100},
+			{Date: 2 * day, TVL: 500},
+			{Date: 2*day + 300, TVL: 600},
+			{Date: 3 * day, TVL: 700},
+			{Date: 4 * day, TVL: 0},
+		},
+		"Aptos": {{Date: 1 * day, TVL: 100}},
+	}
+
+	got := CalculateChainShareHistory(history, chainTVL)
+
+	if _, ok := got["Aptos"]; ok {
+		t.Fatalf("expected chains without TVS history to be omitted, got %+v", got)
+	}
+	series := got["Sui"]
+	want := []ChainSharePoint{
+		{Timestamp: 1*day + 200, Date: "1970-01-02", TVS: 10, ChainTVL: 100, Share: 10},
+		{Timestamp: 2*day + 100, Date: "1970-01-03", TVS: 30, ChainTVL: 600, Share: 5},
+	}
+	if len(series) != len(want) {
+		t.Fatalf("expected %d points, got %+v", len(want), series)
+	}
+	for i := range want {
+		if series[i] != want[i] {
+			t.Fatalf("point %d: got %+v want %+v", i, series[i], want[i])
+		}
+	}
+
+	if CalculateChainShareHistory(nil, chainTVL) != nil {
+		t.Fatalf("expected nil series without history")
+	}
+}
@@ -18,8 +18,9 @@ type ChartDataPoint struct {
 }
 
 // ExtractChartHistory converts oracle chart data into a sorted slice of ChartDataPoint for the given oracle.
+// TVS is the chart's plain TVL adjusted by policy; Borrowed and Staking report the raw components.
 // It returns an empty slice when no chart data is available; never nil.
-func ExtractChartHistory(oracleResp *api.OracleAPIResponse, oracleName string) []ChartDataPoint {
+func ExtractChartHistory(oracleResp *api.OracleAPIResponse, oracleName string, policy TVSPolicy) []ChartDataPoint {
 	if oracleResp == nil || len(oracleResp.Chart) == 0 {
 		return []ChartDataPoint{}
 	}
@@ -36,7 +37,7 @@ func ExtractChartHistory(oracleResp *api.OracleAPIResponse, oracleName string) [
 			continue
 		}
 
-		tvs := oracleEntry["tvl"]
+		tvs := policy.chartTVS(oracleEntry)
 		borrowed := oracleEntry["borrowed"]
 		staking := oracleEntry["staking"]
 
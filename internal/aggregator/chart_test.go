@@ -22,7 +22,7 @@ func TestExtractChartHistory_FiltersOracleAndSorts(t *testing.T) {
 		},
 	}
 
-	got := ExtractChartHistory(resp, "Switchboard")
+	got := ExtractChartHistory(resp, "Switchboard", testPolicy)
 
 	want := []ChartDataPoint{
 		{Timestamp: 1, Date: "1970-01-01", TVS: 10},
@@ -58,7 +58,7 @@ func TestExtractChartHistory_EmptyWhenNoData(t *testing.T) {
 
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ExtractChartHistory(tt.resp, "Switchboard")
+			got := ExtractChartHistory(tt.resp, "Switchboard", testPolicy)
 			if got == nil {
 				t.Fatalf("expected empty slice, got nil")
 			}
@@ -0,0 +1,98 @@
+package aggregator
+
+import (
+	"math"
+	"sort"
+)
+
+// Concentration measures how unevenly TVS is spread across a set of
+// protocols, chains or categories with TVS. HHI is the Herfindahl-Hirschman
+// Index on percentage shares (0-10000, above 2500 is highly concentrated);
+// the top shares are percentages of total TVS; Gini runs from 0 (even) to 1
+// (one member holds everything).
+type Concentration struct {
+	Count      int     `json:"count"`
+	HHI        float64 `json:"hhi"`
+	Top1Share  float64 `json:"top_1_share"`
+	Top5Share  float64 `json:"top_5_share"`
+	Top10Share float64 `json:"top_10_share"`
+	Gini       float64 `json:"gini"`
+}
+
+// ConcentrationMetrics holds concentration across protocols, chains and
+// source categories.
+type ConcentrationMetrics struct {
+	Protocols  Concentration `json:"protocols"`
+	Chains     Concentration `json:"chains"`
+	Categories Concentration `json:"categories"`
+}
+
+// CalculateConcentrationMetrics computes concentration from protocol TVS and
+// the chain and category breakdowns.
+func CalculateConcentrationMetrics(protocols []AggregatedProtocol, chains []ChainBreakdown, categories []CategoryBreakdown) *ConcentrationMetrics {
+	protocolTVS := make([]float64, 0, len(protocols))
+	for _, p := range protocols {
+		protocolTVS = append(protocolTVS, p.TVS)
+	}
+	chainTVS := make([]float64, 0, len(chains))
+	for _, c := range chains {
+		chainTVS = append(chainTVS, c.TVS)
+	}
+	categoryTVS := make([]float64, 0, len(categories))
+	for _, c := range categories {
+		categoryTVS = append(categoryTVS, c.TVS)
+	}
+
+	return &ConcentrationMetrics{
+		Protocols:  CalculateConcentration(protocolTVS),
+		Chains:     CalculateConcentration(chainTVS),
+		Categories: CalculateConcentration(categoryTVS),
+	}
+}
+
+// CalculateConcentration computes concentration over values, ignoring values
+// that are not positive. All fields are zero when no value is positive.
+func CalculateConcentration(values []float64) Concentration {
+	positive := make([]float64, 0, len(values))
+	var total float64
+	for _, v := range values {
+		if v > 0 {
+			positive = append(positive, v)
+			total += v
+		}
+	}
+	if len(positive) == 0 {
+		return Concentration{}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(positive)))
+
+	c := Concentration{Count: len(positive)}
+	var hhi, top1, top5, top10 float64
+	for i, v := range positive {
+		share := v / total * 100
+		hhi += share * share
+		if i < 1 {
+			top1 += share
+		}
+		if i < 5 {
+			top5 += share
+		}
+		if i < 10 {
+			top10 += share
+		}
+	}
+	c.HHI = roundCents(hhi)
+	c.Top1Share = roundCents(top1)
+	c.Top5Share = roundCents(top5)
+	c.Top10Share = roundCents(top10)
+
+	// Gini over values ranked ascending: 2*sum(i*x_i)/(n*sum(x)) - (n+1)/n.
+	n := float64(len(positive))
+	var weighted float64
+	for i, v := range positive {
+		weighted += float64(len(positive)-i) * v
+	}
+	gini := 2*weighted/(n*total) - (n+1)/n
+	c.Gini = math.Round(gini*10000) / 10000
+	return c
+}
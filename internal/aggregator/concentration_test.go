@@ -0,0 +1,67 @@
+package aggregator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCalculateConcentration(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   Concentration
+	}{
+		{
+			name:   "single holder",
+			values: []float64{500, 0},
+			want:   Concentration{Count: 1, HHI: 10_000, Top1Share: 100, Top5Share: 100, Top10Share: 100, Gini: 0},
+		},
+		{
+			name:   "even split",
+			values: []float64{25, 25, 25, 25},
+			want:   Concentration{Count: 4, HHI: 2_500, Top1Share: 25, Top5Share: 100, Top10Share: 100, Gini: 0},
+		},
+		{
+			name:   "skewed",
+			values: []float64{10, 70, 20},
+			// Ascending 10, 20, 70: 2*(10+40+210)/(3*100) - 4/3 = 0.4.
+			want: Concentration{Count: 3, HHI: 5_400, Top1Share: 70, Top5Share: 100, Top10Share: 100, Gini: 0.4},
+		},
+		{
+			name:   "long tail",
+			values: []float64{40, 10, 10, 10, 10, 5, 5, 5, 1, 1, 1, 1, 1},
+			want:   Concentration{Count: 13, HHI: 2_080, Top1Share: 40, Top5Share: 80, Top10Share: 97, Gini: 0.5538},
+		},
+		{
+			name:   "empty",
+			values: nil,
+			want:   Concentration{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalculateConcentration(tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateConcentrationMetrics(t *testing.T) {
+	got := CalculateConcentrationMetrics(
+		[]AggregatedProtocol{{TVS: 90}, {TVS: 10}, {TVS: 0}},
+		[]ChainBreakdown{{Chain: "Solana", TVS: 100}},
+		[]CategoryBreakdown{{Category: "Lending", TVS: 50}, {Category: "Dexs", TVS: 50}},
+	)
+
+	if got.Protocols.Count != 2 || got.Protocols.Top1Share != 90 || got.Protocols.HHI != 8_200 {
+		t.Errorf("protocols = %+v", got.Protocols)
+	}
+	if got.Chains.HHI != 10_000 || got.Chains.Top1Share != 100 {
+		t.Errorf("chains = %+v", got.Chains)
+	}
+	if got.Categories.HHI != 5_000 || got.Categories.Gini != 0 {
+		t.Errorf("categories = %+v", got.Categories)
+	}
+}
@@ -9,34 +9,19 @@ import (
 )
 
 // ExtractProtocolData enriches filtered protocols with TVS data and returns the latest timestamp
-// along with counts of protocols that have/are missing TVS.
-func ExtractProtocolData(protocols []api.Protocol, oracleResp *api.OracleAPIResponse, oracleName string) ([]AggregatedProtocol, int64, int, int) {
+// along with a report of how protocols were matched to oraclesTVS keys (see ResolveProtocols).
+// TVS is counted under policy; aliases pins protocol slugs or names to oraclesTVS keys.
+func ExtractProtocolData(protocols []api.Protocol, oracleResp *api.OracleAPIResponse, oracleName string, policy TVSPolicy, aliases map[string]string) ([]AggregatedProtocol, int64, *ProtocolMatchReport) {
 	timestamp := ExtractLatestTimestamp(oracleResp)
+	matches := ResolveProtocols(protocols, oracleTVSKeys(oracleResp, oracleName), aliases)
+	report := NewProtocolMatchReport(protocols, matches, oracleResp, oracleName, policy)
 	if len(protocols) == 0 {
-		return []AggregatedProtocol{}, timestamp, 0, 0
+		return []AggregatedProtocol{}, timestamp, report
 	}
 
 	logger := slog.Default()
 	result := make([]AggregatedProtocol, 0, len(protocols))
-	withTVS := 0
-	withoutTVS := 0
-
-	if oracleResp == nil {
-		for _, p := range protocols {
-			result = append(result, AggregatedProtocol{
-				Name:       p.Name,
-				Slug:       p.Slug,
-				Category:   p.Category,
-				URL:        p.URL,
-				TVL:        p.TVL,
-				Chains:     copyChains(p.Chains),
-				TVSByChain: make(map[string]float64),
-			})
-		}
-		return result, timestamp, 0, len(protocols)
-	}
-
-	for _, p := range protocols {
+	for i, p := range protocols {
 		agg := AggregatedProtocol{
 			Name:       p.Name,
 			Slug:       p.Slug,
@@ -47,37 +32,32 @@ func ExtractProtocolData(protocols []api.Protocol, oracleResp *api.OracleAPIResp
 			TVSByChain: make(map[string]float64),
 		}
 
-		slugKey := strings.TrimSpace(p.Slug)
-		nameKey := strings.TrimSpace(p.Name)
-		protocolKey := slugKey
-		if protocolKey == "" {
-			protocolKey = nameKey
-		}
-
-		total, byChain, found := ExtractProtocolTVS(oracleResp.OraclesTVS, oracleName, protocolKey)
-		if !found && nameKey != "" && nameKey != protocolKey {
-			total, byChain, found = ExtractProtocolTVS(oracleResp.OraclesTVS, oracleName, nameKey)
-			if !found {
-				protocolKey = nameKey
-			}
-		}
-
-		if found {
+		match := matches[i]
+		if match.Key != "" {
+			total, byChain, _ := ExtractProtocolTVS(oracleResp.OraclesTVS, oracleName, match.Key, policy)
 			agg.TVS = total
 			agg.TVSByChain = byChain
-			withTVS++
+			agg.MatchStrategy = match.Strategy
 		} else {
-			withoutTVS++
+			protocolKey := strings.TrimSpace(p.Slug)
+			if protocolKey == "" {
+				protocolKey = strings.TrimSpace(p.Name)
+			}
+			reason := "not found in oraclesTVS"
+			if len(match.Candidates) > 0 {
+				reason = "ambiguous in oraclesTVS"
+			}
 			logger.Warn("protocol_tvs_unavailable",
 				"protocol", protocolKey,
-				"reason", "not found in oraclesTVS",
+				"reason", reason,
+				"candidates", match.Candidates,
 			)
 		}
 
 		result = append(result, agg)
 	}
 
-	return result, timestamp, withTVS, withoutTVS
+	return result, timestamp, report
 }
 
 // ExtractLatestTimestamp returns the latest Unix timestamp found in the oracle chart data.
@@ -33,7 +33,8 @@ func TestExtractProtocolData_PopulatesMetadataAndTVS(t *testing.T) {
 		Chains:   []string{"Solana"},
 	}}
 
-	got, ts, withTVS, withoutTVS := ExtractProtocolData(protocols, oracleResp, "Switchboard")
+	got, ts, report := ExtractProtocolData(protocols, oracleResp, "Switchboard", testPolicy, nil)
+	withTVS, withoutTVS := report.Matched, report.ProtocolCount-report.Matched
 
 	if ts != 1732924800 {
 		t.Fatalf("timestamp mismatch: got %d, want %d", ts, 1732924800)
@@ -80,7 +81,8 @@ func TestExtractProtocolData_MultiChainTVS(t *testing.T) {
 		Chains: []string{"Solana", "Sui"},
 	}}
 
-	got, _, withTVS, withoutTVS := ExtractProtocolData(protocols, oracleResp, "Switchboard")
+	got, _, report := ExtractProtocolData(protocols, oracleResp, "Switchboard", testPolicy, nil)
+	withTVS, withoutTVS := report.Matched, report.ProtocolCount-report.Matched
 
 	agg := got[0]
 	if agg.TVS != 1_000_000 {
@@ -107,7 +109,8 @@ func TestExtractProtocolData_HandlesMissingChains(t *testing.T) {
 		Slug: "no-chains",
 	}}
 
-	got, ts, withTVS, withoutTVS := ExtractProtocolData(protocols, oracleResp, "Switchboard")
+	got, ts, report := ExtractProtocolData(protocols, oracleResp, "Switchboard", testPolicy, nil)
+	withTVS, withoutTVS := report.Matched, report.ProtocolCount-report.Matched
 
 	if ts != 1733000000 {
 		t.Fatalf("timestamp = %d, want 1733000000", ts)
@@ -139,7 +142,8 @@ func TestExtractProtocolData_MissingOracleData(t *testing.T) {
 		Chains: []string{"Solana"},
 	}}
 
-	got, ts, withTVS, withoutTVS := ExtractProtocolData(protocols, oracleResp, "Switchboard")
+	got, ts, report := ExtractProtocolData(protocols, oracleResp, "Switchboard", testPolicy, nil)
+	withTVS, withoutTVS := report.Matched, report.ProtocolCount-report.Matched
 
 	if ts != 1733000000 {
 		t.Fatalf("timestamp = %d, want 1733000000", ts)
@@ -161,7 +165,8 @@ func TestExtractProtocolData_MissingOracleData(t *testing.T) {
 func TestExtractProtocolData_EmptyInputs(t *testing.T) {
 	oracleResp := &api.OracleAPIResponse{Chart: map[string]map[string]map[string]float64{"1733000000": {}}}
 
-	got, ts, withTVS, withoutTVS := ExtractProtocolData(nil, oracleResp, "Switchboard")
+	got, ts, report := ExtractProtocolData(nil, oracleResp, "Switchboard", testPolicy, nil)
+	withTVS, withoutTVS := report.Matched, report.ProtocolCount-report.Matched
 	if ts != 1733000000 {
 		t.Fatalf("timestamp = %d, want 1733000000", ts)
 	}
@@ -242,7 +247,8 @@ func TestExtractProtocolData_LogsWarningWhenMissingTVS(t *testing.T) {
 
 	protocols := []api.Protocol{{Slug: "missing-proto"}}
 
-	_, _, withTVS, withoutTVS := ExtractProtocolData(protocols, oracleResp, "Switchboard")
+	_, _, report := ExtractProtocolData(protocols, oracleResp, "Switchboard", testPolicy, nil)
+	withTVS, withoutTVS := report.Matched, report.ProtocolCount-report.Matched
 
 	if withTVS != 0 || withoutTVS != 1 {
 		t.Fatalf("expected counts with=0 without=1, got with=%d without=%d", withTVS, withoutTVS)
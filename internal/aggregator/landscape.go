@@ -0,0 +1,289 @@
+package aggregator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/api"
+)
+
+// OracleStanding is one oracle's position within a market: all of DefiLlama,
+// one chain, or one protocol category. Share is the percentage of the
+// market's TVS secured by the oracle.
+type OracleStanding struct {
+	Oracle        string  `json:"oracle"`
+	TVS           float64 `json:"tvs"`
+	Share         float64 `json:"share"`
+	Rank          int     `json:"rank"`
+	ProtocolCount int     `json:"protocol_count"`
+}
+
+// LandscapeMarket ranks every oracle securing value in one chain or category.
+// Position is the configured oracle's standing, nil when it is absent.
+type LandscapeMarket struct {
+	Name     string           `json:"name"`
+	TVS      float64          `json:"tvs"`
+	Oracles  []OracleStanding `json:"oracles"`
+	Position *OracleStanding  `json:"position,omitempty"`
+}
+
+// ShareMovement compares the configured oracle's share of total oracle TVS
+// and its rank on the latest chart point with the point Period earlier.
+// RankChange is positive when the oracle moved up.
+type ShareMovement struct {
+	Period            string  `json:"period"`
+	Timestamp         int64   `json:"timestamp"`
+	Share             float64 `json:"share"`
+	PreviousTimestamp int64   `json:"previous_timestamp"`
+	PreviousShare     float64 `json:"previous_share"`
+	ShareChange       float64 `json:"share_change"`
+	Rank              int     `json:"rank"`
+	PreviousRank      int     `json:"previous_rank"`
+	RankChange        int     `json:"rank_change"`
+}
+
+// OracleLandscape ranks every oracle in the /oracles response overall, per
+// chain and per category, from the configured oracle's point of view.
+type OracleLandscape struct {
+	Oracle     string            `json:"oracle"`
+	Timestamp  int64             `json:"timestamp"`
+	TotalTVS   float64           `json:"total_tvs"`
+	Overall    []OracleStanding  `json:"overall"`
+	Position   *OracleStanding   `json:"position,omitempty"`
+	ByChain    []LandscapeMarket `json:"by_chain"`
+	ByCategory []LandscapeMarket `json:"by_category"`
+	Movement   []ShareMovement   `json:"movement"`
+}
+
+// landscapePeriods are the movement windows reported from the chart.
+var landscapePeriods = []struct {
+	name    string
+	seconds int64
+}{
+	{"24h", Hours24},
+	{"7d", Days7},
+	{"30d", Days30},
+}
+
+// chartPointTolerance matches a movement target to a daily chart point.
+const chartPointTolerance = Hours24 / 2
+
+// landscapeTally accumulates TVS and protocol counts per oracle in a market.
+type landscapeTally map[string]*OracleStanding
+
+func (t landscapeTally) add(oracle string, tvs float64) {
+	s, ok := t[oracle]
+	if !ok {
+		s = &OracleStanding{Oracle: oracle}
+		t[oracle] = s
+	}
+	s.TVS += tvs
+	s.ProtocolCount++
+}
+
+// CalculateOracleLandscape ranks all oracles in oracleResp by TVS overall,
+// per chain and per category (looked up in index; "Uncategorized" when
+// unknown), and reports oracleName's share and rank movement over 24h, 7d and
+// 30d from the chart. TVS is counted under policy, as in ExtractProtocolTVS.
+// It returns nil when the response has no oraclesTVS.
+func CalculateOracleLandscape(oracleResp *api.OracleAPIResponse, index *api.ProtocolIndex, oracleName string, policy TVSPolicy) *OracleLandscape {
+	if oracleResp == nil || len(oracleResp.OraclesTVS) == 0 {
+		return nil
+	}
+
+	overall := landscapeTally{}
+	byChain := map[string]landscapeTally{}
+	byCategory := map[string]landscapeTally{}
+
+	for oracle, protocols := range oracleResp.OraclesTVS {
+		for key := range protocols {
+			total, chains, found := ExtractProtocolTVS(oracleResp.OraclesTVS, oracle, key, policy)
+			if !found || total <= 0 {
+				continue
+			}
+
+			overall.add(oracle, total)
+			for chain, tvs := range chains {
+				if tvs <= 0 {
+					continue
+				}
+				if byChain[chain] == nil {
+					byChain[chain] = landscapeTally{}
+				}
+				byChain[chain].add(oracle, tvs)
+			}
+
+			category := protocolCategory(index, key)
+			if byCategory[category] == nil {
+				byCategory[category] = landscapeTally{}
+			}
+			byCategory[category].add(oracle, total)
+		}
+	}
+
+	landscape := &OracleLandscape{
+		Oracle:     oracleName,
+		Timestamp:  ExtractLatestTimestamp(oracleResp),
+		ByChain:    markets(byChain, oracleName),
+		ByCategory: markets(byCategory, oracleName),
+		Movement:   shareMovement(oracleResp.Chart, oracleName, policy),
+	}
+	landscape.TotalTVS, landscape.Overall, landscape.Position = rankTally(overall, oracleName)
+
+	return landscape
+}
+
+// protocolCategory resolves the category of an oraclesTVS key, which is a
+// slug or, for some protocols, a display name.
+func protocolCategory(index *api.ProtocolIndex, key string) string {
+	ref, ok := index.LookupSlug(key)
+	if !ok {
+		ref, ok = index.LookupName(key)
+	}
+	if !ok || strings.TrimSpace(ref.Category) == "" {
+		return uncategorized
+	}
+	return ref.Category
+}
+
+// rankTally sorts a market's oracles by TVS descending (ties by name), fills
+// in share and rank, and returns the market total and oracleName's standing.
+func rankTally(tally landscapeTally, oracleName string) (float64, []OracleStanding, *OracleStanding) {
+	var total float64
+	standings := make([]OracleStanding, 0, len(tally))
+	for _, s := range tally {
+		total += s.TVS
+		standings = append(standings, *s)
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		if standings[i].TVS != standings[j].TVS {
+			return standings[i].TVS > standings[j].TVS
+		}
+		return standings[i].Oracle < standings[j].Oracle
+	})
+
+	var position *OracleStanding
+	for i := range standings {
+		standings[i].Rank = i + 1
+		if total > 0 {
+			standings[i].Share = (standings[i].TVS / total) * 100
+		}
+		if strings.EqualFold(standings[i].Oracle, oracleName) {
+			s := standings[i]
+			position = &s
+		}
+	}
+	return total, standings, position
+}
+
+// markets ranks every market and sorts them by total TVS descending.
+func markets(tallies map[string]landscapeTally, oracleName string) []LandscapeMarket {
+	result := make([]LandscapeMarket, 0, len(tallies))
+	for name, tally := range tallies {
+		market := LandscapeMarket{Name: name}
+		market.TVS, market.Oracles, market.Position = rankTally(tally, oracleName)
+		result = append(result, market)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TVS != result[j].TVS {
+			return result[i].TVS > result[j].TVS
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// shareMovement reports oracleName's share and rank on the latest chart point
+// against the chart point closest to each period earlier. Periods without a
+// point within a day's tolerance, or where the oracle is absent from either
+// point, are omitted.
+func shareMovement(chart map[string]map[string]map[string]float64, oracleName string, policy TVSPolicy) []ShareMovement {
+	if len(chart) == 0 {
+		return []ShareMovement{}
+	}
+
+	timestamps := make([]int64, 0, len(chart))
+	points := make(map[int64]map[string]map[string]float64, len(chart))
+	for tsStr, oracles := range chart {
+		ts, err := strconv.ParseInt(tsStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		timestamps = append(timestamps, ts)
+		points[ts] = oracles
+	}
+	if len(timestamps) == 0 {
+		return []ShareMovement{}
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+
+	standing := func(ts int64) *OracleStanding {
+		tally := landscapeTally{}
+		for oracle, values := range points[ts] {
+			if tvs := policy.chartTVS(values); tvs > 0 {
+				tally.add(oracle, tvs)
+			}
+		}
+		_, _, position := rankTally(tally, oracleName)
+		return position
+	}
+
+	latest := timestamps[len(timestamps)-1]
+	current := standing(latest)
+	movements := make([]ShareMovement, 0, len(landscapePeriods))
+	if current == nil {
+		return movements
+	}
+
+	for _, period := range landscapePeriods {
+		previousTS, ok := closestTimestamp(timestamps, latest-period.seconds, chartPointTolerance)
+		if !ok {
+			continue
+		}
+		previous := standing(previousTS)
+		if previous == nil {
+			continue
+		}
+		movements = append(movements, ShareMovement{
+			Period:            period.name,
+			Timestamp:         latest,
+			Share:             current.Share,
+			PreviousTimestamp: previousTS,
+			PreviousShare:     previous.Share,
+			ShareChange:       current.Share - previous.Share,
+			Rank:              current.Rank,
+			PreviousRank:      previous.Rank,
+			RankChange:        previous.Rank - current.Rank,
+		})
+	}
+	return movements
+}
+
+// closestTimestamp returns the timestamp nearest target within tolerance.
+func closestTimestamp(timestamps []int64, target, tolerance int64) (int64, bool) {
+	var (
+		best  int64
+		found bool
+	)
+	for _, ts := range timestamps {
+		diff := ts - target
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > tolerance {
+			continue
+		}
+		if !found || diff < abs64(best-target) {
+			best, found = ts, true
+		}
+	}
+	return best, found
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,135 @@
+package aggregator
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/api"
+)
+
+func landscapeResponse() *api.OracleAPIResponse {
+	day := int64(24 * 60 * 60)
+	latest := int64(1_700_000_000)
+	chart := map[string]map[string]map[string]float64{
+		strconv.FormatInt(latest, 10): {
+			"Switchboard": {"tvl": 300},
+			"Pyth":        {"tvl": 600},
+			"Chainlink":   {"tvl": 100},
+		},
+		strconv.FormatInt(latest-day, 10): {
+			"Switchboard": {"tvl": 100},
+			"Pyth":        {"tvl": 700},
+			"Chainlink":   {"tvl": 200},
+		},
+		strconv.FormatInt(latest-7*day+3600, 10): {
+			"Switchboard": {"tvl": 50},
+			"Pyth":        {"tvl": 50},
+		},
+	}
+
+	return &api.OracleAPIResponse{
+		Chart: chart,
+		OraclesTVS: map[string]map[string]map[string]float64{
+			"Switchboard": {
+				"kamino": {"Solana": 200, "Solana-borrowed": 999},
+				"navi":   {"Sui": 100},
+			},
+			"Pyth": {
+				"drift":   {"Solana": 500},
+				"Suilend": {"Sui": 50},
+			},
+			"Chainlink": {
+				"aave": {"Ethereum": 1000},
+			},
+		},
+	}
+}
+
+func TestCalculateOracleLandscape_RanksOverallChainAndCategory(t *testing.T) {
+	index := api.NewProtocolIndex([]api.ProtocolRef{
+		{Slug: "kamino", Name: "Kamino", Category: "Lending"},
+		{Slug: "navi", Name: "NAVI", Category: "Lending"},
+		{Slug: "drift", Name: "Drift", Category: "Derivatives"},
+		{Slug: "suilend", Name: "Suilend", Category: "Lending"},
+		{Slug: "aave", Name: "Aave", Category: "Lending"},
+	})
+
+	got := CalculateOracleLandscape(landscapeResponse(), index, "Switchboard", testPolicy)
+	if got == nil {
+		t.Fatalf("expected landscape")
+	}
+
+	if got.TotalTVS != 1850 || len(got.Overall) != 3 {
+		t.Fatalf("unexpected overall totals: %+v", got)
+	}
+	if got.Overall[0].Oracle != "Chainlink" || got.Overall[1].Oracle != "Pyth" {
+		t.Fatalf("unexpected overall order: %+v", got.Overall)
+	}
+	if got.Position == nil || got.Position.Rank != 3 || got.Position.ProtocolCount != 2 {
+		t.Fatalf("unexpected position: %+v", got.Position)
+	}
+	if math.Abs(got.Position.Share-300.0/1850*100) > 1e-9 {
+		t.Fatalf("unexpected overall share %v", got.Position.Share)
+	}
+
+	var sui *LandscapeMarket
+	for i := range got.ByChain {
+		if got.ByChain[i].Name == "Sui" {
+			sui = &got.ByChain[i]
+		}
+	}
+	if sui == nil || sui.Position == nil || sui.Position.Rank != 1 || math.Abs(sui.Position.Share-100.0/150*100) > 1e-9 {
+		t.Fatalf("unexpected Sui market: %+v", sui)
+	}
+	if got.ByChain[0].Name != "Ethereum" {
+		t.Fatalf("expected chains sorted by TVS, got %+v", got.ByChain)
+	}
+	for _, m := range got.ByChain {
+		if m.Name == "Ethereum" && m.Position != nil {
+			t.Fatalf("expected no position where the oracle is absent: %+v", m)
+		}
+	}
+
+	if len(got.ByCategory) != 2 || got.ByCategory[0].Name != "Lending" {
+		t.Fatalf("unexpected categories: %+v", got.ByCategory)
+	}
+	lending := got.ByCategory[0]
+	if lending.TVS != 1350 || lending.Position == nil || lending.Position.Rank != 2 {
+		t.Fatalf("unexpected Lending market: %+v", lending)
+	}
+}
+
+func TestCalculateOracleLandscape_MovementFromChart(t *testing.T) {
+	got := CalculateOracleLandscape(landscapeResponse(), nil, "Switchboard", testPolicy)
+
+	if len(got.Movement) != 2 {
+		t.Fatalf("expected 24h and 7d movement (no 30d point), got %+v", got.Movement)
+	}
+	day := got.Movement[0]
+	if day.Period != "24h" || day.Rank != 2 || day.PreviousRank != 3 || day.RankChange != 1 {
+		t.Fatalf("unexpected 24h movement: %+v", day)
+	}
+	if math.Abs(day.ShareChange-20) > 1e-9 {
+		t.Fatalf("expected +20pp share change, got %v", day.ShareChange)
+	}
+	week := got.Movement[1]
+	if week.Period != "7d" || week.PreviousRank != 2 || week.RankChange != 0 || math.Abs(week.PreviousShare-50) > 1e-9 {
+		t.Fatalf("unexpected 7d movement: %+v", week)
+	}
+
+	for _, m := range got.ByCategory {
+		if m.Name != "Uncategorized" {
+			t.Fatalf("expected Uncategorized without an index, got %+v", m)
+		}
+	}
+}
+
+func TestCalculateOracleLandscape_NilWithoutData(t *testing.T) {
+	if got := CalculateOracleLandscape(nil, nil, "Switchboard", testPolicy); got != nil {
+		t.Fatalf("expected nil, got %+v", got)
+	}
+	if got := CalculateOracleLandscape(&api.OracleAPIResponse{}, nil, "Switchboard", testPolicy); got != nil {
+		t.Fatalf("expected nil for empty oraclesTVS, got %+v", got)
+	}
+}
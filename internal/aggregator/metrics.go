@@ -4,6 +4,8 @@ import (
 	"math"
 	"sort"
 	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/chainregistry"
 )
 
 // Time offsets for historical comparison (seconds).
@@ -14,8 +16,9 @@ const (
 	SnapshotTolerance = 2 * 60 * 60 // 2 hours
 )
 
-// CalculateChainBreakdown aggregates TVS metrics per chain and returns them sorted by TVS descending.
-func CalculateChainBreakdown(protocols []AggregatedProtocol) []ChainBreakdown {
+// CalculateChainBreakdown aggregates TVS metrics per canonical chain in chains and returns them sorted
+// by TVS descending. A protocol reporting one chain under two names counts once for that chain.
+func CalculateChainBreakdown(protocols []AggregatedProtocol, chains *chainregistry.Registry) []ChainBreakdown {
 	if len(protocols) == 0 {
 		return []ChainBreakdown{}
 	}
@@ -27,7 +30,7 @@ func CalculateChainBreakdown(protocols []AggregatedProtocol) []ChainBreakdown {
 
 	var totalTVS float64
 	for _, p := range protocols {
-		for chain, tvs := range p.TVSByChain {
+		for chain, tvs := range CanonicalChainTVS(p.TVSByChain, chains) {
 			if tvs == 0 {
 				continue
 			}
@@ -51,12 +54,17 @@ func CalculateChainBreakdown(protocols []AggregatedProtocol) []ChainBreakdown {
 			percentage = (data.tvs / totalTVS) * 100
 		}
 
-		result = append(result, ChainBreakdown{
+		item := ChainBreakdown{
 			Chain:         chain,
 			TVS:           data.tvs,
 			Percentage:    percentage,
 			ProtocolCount: data.protocolCount,
-		})
+		}
+		if ref, ok := chains.Lookup(chain); ok {
+			item.DisplayName = ref.DisplayName
+			item.ChainID = ref.ChainID
+		}
+		result = append(result, item)
 	}
 
 	sort.Slice(result, func(i, j int) bool {
@@ -66,48 +74,65 @@ func CalculateChainBreakdown(protocols []AggregatedProtocol) []ChainBreakdown {
 	return result
 }
 
-// CalculateCategoryBreakdown aggregates TVS metrics per category and returns them sorted by TVS descending.
+// CalculateCategoryBreakdown aggregates TVS metrics per raw category and returns them sorted by TVS descending.
 func CalculateCategoryBreakdown(protocols []AggregatedProtocol) []CategoryBreakdown {
+	return categoryBreakdown(protocols, false)
+}
+
+// CalculateMappedCategoryBreakdown aggregates TVS metrics per MappedCategory, falling back to the raw
+// category for protocols without one, and lists the raw categories behind each entry.
+func CalculateMappedCategoryBreakdown(protocols []AggregatedProtocol) []CategoryBreakdown {
+	return categoryBreakdown(protocols, true)
+}
+
+func categoryBreakdown(protocols []AggregatedProtocol, mapped bool) []CategoryBreakdown {
 	if len(protocols) == 0 {
 		return []CategoryBreakdown{}
 	}
 
-	categoryData := make(map[string]struct {
+	type categoryData struct {
 		tvs           float64
 		protocolCount int
-	})
+		raw           map[string]struct{}
+	}
+	byCategory := make(map[string]*categoryData)
 
 	var totalTVS float64
 	for _, p := range protocols {
-		category := p.Category
-		if category == "" {
-			category = "Uncategorized"
+		raw := rawCategory(p.Category)
+		category := raw
+		if mapped && p.MappedCategory != "" {
+			category = p.MappedCategory
 		}
 
-		data := categoryData[category]
+		data, ok := byCategory[category]
+		if !ok {
+			data = &categoryData{raw: make(map[string]struct{})}
+			byCategory[category] = data
+		}
 		data.tvs += p.TVS
 		data.protocolCount++
-		categoryData[category] = data
+		data.raw[raw] = struct{}{}
 		totalTVS += p.TVS
 	}
 
-	if len(categoryData) == 0 {
-		return []CategoryBreakdown{}
-	}
-
-	result := make([]CategoryBreakdown, 0, len(categoryData))
-	for category, data := range categoryData {
+	result := make([]CategoryBreakdown, 0, len(byCategory))
+	for category, data := range byCategory {
 		percentage := 0.0
 		if totalTVS > 0 {
 			percentage = (data.tvs / totalTVS) * 100
 		}
 
-		result = append(result, CategoryBreakdown{
+		item := CategoryBreakdown{
 			Category:      category,
 			TVS:           data.tvs,
 			Percentage:    percentage,
 			ProtocolCount: data.protocolCount,
-		})
+		}
+		if mapped {
+			item.RawCategories = sortedKeys(data.raw)
+		}
+		result = append(result, item)
 	}
 
 	sort.Slice(result, func(i, j int) bool {
@@ -232,3 +257,44 @@ func CalculateChangeMetrics(currentTVS float64, currentProtocolCount int, histor
 
 	return metrics
 }
+
+// CalculateProtocolChanges sets each protocol's 24h, 7d and 30d TVS change and
+// its 7d rank change from the per-protocol entries of the snapshots found with
+// the same tolerance as CalculateChangeMetrics. Protocols are matched by slug;
+// protocols must already be ranked.
+func CalculateProtocolChanges(protocols []AggregatedProtocol, history []Snapshot) {
+	now := time.Now().Unix()
+
+	snapshot24h := FindSnapshotAtTime(history, now-Hours24, SnapshotTolerance)
+	snapshot7d := FindSnapshotAtTime(history, now-Days7, SnapshotTolerance)
+	snapshot30d := FindSnapshotAtTime(history, now-Days30, SnapshotTolerance)
+
+	for i := range protocols {
+		p := &protocols[i]
+		p.Change24h = protocolTVSChange(snapshot24h, p)
+		p.Change7d = protocolTVSChange(snapshot7d, p)
+		p.Change30d = protocolTVSChange(snapshot30d, p)
+
+		if prev, ok := snapshotProtocol(snapshot7d, p.Slug); ok && prev.Rank > 0 && p.Rank > 0 {
+			delta := prev.Rank - p.Rank
+			p.RankChange7d = &delta
+		}
+	}
+}
+
+func protocolTVSChange(snapshot *Snapshot, p *AggregatedProtocol) *float64 {
+	prev, ok := snapshotProtocol(snapshot, p.Slug)
+	if !ok {
+		return nil
+	}
+	change := CalculatePercentageChange(prev.TVS, p.TVS)
+	return &change
+}
+
+func snapshotProtocol(snapshot *Snapshot, slug string) (ProtocolSnapshot, bool) {
+	if snapshot == nil || slug == "" {
+		return ProtocolSnapshot{}, false
+	}
+	prev, ok := snapshot.Protocols[slug]
+	return prev, ok
+}
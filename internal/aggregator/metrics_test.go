@@ -54,7 +54,7 @@ func TestCalculateChainBreakdown(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := CalculateChainBreakdown(tt.protocols)
+			got := CalculateChainBreakdown(tt.protocols, nil)
 
 			if len(got) != tt.wantChains {
 				t.Fatalf("got %d chains, want %d", len(got), tt.wantChains)
@@ -481,6 +481,45 @@ func TestCalculateChangeMetrics(t *testing.T) {
 	})
 }
 
+func TestCalculateProtocolChanges(t *testing.T) {
+	now := time.Now().Unix()
+	history := []Snapshot{
+		{Timestamp: now - Days30, Protocols: map[string]ProtocolSnapshot{"alpha": {TVS: 400, Rank: 3}}},
+		{Timestamp: now - Days7, Protocols: map[string]ProtocolSnapshot{
+			"alpha": {TVS: 800, Rank: 2},
+			"beta":  {TVS: 500, Rank: 1},
+		}},
+		{Timestamp: now - Hours24, TVS: 1_000},
+	}
+	protocols := []AggregatedProtocol{
+		{Slug: "alpha", TVS: 1_000, Rank: 1},
+		{Slug: "beta", TVS: 450, Rank: 2},
+		{Slug: "gamma", TVS: 10, Rank: 3},
+	}
+
+	CalculateProtocolChanges(protocols, history)
+
+	alpha := protocols[0]
+	if alpha.Change24h != nil {
+		t.Errorf("alpha Change24h = %v, want nil for snapshot without protocols", *alpha.Change24h)
+	}
+	assertFloatPtr(t, alpha.Change7d, 25)
+	assertFloatPtr(t, alpha.Change30d, 150)
+	assertIntPtr(t, alpha.RankChange7d, 1)
+
+	beta := protocols[1]
+	assertFloatPtr(t, beta.Change7d, -10)
+	assertIntPtr(t, beta.RankChange7d, -1)
+	if beta.Change30d != nil {
+		t.Errorf("beta Change30d = %v, want nil when absent from snapshot", *beta.Change30d)
+	}
+
+	gamma := protocols[2]
+	if gamma.Change7d != nil || gamma.RankChange7d != nil {
+		t.Errorf("gamma changes = %v/%v, want nil for new protocol", gamma.Change7d, gamma.RankChange7d)
+	}
+}
+
 func TestChangeMetricsJSONSerialization(t *testing.T) {
 	change := 10.0
 	count := 5
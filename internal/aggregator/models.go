@@ -1,16 +1,31 @@
 package aggregator
 
 // AggregatedProtocol represents a protocol with enriched TVS data for output.
+// MappedCategory is the reporting-taxonomy category, set only when a category
+// taxonomy is configured. MatchStrategy names the strategy that matched the
+// protocol to its oraclesTVS key; it is empty for protocols without TVS.
+// The change fields compare TVS and rank with the protocol's entry in the
+// snapshots 24h, 7d and 30d back; they are nil when no snapshot in range
+// records the protocol. RankChange7d is positive when the protocol moved up.
+// QuarantineCycles counts the cycles TVS has been held at its previously
+// published value after an anomalous move.
 type AggregatedProtocol struct {
-	Name       string             `json:"name"`
-	Slug       string             `json:"slug"`
-	Category   string             `json:"category"`
-	URL        string             `json:"url"`
-	TVL        float64            `json:"tvl"`
-	Chains     []string           `json:"chains"`
-	TVS        float64            `json:"tvs"`
-	TVSByChain map[string]float64 `json:"tvs_by_chain"`
-	Rank       int                `json:"rank"`
+	Name             string             `json:"name"`
+	Slug             string             `json:"slug"`
+	Category         string             `json:"category"`
+	MappedCategory   string             `json:"mapped_category,omitempty"`
+	URL              string             `json:"url"`
+	TVL              float64            `json:"tvl"`
+	Chains           []string           `json:"chains"`
+	TVS              float64            `json:"tvs"`
+	TVSByChain       map[string]float64 `json:"tvs_by_chain"`
+	Rank             int                `json:"rank"`
+	MatchStrategy    string             `json:"match_strategy,omitempty"`
+	Change24h        *float64           `json:"change_24h,omitempty"`
+	Change7d         *float64           `json:"change_7d,omitempty"`
+	Change30d        *float64           `json:"change_30d,omitempty"`
+	RankChange7d     *int               `json:"rank_change_7d,omitempty"`
+	QuarantineCycles int                `json:"quarantine_cycles,omitempty"`
 }
 
 // LargestProtocol represents the top protocol by TVL.
@@ -22,29 +37,60 @@ type LargestProtocol struct {
 }
 
 // ChainBreakdown represents TVS metrics for a single blockchain.
+// ChainTVL is the chain's total DeFi TVL and ChainTVLShare the TVS as a
+// percentage of it; both are unset when chain TVL data is unavailable.
 type ChainBreakdown struct {
-	Chain         string  `json:"chain"`
-	TVS           float64 `json:"tvs"`
-	Percentage    float64 `json:"percentage"`
-	ProtocolCount int     `json:"protocol_count"`
+	Chain         string   `json:"chain"`
+	DisplayName   string   `json:"display_name,omitempty"`
+	ChainID       int64    `json:"chain_id,omitempty"`
+	TVS           float64  `json:"tvs"`
+	Percentage    float64  `json:"percentage"`
+	ProtocolCount int      `json:"protocol_count"`
+	ChainTVL      float64  `json:"chain_tvl,omitempty"`
+	ChainTVLShare *float64 `json:"chain_tvl_share,omitempty"`
+}
+
+// ChainSharePoint is one day of TVS on a chain expressed as a percentage of
+// the chain's total DeFi TVL.
+type ChainSharePoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Date      string  `json:"date"`
+	TVS       float64 `json:"tvs"`
+	ChainTVL  float64 `json:"chain_tvl"`
+	Share     float64 `json:"share"`
 }
 
 // CategoryBreakdown represents TVS metrics for a protocol category.
+// RawCategories lists the source categories grouped into a mapped category.
 type CategoryBreakdown struct {
-	Category      string  `json:"category"`
-	TVS           float64 `json:"tvs"`
-	Percentage    float64 `json:"percentage"`
-	ProtocolCount int     `json:"protocol_count"`
+	Category      string   `json:"category"`
+	TVS           float64  `json:"tvs"`
+	Percentage    float64  `json:"percentage"`
+	ProtocolCount int      `json:"protocol_count"`
+	RawCategories []string `json:"raw_categories,omitempty"`
 }
 
 // Snapshot represents a point-in-time TVS measurement for historical tracking.
+// Protocols holds each protocol's TVS and rank keyed by slug and
+// Concentration the concentration metrics of the run; both are empty in
+// snapshots written before they were recorded.
 type Snapshot struct {
-	Timestamp     int64              `json:"timestamp"`
-	Date          string             `json:"date"`
-	TVS           float64            `json:"tvs"`
-	TVSByChain    map[string]float64 `json:"tvs_by_chain"`
-	ProtocolCount int                `json:"protocol_count"`
-	ChainCount    int                `json:"chain_count"`
+	Timestamp     int64                       `json:"timestamp"`
+	Date          string                      `json:"date"`
+	TVS           float64                     `json:"tvs"`
+	TVSByChain    map[string]float64          `json:"tvs_by_chain"`
+	ProtocolCount int                         `json:"protocol_count"`
+	ChainCount    int                         `json:"chain_count"`
+	Protocols     map[string]ProtocolSnapshot `json:"protocols,omitempty"`
+	Concentration *ConcentrationMetrics       `json:"concentration,omitempty"`
+}
+
+// ProtocolSnapshot is one protocol's published TVS and rank in a Snapshot.
+// QuarantineCycles is non-zero while TVS is held after an anomalous move.
+type ProtocolSnapshot struct {
+	TVS              float64 `json:"tvs"`
+	Rank             int     `json:"rank"`
+	QuarantineCycles int     `json:"quarantine_cycles,omitempty"`
 }
 
 // ChangeMetrics captures TVS and protocol count changes over time windows.
@@ -71,4 +117,21 @@ type AggregationResult struct {
 	LargestProtocol     *LargestProtocol     `json:"largest_protocol,omitempty"`
 	ChangeMetrics       ChangeMetrics        `json:"change_metrics"`
 	Timestamp           int64                `json:"timestamp"`
+	// MappedCategoryBreakdown and UnmappedCategories are set only when a
+	// category taxonomy is configured.
+	MappedCategoryBreakdown []CategoryBreakdown `json:"mapped_category_breakdown,omitempty"`
+	UnmappedCategories      []UnmappedCategory  `json:"unmapped_categories,omitempty"`
+	// Anomalies lists the TVS moves flagged this cycle, the total first.
+	Anomalies []Anomaly `json:"anomalies,omitempty"`
+	// Concentration measures how concentrated TVS is across protocols,
+	// chains and categories.
+	Concentration *ConcentrationMetrics `json:"concentration,omitempty"`
+	// ProtocolMatches reports how protocols were matched to oraclesTVS keys.
+	ProtocolMatches *ProtocolMatchReport `json:"protocol_matches,omitempty"`
+	// Analytics holds rolling statistics over the chart history; nil until
+	// set from it.
+	Analytics *Analytics `json:"analytics,omitempty"`
+	// ChainShareHistory holds a daily share series per chain; nil unless
+	// chain TVL data was fetched.
+	ChainShareHistory map[string][]ChainSharePoint `json:"chain_share_history,omitempty"`
 }
@@ -0,0 +1,98 @@
+package aggregator
+
+import (
+	"sort"
+	"strings"
+)
+
+// TVSPolicy lists the DefiLlama TVL components that count toward TVS,
+// mirroring the toggles on DefiLlama's UI. Components fall into two groups:
+//
+//   - borrowed, staking, pool2, vesting, offers and treasury are reported on
+//     top of plain TVL and are added when included.
+//   - doublecounted and liquidstaking are already part of plain TVL and are
+//     subtracted when excluded; dcandlsoverlap (value counted in both) is
+//     added back when both are excluded so it is not subtracted twice.
+//
+// The zero value counts plain TVL minus doublecounted and liquidstaking.
+type TVSPolicy struct {
+	components []string
+}
+
+// overlapComponents are included in plain TVL and removed when excluded.
+var overlapComponents = map[string]struct{}{
+	"doublecounted": {},
+	"liquidstaking": {},
+}
+
+// NewTVSPolicy returns a policy counting the given components. Names are
+// case-insensitive; blanks and duplicates are ignored.
+func NewTVSPolicy(components []string) TVSPolicy {
+	seen := make(map[string]struct{}, len(components))
+	normalized := make([]string, 0, len(components))
+	for _, c := range components {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" {
+			continue
+		}
+		if _, ok := seen[c]; ok {
+			continue
+		}
+		seen[c] = struct{}{}
+		normalized = append(normalized, c)
+	}
+	sort.Strings(normalized)
+	return TVSPolicy{components: normalized}
+}
+
+// Components returns the included components, sorted. It never returns nil.
+func (p TVSPolicy) Components() []string {
+	return append([]string{}, p.components...)
+}
+
+// Includes reports whether component counts toward TVS.
+func (p TVSPolicy) Includes(component string) bool {
+	component = strings.ToLower(component)
+	for _, c := range p.components {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
+
+// contribution returns how much a component value adds to plain TVL under
+// the policy: the value itself, its negation, or zero.
+func (p TVSPolicy) contribution(component string, value float64) float64 {
+	component = strings.ToLower(component)
+	if component == "dcandlsoverlap" {
+		if !p.Includes("doublecounted") && !p.Includes("liquidstaking") {
+			return value
+		}
+		return 0
+	}
+	if _, ok := overlapComponents[component]; ok {
+		if p.Includes(component) {
+			return 0
+		}
+		return -value
+	}
+	if p.Includes(component) {
+		return value
+	}
+	return 0
+}
+
+// chartTVS applies the policy to one oracle's chart entry, whose "tvl" value
+// is plain TVL and whose other keys are components.
+func (p TVSPolicy) chartTVS(values map[string]float64) float64 {
+	var tvs float64
+	for key, value := range values {
+		if strings.EqualFold(key, "tvl") {
+			tvs += value
+			continue
+		}
+		tvs += p.contribution(key, value)
+	}
+	return tvs
+}
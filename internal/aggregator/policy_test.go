@@ -0,0 +1,180 @@
+package aggregator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/api"
+)
+
+// testPolicy is the default aggregation.tvs_policy: every component except
+// borrowed.
+var testPolicy = NewTVSPolicy([]string{"doublecounted", "liquidstaking", "offers", "pool2", "staking", "treasury", "vesting"})
+
+// plainPolicy counts plain TVL as DefiLlama reports it, with no components
+// added or removed.
+var plainPolicy = NewTVSPolicy([]string{"doublecounted", "liquidstaking"})
+
+func TestNewTVSPolicy_NormalizesComponents(t *testing.T) {
+	p := NewTVSPolicy([]string{" Staking", "borrowed", "", "STAKING"})
+
+	if got, want := p.Components(), []string{"borrowed", "staking"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Components() = %v, want %v", got, want)
+	}
+	if !p.Includes("Staking") || p.Includes("pool2") {
+		t.Fatalf("Includes mismatch for %v", p.Components())
+	}
+	if got := NewTVSPolicy(nil).Components(); got == nil || len(got) != 0 {
+		t.Fatalf("empty policy Components() = %#v, want empty non-nil slice", got)
+	}
+}
+
+func TestExtractProtocolTVS_AppliesPolicy(t *testing.T) {
+	oraclesTVS := map[string]map[string]map[string]float64{
+		"Switchboard": {
+			"proto-a": {
+				"Solana":               1_000,
+				"Solana-borrowed":      400,
+				"Solana-staking":       200,
+				"Solana-pool2":         50,
+				"Solana-doublecounted": 100,
+				"Sui":                  300,
+				"Sui-staking":          20,
+				"borrowed":             400,
+				"staking":              220,
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		policy    TVSPolicy
+		wantTotal float64
+		wantChain map[string]float64
+	}{
+		{
+			name:      "default counts every component except borrowed",
+			policy:    testPolicy,
+			wantTotal: 1_570,
+			wantChain: map[string]float64{"Solana": 1_250, "Sui": 320},
+		},
+		{
+			name:      "plain tvl",
+			policy:    plainPolicy,
+			wantTotal: 1_300,
+			wantChain: map[string]float64{"Solana": 1_000, "Sui": 300},
+		},
+		{
+			name:      "additive components folded into their chain",
+			policy:    NewTVSPolicy([]string{"borrowed", "staking", "doublecounted"}),
+			wantTotal: 1_920,
+			wantChain: map[string]float64{"Solana": 1_600, "Sui": 320},
+		},
+		{
+			name:      "excluded doublecounted is subtracted",
+			policy:    NewTVSPolicy([]string{"pool2"}),
+			wantTotal: 1_250,
+			wantChain: map[string]float64{"Solana": 950, "Sui": 300},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, byChain, found := ExtractProtocolTVS(oraclesTVS, "Switchboard", "proto-a", tt.policy)
+			if !found {
+				t.Fatalf("expected TVS to be found")
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %v, want %v", total, tt.wantTotal)
+			}
+			if !reflect.DeepEqual(byChain, tt.wantChain) {
+				t.Errorf("byChain = %v, want %v", byChain, tt.wantChain)
+			}
+		})
+	}
+}
+
+// TestExtractProtocolTVS_DefaultPolicyKeepsBaselineTotals pins the totals the
+// extractor published before aggregation.tvs_policy existed, when every chain
+// key except borrowed ones was summed.
+func TestExtractProtocolTVS_DefaultPolicyKeepsBaselineTotals(t *testing.T) {
+	protocols := map[string]map[string]float64{
+		"lending": {
+			"Solana":          757_000_000,
+			"Solana-borrowed": 442_000_000,
+			"Sui":             50_000_000,
+			"Sui-borrowed":    30_000_000,
+		},
+		"yield": {
+			"Solana":          1_000,
+			"Solana-staking":  200,
+			"Solana-pool2":    50,
+			"Solana-treasury": 25,
+			"Solana-vesting":  10,
+			"Solana-offers":   5,
+			"Sui":             300,
+			"Sui-staking":     20,
+			"Sui-borrowed":    90,
+		},
+	}
+
+	for slug, keys := range protocols {
+		var baseline float64
+		for key, tvs := range keys {
+			if !strings.HasSuffix(strings.ToLower(key), "borrowed") {
+				baseline += tvs
+			}
+		}
+
+		oraclesTVS := map[string]map[string]map[string]float64{"Switchboard": {slug: keys}}
+		total, _, found := ExtractProtocolTVS(oraclesTVS, "Switchboard", slug, testPolicy)
+		if !found || total != baseline {
+			t.Errorf("%s: total = %v (found %v), want baseline %v", slug, total, found, baseline)
+		}
+	}
+}
+
+func TestExtractChartHistory_AppliesPolicy(t *testing.T) {
+	resp := &api.OracleAPIResponse{
+		Chart: map[string]map[string]map[string]float64{
+			"1700000000": {
+				"Switchboard": {
+					"tvl":            1_000,
+					"borrowed":       400,
+					"staking":        200,
+					"doublecounted":  100,
+					"liquidstaking":  60,
+					"dcAndLsOverlap": 10,
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		policy TVSPolicy
+		want   float64
+	}{
+		{name: "default", policy: testPolicy, want: 1_200},
+		{name: "plain tvl", policy: plainPolicy, want: 1_000},
+		{name: "borrowed and staking", policy: NewTVSPolicy([]string{"borrowed", "staking", "doublecounted", "liquidstaking"}), want: 1_600},
+		{name: "overlap added back", policy: NewTVSPolicy(nil), want: 850},
+		{name: "overlap kept once", policy: NewTVSPolicy([]string{"liquidstaking"}), want: 900},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractChartHistory(resp, "Switchboard", tt.policy)
+			if len(got) != 1 {
+				t.Fatalf("expected 1 point, got %d", len(got))
+			}
+			if got[0].TVS != tt.want {
+				t.Errorf("TVS = %v, want %v", got[0].TVS, tt.want)
+			}
+			if got[0].Borrowed != 400 || got[0].Staking != 200 {
+				t.Errorf("components = borrowed %v staking %v, want raw 400 and 200", got[0].Borrowed, got[0].Staking)
+			}
+		})
+	}
+}
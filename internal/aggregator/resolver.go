@@ -0,0 +1,278 @@
+package aggregator
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/api"
+)
+
+// Match strategies, in the order they are tried. A protocol is matched by the
+// first strategy that yields exactly one oraclesTVS key no earlier strategy
+// has claimed.
+const (
+	MatchAlias       = "alias"
+	MatchSlug        = "slug"
+	MatchName        = "name"
+	MatchCase        = "case_insensitive"
+	MatchSlugify     = "slugify"
+	MatchPunctuation = "punctuation_insensitive"
+	MatchParent      = "parent"
+	MatchChild       = "child"
+)
+
+var matchStrategies = []string{MatchAlias, MatchSlug, MatchName, MatchCase, MatchSlugify, MatchPunctuation, MatchParent, MatchChild}
+
+// ProtocolMatch is the oraclesTVS key a protocol resolved to and the strategy
+// that found it. Candidates lists the keys an ambiguous protocol could not
+// choose between; Key is empty for unmatched and ambiguous protocols.
+type ProtocolMatch struct {
+	Key        string
+	Strategy   string
+	Candidates []string
+}
+
+// UnresolvedProtocol is a protocol with no TVS because no oraclesTVS key, or
+// more than one, matched it. Strategy is the strategy that found the
+// competing candidates of an ambiguous protocol.
+type UnresolvedProtocol struct {
+	Name       string   `json:"name"`
+	Slug       string   `json:"slug"`
+	Category   string   `json:"category"`
+	TVL        float64  `json:"tvl"`
+	Strategy   string   `json:"strategy,omitempty"`
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+// UnclaimedKey is an oraclesTVS key no protocol resolved to, with the TVS it
+// holds under the aggregation's policy.
+type UnclaimedKey struct {
+	Key string  `json:"key"`
+	TVS float64 `json:"tvs"`
+}
+
+// ProtocolMatchReport records how the oracle's protocols were matched to
+// oraclesTVS keys: the count per strategy, the protocols left unmatched or
+// ambiguous, and the TVS under keys nothing matched.
+type ProtocolMatchReport struct {
+	Oracle        string               `json:"oracle"`
+	Timestamp     int64                `json:"timestamp"`
+	ProtocolCount int                  `json:"protocol_count"`
+	Matched       int                  `json:"matched"`
+	ByStrategy    map[string]int       `json:"by_strategy"`
+	Unmatched     []UnresolvedProtocol `json:"unmatched"`
+	Ambiguous     []UnresolvedProtocol `json:"ambiguous"`
+	UnclaimedKeys []UnclaimedKey       `json:"unclaimed_keys"`
+}
+
+// ResolveProtocols matches each protocol to one of keys, returning matches
+// aligned with protocols. Strategies run in order across all protocols, so a
+// key claimed by an exact match is never offered to a looser one. A protocol
+// with several candidate keys under a strategy, and protocols competing for
+// one key, are left ambiguous. aliases maps a protocol slug or name
+// (case-insensitive) to its oraclesTVS key.
+func ResolveProtocols(protocols []api.Protocol, keys []string, aliases map[string]string) []ProtocolMatch {
+	matches := make([]ProtocolMatch, len(protocols))
+	if len(protocols) == 0 || len(keys) == 0 {
+		return matches
+	}
+
+	aliasByKey := make(map[string]string, len(aliases))
+	for from, to := range aliases {
+		aliasByKey[categoryKey(from)] = strings.TrimSpace(to)
+	}
+
+	claimed := make(map[string]struct{}, len(protocols))
+	for _, strategy := range matchStrategies {
+		proposals := make(map[string][]int)
+		for i, p := range protocols {
+			if matches[i].Strategy != "" {
+				continue
+			}
+			var candidates []string
+			for _, key := range keys {
+				if _, ok := claimed[key]; ok {
+					continue
+				}
+				if matchesStrategy(strategy, p, key, aliasByKey) {
+					candidates = append(candidates, key)
+				}
+			}
+			switch {
+			case len(candidates) == 1:
+				proposals[candidates[0]] = append(proposals[candidates[0]], i)
+			case len(candidates) > 1:
+				sort.Strings(candidates)
+				matches[i] = ProtocolMatch{Strategy: strategy, Candidates: candidates}
+			}
+		}
+
+		for key, indexes := range proposals {
+			claimed[key] = struct{}{}
+			if len(indexes) == 1 {
+				matches[indexes[0]] = ProtocolMatch{Key: key, Strategy: strategy}
+				continue
+			}
+			for _, i := range indexes {
+				matches[i] = ProtocolMatch{Strategy: strategy, Candidates: []string{key}}
+			}
+		}
+	}
+	return matches
+}
+
+// matchesStrategy reports whether key identifies p under strategy.
+func matchesStrategy(strategy string, p api.Protocol, key string, aliases map[string]string) bool {
+	slug := strings.TrimSpace(p.Slug)
+	name := strings.TrimSpace(p.Name)
+	key = strings.TrimSpace(key)
+
+	switch strategy {
+	case MatchAlias:
+		for _, from := range []string{slug, name} {
+			if to, ok := aliases[categoryKey(from)]; ok && from != "" && foldKey(to) == foldKey(key) {
+				return true
+			}
+		}
+	case MatchSlug:
+		return slug != "" && key == slug
+	case MatchName:
+		return name != "" && key == name
+	case MatchCase:
+		return (slug != "" && strings.EqualFold(key, slug)) || (name != "" && strings.EqualFold(key, name))
+	case MatchSlugify:
+		k := slugifyKey(key)
+		return k != "" && (k == slugifyKey(slug) || k == slugifyKey(name))
+	case MatchPunctuation:
+		k := foldKey(key)
+		return k != "" && (k == foldKey(slug) || k == foldKey(name))
+	case MatchParent:
+		parent := strings.TrimPrefix(strings.TrimSpace(p.ParentProtocol), "parent#")
+		return parent != "" && foldKey(parent) == foldKey(key)
+	case MatchChild:
+		k := keyWords(key)
+		for _, words := range [][]string{keyWords(name), keyWords(slug)} {
+			if isWordPrefix(k, words) || isWordPrefix(words, k) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NewProtocolMatchReport summarises matches for protocols. The TVS of keys
+// no protocol resolved to is counted under policy.
+func NewProtocolMatchReport(protocols []api.Protocol, matches []ProtocolMatch, oracleResp *api.OracleAPIResponse, oracleName string, policy TVSPolicy) *ProtocolMatchReport {
+	report := &ProtocolMatchReport{
+		Oracle:        oracleName,
+		Timestamp:     ExtractLatestTimestamp(oracleResp),
+		ProtocolCount: len(protocols),
+		ByStrategy:    make(map[string]int),
+		Unmatched:     []UnresolvedProtocol{},
+		Ambiguous:     []UnresolvedProtocol{},
+		UnclaimedKeys: []UnclaimedKey{},
+	}
+
+	claimed := make(map[string]struct{}, len(matches))
+	for i, p := range protocols {
+		var m ProtocolMatch
+		if i < len(matches) {
+			m = matches[i]
+		}
+		if m.Key != "" {
+			report.Matched++
+			report.ByStrategy[m.Strategy]++
+			claimed[m.Key] = struct{}{}
+			continue
+		}
+		unresolved := UnresolvedProtocol{Name: p.Name, Slug: p.Slug, Category: p.Category, TVL: p.TVL}
+		if len(m.Candidates) > 0 {
+			unresolved.Strategy = m.Strategy
+			unresolved.Candidates = m.Candidates
+			report.Ambiguous = append(report.Ambiguous, unresolved)
+			continue
+		}
+		report.Unmatched = append(report.Unmatched, unresolved)
+	}
+	sortUnresolved(report.Unmatched)
+	sortUnresolved(report.Ambiguous)
+
+	for _, key := range oracleTVSKeys(oracleResp, oracleName) {
+		if _, ok := claimed[key]; ok {
+			continue
+		}
+		tvs, _, _ := ExtractProtocolTVS(oracleResp.OraclesTVS, oracleName, key, policy)
+		report.UnclaimedKeys = append(report.UnclaimedKeys, UnclaimedKey{Key: key, TVS: tvs})
+	}
+	sort.Slice(report.UnclaimedKeys, func(i, j int) bool {
+		if report.UnclaimedKeys[i].TVS != report.UnclaimedKeys[j].TVS {
+			return report.UnclaimedKeys[i].TVS > report.UnclaimedKeys[j].TVS
+		}
+		return report.UnclaimedKeys[i].Key < report.UnclaimedKeys[j].Key
+	})
+	return report
+}
+
+// oracleTVSKeys returns the protocol keys under oracleName in oraclesTVS,
+// sorted.
+func oracleTVSKeys(oracleResp *api.OracleAPIResponse, oracleName string) []string {
+	if oracleResp == nil {
+		return nil
+	}
+	data := oracleResp.OraclesTVS[oracleName]
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortUnresolved(list []UnresolvedProtocol) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].TVL != list[j].TVL {
+			return list[i].TVL > list[j].TVL
+		}
+		return list[i].Slug < list[j].Slug
+	})
+}
+
+// slugifyKey applies DefiLlama's slug rule: lowercase, spaces to hyphens and
+// apostrophes dropped.
+func slugifyKey(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.ReplaceAll(s, " ", "-")
+	return strings.ReplaceAll(s, "'", "")
+}
+
+// foldKey lowercases s and keeps only letters and digits.
+func foldKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, strings.ToLower(s))
+}
+
+// keyWords splits s into lowercase runs of letters and digits.
+func keyWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// isWordPrefix reports whether prefix is a strict, non-empty word prefix of
+// words, as "Kamino" is of "Kamino Lend".
+func isWordPrefix(prefix, words []string) bool {
+	if len(prefix) == 0 || len(prefix) >= len(words) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != words[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package aggregator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/api"
+)
+
+func TestResolveProtocols_Strategies(t *testing.T) {
+	keys := []string{"jupiter", "Marginfi", "SOLEND", "Drift-Protocol", "Saber's Swap", "Switchboard Labs", "kamino", "Orca Whirlpools", "renamed-key"}
+	protocols := []api.Protocol{
+		{Slug: "jupiter", Name: "Jupiter Aggregator"},
+		{Slug: "marginfi-v2", Name: "Marginfi"},
+		{Slug: "solend", Name: "Save"},
+		{Slug: "drift", Name: "Drift Protocol"},
+		{Slug: "sabers-swap", Name: "Saber Swap"},
+		{Slug: "sb-labs", Name: "Switchboard.Labs"},
+		{Slug: "kamino-lend", Name: "Kamino Lending", ParentProtocol: "parent#kamino"},
+		{Slug: "orca", Name: "Orca"},
+		{Slug: "old-name", Name: "Old Name"},
+		{Slug: "nowhere", Name: "Nowhere"},
+	}
+	aliases := map[string]string{"Old-Name": "Renamed-Key"}
+
+	want := []ProtocolMatch{
+		{Key: "jupiter", Strategy: MatchSlug},
+		{Key: "Marginfi", Strategy: MatchName},
+		{Key: "SOLEND", Strategy: MatchCase},
+		{Key: "Drift-Protocol", Strategy: MatchSlugify},
+		{Key: "Saber's Swap", Strategy: MatchSlugify},
+		{Key: "Switchboard Labs", Strategy: MatchPunctuation},
+		{Key: "kamino", Strategy: MatchParent},
+		{Key: "Orca Whirlpools", Strategy: MatchChild},
+		{Key: "renamed-key", Strategy: MatchAlias},
+		{},
+	}
+
+	got := ResolveProtocols(protocols, keys, aliases)
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("%s: got %+v, want %+v", protocols[i].Slug, got[i], want[i])
+		}
+	}
+}
+
+func TestResolveProtocols_ExactMatchClaimsKeyFirst(t *testing.T) {
+	keys := []string{"kamino", "Kamino Lend"}
+	protocols := []api.Protocol{
+		{Slug: "kamino-lending", Name: "Kamino Lending V2"},
+		{Slug: "kamino", Name: "Kamino"},
+	}
+
+	got := ResolveProtocols(protocols, keys, nil)
+	if got[1].Key != "kamino" || got[1].Strategy != MatchSlug {
+		t.Fatalf("exact match = %+v, want kamino by slug", got[1])
+	}
+	if got[0].Key != "" || got[0].Strategy != "" {
+		t.Fatalf("child of claimed key = %+v, want unmatched", got[0])
+	}
+}
+
+func TestResolveProtocols_Ambiguous(t *testing.T) {
+	keys := []string{"Drift Vaults", "Drift Perps", "Jito"}
+	protocols := []api.Protocol{
+		{Slug: "drift", Name: "Drift"},
+		{Slug: "jito-restaking", Name: "Jito Restaking"},
+		{Slug: "jito-stakenet", Name: "Jito StakeNet"},
+	}
+
+	got := ResolveProtocols(protocols, keys, nil)
+	want := []ProtocolMatch{
+		{Strategy: MatchChild, Candidates: []string{"Drift Perps", "Drift Vaults"}},
+		{Strategy: MatchChild, Candidates: []string{"Jito"}},
+		{Strategy: MatchChild, Candidates: []string{"Jito"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestNewProtocolMatchReport(t *testing.T) {
+	oracleResp := &api.OracleAPIResponse{
+		Chart: map[string]map[string]map[string]float64{"1733000000": {}},
+		OraclesTVS: map[string]map[string]map[string]float64{
+			"Switchboard": {
+				"jupiter":      {"Solana": 500},
+				"Drift Vaults": {"Solana": 30},
+				"Drift Perps":  {"Solana": 20},
+				"Orphan":       {"Solana": 70},
+			},
+		},
+	}
+	protocols := []api.Protocol{
+		{Slug: "jupiter", Name: "Jupiter", TVL: 900},
+		{Slug: "drift", Name: "Drift", Category: "Derivatives", TVL: 300},
+		{Slug: "ghost", Name: "Ghost", TVL: 10},
+	}
+
+	matches := ResolveProtocols(protocols, oracleTVSKeys(oracleResp, "Switchboard"), nil)
+	report := NewProtocolMatchReport(protocols, matches, oracleResp, "Switchboard", testPolicy)
+
+	if report.Timestamp != 1733000000 || report.ProtocolCount != 3 || report.Matched != 1 {
+		t.Fatalf("unexpected counts: %+v", report)
+	}
+	if !reflect.DeepEqual(report.ByStrategy, map[string]int{MatchSlug: 1}) {
+		t.Errorf("ByStrategy = %v", report.ByStrategy)
+	}
+	wantUnmatched := []UnresolvedProtocol{{Name: "Ghost", Slug: "ghost", TVL: 10}}
+	if !reflect.DeepEqual(report.Unmatched, wantUnmatched) {
+		t.Errorf("Unmatched = %+v, want %+v", report.Unmatched, wantUnmatched)
+	}
+	wantAmbiguous := []UnresolvedProtocol{{
+		Name: "Drift", Slug: "drift", Category: "Derivatives", TVL: 300,
+		Strategy: MatchChild, Candidates: []string{"Drift Perps", "Drift Vaults"},
+	}}
+	if !reflect.DeepEqual(report.Ambiguous, wantAmbiguous) {
+		t.Errorf("Ambiguous = %+v, want %+v", report.Ambiguous, wantAmbiguous)
+	}
+	wantUnclaimed := []UnclaimedKey{{Key: "Orphan", TVS: 70}, {Key: "Drift Vaults", TVS: 30}, {Key: "Drift Perps", TVS: 20}}
+	if !reflect.DeepEqual(report.UnclaimedKeys, wantUnclaimed) {
+		t.Errorf("UnclaimedKeys = %+v, want %+v", report.UnclaimedKeys, wantUnclaimed)
+	}
+}
+
+func TestAggregate_RecordsMatchStrategy(t *testing.T) {
+	oracleResp := &api.OracleAPIResponse{
+		Chart: map[string]map[string]map[string]float64{"1733000000": {}},
+		OraclesTVS: map[string]map[string]map[string]float64{
+			"Switchboard": {"Kamino": {"Solana": 100}},
+		},
+	}
+	protocols := []api.Protocol{
+		{Slug: "kamino-lend", Name: "Kamino Lend", Oracles: []string{"Switchboard"}},
+	}
+
+	agg := NewAggregator("Switchboard", Options{TVSPolicy: testPolicy, ProtocolAliases: map[string]string{"kamino-lend": "kamino"}})
+	result := agg.Aggregate(t.Context(), oracleResp, protocols, nil)
+
+	if result.ProtocolsWithTVS != 1 || result.TotalTVS != 100 {
+		t.Fatalf("expected aliased protocol to carry TVS, got %+v", result)
+	}
+	if got := result.Protocols[0].MatchStrategy; got != MatchAlias {
+		t.Errorf("MatchStrategy = %q, want %q", got, MatchAlias)
+	}
+	if result.ProtocolMatches == nil || result.ProtocolMatches.ByStrategy[MatchAlias] != 1 {
+		t.Errorf("ProtocolMatches = %+v", result.ProtocolMatches)
+	}
+}
@@ -0,0 +1,165 @@
+package aggregator
+
+import (
+	"sort"
+	"strings"
+)
+
+// uncategorized stands in for a blank category.
+const uncategorized = "Uncategorized"
+
+// CategoryTaxonomy maps source categories (DefiLlama's or free text from
+// custom data) to the reporting taxonomy. Rules map several source categories
+// to one category, matched case-insensitively; per-slug overrides win over
+// rules. A nil taxonomy maps nothing.
+type CategoryTaxonomy struct {
+	rules     map[string]string
+	overrides map[string]string
+}
+
+// UnmappedCategory is a source category no rule maps, with the protocols that
+// fell back to it. TVS is the combined TVS (or TVL, in the TVL outputs) of
+// those protocols.
+type UnmappedCategory struct {
+	Category      string   `json:"category"`
+	ProtocolCount int      `json:"protocol_count"`
+	TVS           float64  `json:"tvs"`
+	Protocols     []string `json:"protocols"`
+}
+
+// CategoryReport lists the categories the taxonomy left unmapped.
+type CategoryReport struct {
+	Timestamp       int64              `json:"timestamp"`
+	ProtocolCount   int                `json:"protocol_count"`
+	MappedProtocols int                `json:"mapped_protocols"`
+	Unmapped        []UnmappedCategory `json:"unmapped"`
+}
+
+// NewCategoryTaxonomy builds a taxonomy from mapping (reporting category to
+// source categories) and overrides (protocol slug to reporting category). It
+// returns nil when both are empty.
+func NewCategoryTaxonomy(mapping map[string][]string, overrides map[string]string) *CategoryTaxonomy {
+	if len(mapping) == 0 && len(overrides) == 0 {
+		return nil
+	}
+
+	t := &CategoryTaxonomy{
+		rules:     make(map[string]string),
+		overrides: make(map[string]string, len(overrides)),
+	}
+	for category, sources := range mapping {
+		category = strings.TrimSpace(category)
+		for _, source := range sources {
+			t.rules[categoryKey(source)] = category
+		}
+	}
+	for slug, category := range overrides {
+		t.overrides[categoryKey(slug)] = strings.TrimSpace(category)
+	}
+	return t
+}
+
+// Map returns the reporting category for a protocol, trying its slug's
+// override before the rule for its category. A blank category is looked up
+// as "Uncategorized".
+func (t *CategoryTaxonomy) Map(slug, category string) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	if mapped, ok := t.overrides[categoryKey(slug)]; ok && slug != "" {
+		return mapped, true
+	}
+	mapped, ok := t.rules[categoryKey(rawCategory(category))]
+	return mapped, ok
+}
+
+// MapProtocolCategories sets each protocol's MappedCategory from taxonomy,
+// falling back to its raw category when unmapped. A nil taxonomy leaves
+// protocols untouched.
+func MapProtocolCategories(protocols []AggregatedProtocol, taxonomy *CategoryTaxonomy) {
+	if taxonomy == nil {
+		return
+	}
+	for i := range protocols {
+		mapped, ok := taxonomy.Map(protocols[i].Slug, protocols[i].Category)
+		if !ok {
+			mapped = rawCategory(protocols[i].Category)
+		}
+		protocols[i].MappedCategory = mapped
+	}
+}
+
+// FindUnmappedCategories groups the protocols taxonomy cannot map by raw
+// category, largest TVS first. It returns nil for a nil taxonomy.
+func FindUnmappedCategories(protocols []AggregatedProtocol, taxonomy *CategoryTaxonomy) []UnmappedCategory {
+	if taxonomy == nil {
+		return nil
+	}
+
+	byCategory := make(map[string]*UnmappedCategory)
+	for _, p := range protocols {
+		if _, ok := taxonomy.Map(p.Slug, p.Category); ok {
+			continue
+		}
+		category := rawCategory(p.Category)
+		entry, ok := byCategory[category]
+		if !ok {
+			entry = &UnmappedCategory{Category: category, Protocols: []string{}}
+			byCategory[category] = entry
+		}
+		entry.ProtocolCount++
+		entry.TVS += p.TVS
+		entry.Protocols = append(entry.Protocols, p.Slug)
+	}
+
+	result := make([]UnmappedCategory, 0, len(byCategory))
+	for _, entry := range byCategory {
+		sort.Strings(entry.Protocols)
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TVS != result[j].TVS {
+			return result[i].TVS > result[j].TVS
+		}
+		return result[i].Category < result[j].Category
+	})
+	return result
+}
+
+// NewCategoryReport summarises an aggregation's unmapped categories.
+func NewCategoryReport(result *AggregationResult) *CategoryReport {
+	report := &CategoryReport{
+		Timestamp:     result.Timestamp,
+		ProtocolCount: result.TotalProtocols,
+		Unmapped:      result.UnmappedCategories,
+	}
+	if report.Unmapped == nil {
+		report.Unmapped = []UnmappedCategory{}
+	}
+	report.MappedProtocols = report.ProtocolCount
+	for _, u := range report.Unmapped {
+		report.MappedProtocols -= u.ProtocolCount
+	}
+	return report
+}
+
+// rawCategory returns category, or "Uncategorized" when it is blank.
+func rawCategory(category string) string {
+	if strings.TrimSpace(category) == "" {
+		return uncategorized
+	}
+	return category
+}
+
+func categoryKey(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,131 @@
+package aggregator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/api"
+)
+
+func testTaxonomy() *CategoryTaxonomy {
+	return NewCategoryTaxonomy(
+		map[string][]string{
+			"Money Markets": {"Lending", "cdp"},
+			"Perps":         {"Derivatives"},
+		},
+		map[string]string{"kamino": "Yield"},
+	)
+}
+
+func TestCategoryTaxonomy_Map(t *testing.T) {
+	taxonomy := testTaxonomy()
+
+	tests := []struct {
+		slug, category string
+		want           string
+		wantOK         bool
+	}{
+		{"marginfi", "Lending", "Money Markets", true},
+		{"hubble", "CDP", "Money Markets", true},
+		{"kamino", "Lending", "Yield", true},
+		{"KAMINO", "", "Yield", true},
+		{"orca", "Dexs", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := taxonomy.Map(tt.slug, tt.category)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Map(%q, %q) = %q, %v; want %q, %v", tt.slug, tt.category, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	if NewCategoryTaxonomy(nil, nil) != nil {
+		t.Fatalf("empty config should yield a nil taxonomy")
+	}
+	var none *CategoryTaxonomy
+	if _, ok := none.Map("kamino", "Lending"); ok {
+		t.Fatalf("nil taxonomy should map nothing")
+	}
+}
+
+func TestMappedCategoryBreakdownAndUnmapped(t *testing.T) {
+	taxonomy := testTaxonomy()
+	protocols := []AggregatedProtocol{
+		{Slug: "marginfi", Category: "Lending", TVS: 100},
+		{Slug: "hubble", Category: "CDP", TVS: 50},
+		{Slug: "kamino", Category: "Lending", TVS: 200},
+		{Slug: "orca", Category: "Dexs", TVS: 30},
+		{Slug: "raydium", Category: "Dexs", TVS: 20},
+		{Slug: "mystery", TVS: 10},
+	}
+
+	MapProtocolCategories(protocols, taxonomy)
+	if protocols[2].MappedCategory != "Yield" || protocols[3].MappedCategory != "Dexs" || protocols[5].MappedCategory != "Uncategorized" {
+		t.Fatalf("unexpected mapped categories: %+v", protocols)
+	}
+
+	mapped := CalculateMappedCategoryBreakdown(protocols)
+	want := map[string]CategoryBreakdown{
+		"Yield":         {Category: "Yield", TVS: 200, ProtocolCount: 1, RawCategories: []string{"Lending"}},
+		"Money Markets": {Category: "Money Markets", TVS: 150, ProtocolCount: 2, RawCategories: []string{"CDP", "Lending"}},
+		"Dexs":          {Category: "Dexs", TVS: 50, ProtocolCount: 2, RawCategories: []string{"Dexs"}},
+		"Uncategorized": {Category: "Uncategorized", TVS: 10, ProtocolCount: 1, RawCategories: []string{"Uncategorized"}},
+	}
+	if len(mapped) != len(want) || mapped[0].Category != "Yield" {
+		t.Fatalf("mapped breakdown = %+v", mapped)
+	}
+	for _, got := range mapped {
+		w := want[got.Category]
+		got.Percentage = 0
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("mapped %s = %+v, want %+v", got.Category, got, w)
+		}
+	}
+
+	raw := CalculateCategoryBreakdown(protocols)
+	if len(raw) != 4 || raw[0].Category != "Lending" || raw[0].RawCategories != nil {
+		t.Fatalf("raw breakdown = %+v", raw)
+	}
+
+	unmapped := FindUnmappedCategories(protocols, taxonomy)
+	wantUnmapped := []UnmappedCategory{
+		{Category: "Dexs", ProtocolCount: 2, TVS: 50, Protocols: []string{"orca", "raydium"}},
+		{Category: "Uncategorized", ProtocolCount: 1, TVS: 10, Protocols: []string{"mystery"}},
+	}
+	if !reflect.DeepEqual(unmapped, wantUnmapped) {
+		t.Fatalf("unmapped = %+v, want %+v", unmapped, wantUnmapped)
+	}
+
+	report := NewCategoryReport(&AggregationResult{Timestamp: 5, TotalProtocols: 6, UnmappedCategories: unmapped})
+	if report.MappedProtocols != 3 || len(report.Unmapped) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+}
+
+func TestAggregate_MappedCategories(t *testing.T) {
+	protocols := []api.Protocol{
+		{Name: "A", Slug: "a", Category: "Lending", Oracles: []string{"Switchboard"}},
+		{Name: "B", Slug: "b", Category: "Dexs", Oracles: []string{"Switchboard"}},
+	}
+	resp := &api.OracleAPIResponse{OraclesTVS: map[string]map[string]map[string]float64{
+		"Switchboard": {"a": {"Solana": 100}, "b": {"Solana": 50}},
+	}}
+
+	plain := NewAggregator("Switchboard", Options{TVSPolicy: testPolicy}).Aggregate(context.Background(), resp, protocols, nil)
+	if plain.MappedCategoryBreakdown != nil || plain.UnmappedCategories != nil {
+		t.Fatalf("expected no mapped output without a taxonomy, got %+v", plain)
+	}
+
+	agg := NewAggregator("Switchboard", Options{TVSPolicy: testPolicy, Categories: testTaxonomy()})
+	result := agg.Aggregate(context.Background(), resp, protocols, nil)
+	if len(result.MappedCategoryBreakdown) != 2 || result.MappedCategoryBreakdown[0].Category != "Money Markets" {
+		t.Fatalf("mapped breakdown = %+v", result.MappedCategoryBreakdown)
+	}
+	if len(result.UnmappedCategories) != 1 || result.UnmappedCategories[0].Category != "Dexs" {
+		t.Fatalf("unmapped = %+v", result.UnmappedCategories)
+	}
+	if result.CategoryBreakdown[0].Category != "Lending" {
+		t.Fatalf("raw breakdown should keep source categories, got %+v", result.CategoryBreakdown)
+	}
+}
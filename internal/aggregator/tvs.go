@@ -1,13 +1,18 @@
 package aggregator
 
-import "strings"
-
-// ExtractProtocolTVS returns total TVS and per-chain TVS for a protocol.
-// It excludes borrowed variants ("chain-borrowed", "borrowed") entirely since
-// borrowed amounts are derived from deposits and should not be double-counted
-// in TVS (Total Value Secured). Returns found=false when the protocol entry
-// is absent or empty.
-func ExtractProtocolTVS(oraclesTVS map[string]map[string]map[string]float64, oracleName, protocolSlug string) (float64, map[string]float64, bool) {
+import (
+	"strings"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/api"
+)
+
+// ExtractProtocolTVS returns total TVS and per-chain TVS for a protocol under
+// policy. Component keys suffixed to a chain ("Solana-borrowed") are folded
+// into that chain according to the policy; bare component keys ("borrowed")
+// are chain totals of the suffixed ones and are skipped so nothing is counted
+// twice. Returns found=false when the protocol entry is absent or no chain
+// carries TVS under the policy.
+func ExtractProtocolTVS(oraclesTVS map[string]map[string]map[string]float64, oracleName, protocolSlug string, policy TVSPolicy) (float64, map[string]float64, bool) {
 	if oraclesTVS == nil || oracleName == "" || protocolSlug == "" {
 		return 0, nil, false
 	}
@@ -23,19 +28,20 @@ func ExtractProtocolTVS(oraclesTVS map[string]map[string]map[string]float64, ora
 	}
 
 	byChain := make(map[string]float64)
-	for chain, tvs := range chains {
-		normalized, isBorrowed := normalizeChainKey(chain)
-		if normalized == "" {
+	for key, tvs := range chains {
+		chain, component := api.SplitChainTVLKey(strings.TrimSpace(key))
+		if chain == "" {
 			continue
 		}
 
-		// Skip borrowed entries entirely - they are derived from deposits
-		// and should not be counted in TVS to avoid double-counting.
-		if isBorrowed {
+		if component == "" {
+			byChain[chain] += tvs
 			continue
 		}
 
-		byChain[normalized] += tvs
+		if delta := policy.contribution(component, tvs); delta != 0 {
+			byChain[chain] += delta
+		}
 	}
 
 	var total float64
@@ -49,17 +55,3 @@ func ExtractProtocolTVS(oraclesTVS map[string]map[string]map[string]float64, ora
 
 	return total, byChain, true
 }
-
-func normalizeChainKey(chain string) (string, bool) {
-	c := strings.TrimSpace(chain)
-	if c == "" {
-		return "", false
-	}
-
-	lower := strings.ToLower(c)
-	if lower == "borrowed" || strings.HasSuffix(lower, "-borrowed") {
-		return "borrowed", true
-	}
-
-	return c, false
-}
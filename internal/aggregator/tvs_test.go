@@ -12,7 +12,7 @@ func TestExtractProtocolTVS_Found(t *testing.T) {
 		},
 	}
 
-	total, byChain, found := ExtractProtocolTVS(oraclesTVS, "Switchboard", "proto-a")
+	total, byChain, found := ExtractProtocolTVS(oraclesTVS, "Switchboard", "proto-a", testPolicy)
 	if !found {
 		t.Fatalf("expected TVS to be found")
 	}
@@ -29,14 +29,14 @@ func TestExtractProtocolTVS_NotFound(t *testing.T) {
 		"Switchboard": {},
 	}
 
-	total, byChain, found := ExtractProtocolTVS(oraclesTVS, "Switchboard", "missing")
+	total, byChain, found := ExtractProtocolTVS(oraclesTVS, "Switchboard", "missing", testPolicy)
 	if found || total != 0 || byChain != nil {
 		t.Fatalf("expected not found result, got found=%v total=%f byChain=%v", found, total, byChain)
 	}
 }
 
 func TestExtractProtocolTVS_BlankSlug(t *testing.T) {
-	total, byChain, found := ExtractProtocolTVS(nil, "Switchboard", "")
+	total, byChain, found := ExtractProtocolTVS(nil, "Switchboard", "", testPolicy)
 	if found || total != 0 || byChain != nil {
 		t.Fatalf("expected not found for blank slug, got found=%v", found)
 	}
@@ -57,7 +57,7 @@ func TestExtractProtocolTVS_ExcludesBorrowed(t *testing.T) {
 		},
 	}
 
-	total, byChain, found := ExtractProtocolTVS(oraclesTVS, "Switchboard", "proto-a")
+	total, byChain, found := ExtractProtocolTVS(oraclesTVS, "Switchboard", "proto-a", testPolicy)
 	if !found {
 		t.Fatalf("expected TVS to be found")
 	}
@@ -97,7 +97,7 @@ func TestExtractProtocolTVS_OnlyBorrowedReturnsNotFound(t *testing.T) {
 		},
 	}
 
-	total, byChain, found := ExtractProtocolTVS(oraclesTVS, "Switchboard", "proto-a")
+	total, byChain, found := ExtractProtocolTVS(oraclesTVS, "Switchboard", "proto-a", testPolicy)
 	if found {
 		t.Fatalf("expected not found when only borrowed entries exist, got total=%f byChain=%+v", total, byChain)
 	}
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+// Endpoint classes guarded by their own circuit breaker.
+const (
+	EndpointClassOracles     = "oracles"
+	EndpointClassProtocols   = "protocols"
+	EndpointClassProtocolTVL = "protocol_tvl"
+	EndpointClassChains      = "chains"
+	EndpointClassChainTVL    = "chain_tvl"
+)
+
+// Breaker defaults applied when the corresponding api.breaker field is zero.
+const (
+	DefaultBreakerFailureThreshold  = 3
+	DefaultBreakerOpenDuration      = 15 * time.Minute
+	DefaultBreakerHalfOpenSuccesses = 1
+)
+
+// ErrCircuitOpen is returned when a breaker is open and no cached response
+// can stand in for the request.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of one endpoint class's circuit breaker.
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// breakerEntry is the persisted state of one breaker.
+type breakerEntry struct {
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	HalfOpenSuccesses   int          `json:"half_open_successes,omitempty"`
+	OpenedAt            time.Time    `json:"opened_at"`
+	LastError           string       `json:"last_error,omitempty"`
+}
+
+// CircuitBreakers tracks one breaker per endpoint class. A breaker opens after
+// failureThreshold consecutive failed fetches, stays open for openDuration,
+// then lets probes through half-open one at a time until halfOpenSuccesses of
+// them succeed (closing it) or one fails (reopening it); requests arriving
+// while a probe is in flight are rejected as if the breaker were open. State
+// is written to path after every change so it survives process restarts and
+// daemon cycles. A nil *CircuitBreakers allows everything.
+type CircuitBreakers struct {
+	mu                sync.Mutex
+	failureThreshold  int
+	openDuration      time.Duration
+	halfOpenSuccesses int
+	path              string
+	entries           map[string]*breakerEntry
+	probing           map[string]bool
+	logger            *slog.Logger
+	now               func() time.Time
+}
+
+// NewCircuitBreakers builds breakers from configuration, loading persisted
+// state from cfg.StateFile when present. It returns nil when disabled.
+func NewCircuitBreakers(cfg config.BreakerConfig, logger *slog.Logger) *CircuitBreakers {
+	if !cfg.Enabled {
+		return nil
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	b := &CircuitBreakers{
+		failureThreshold:  cfg.FailureThreshold,
+		openDuration:      cfg.OpenDuration,
+		halfOpenSuccesses: cfg.HalfOpenSuccesses,
+		path:              strings.TrimSpace(cfg.StateFile),
+		entries:           make(map[string]*breakerEntry),
+		probing:           make(map[string]bool),
+		logger:            logger,
+		now:               time.Now,
+	}
+	if b.failureThreshold <= 0 {
+		b.failureThreshold = DefaultBreakerFailureThreshold
+	}
+	if b.openDuration <= 0 {
+		b.openDuration = DefaultBreakerOpenDuration
+	}
+	if b.halfOpenSuccesses <= 0 {
+		b.halfOpenSuccesses = DefaultBreakerHalfOpenSuccesses
+	}
+
+	b.load()
+	return b
+}
+
+// Allow reports whether a request for class may go upstream and whether it is
+// a half-open probe. An open breaker whose open duration has elapsed moves to
+// half-open here. Only one probe is in flight at a time; the caller must
+// Record its outcome, whatever it is, to release it.
+func (b *CircuitBreakers) Allow(class string) (allowed, probe bool) {
+	if b == nil {
+		return true, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e := b.entry(class)
+	switch e.State {
+	case BreakerOpen:
+		if b.now().Sub(e.OpenedAt) < b.openDuration {
+			return false, false
+		}
+		b.transition(class, e, BreakerHalfOpen, "open_duration_elapsed")
+		e.HalfOpenSuccesses = 0
+		b.save()
+		b.probing[class] = true
+		return true, true
+	case BreakerHalfOpen:
+		if b.probing[class] {
+			return false, false
+		}
+		b.probing[class] = true
+		return true, true
+	default:
+		return true, false
+	}
+}
+
+// Record updates class's breaker with the outcome of a fetch and releases its
+// half-open probe. Outage-like errors (network failures, timeouts,
+// 429/5xx/521) count as failures; nil and not-found count as successes;
+// anything else, including cancellation, is ignored.
+func (b *CircuitBreakers) Record(class string, err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.probing, class)
+	failure := isBreakerFailure(err)
+	if !failure && err != nil && !isNotFoundAPIError(err) {
+		return
+	}
+
+	e := b.entry(class)
+	if failure {
+		e.ConsecutiveFailures++
+		e.LastError = err.Error()
+		switch {
+		case e.State == BreakerHalfOpen:
+			e.OpenedAt = b.now().UTC()
+			b.transition(class, e, BreakerOpen, "probe_failed")
+		case e.State == BreakerClosed && e.ConsecutiveFailures >= b.failureThreshold:
+			e.OpenedAt = b.now().UTC()
+			b.transition(class, e, BreakerOpen, "failure_threshold_reached")
+		}
+		b.save()
+		return
+	}
+
+	changed := e.ConsecutiveFailures > 0
+	e.ConsecutiveFailures = 0
+	e.LastError = ""
+	if e.State == BreakerHalfOpen {
+		e.HalfOpenSuccesses++
+		changed = true
+		if e.HalfOpenSuccesses >= b.halfOpenSuccesses {
+			e.HalfOpenSuccesses = 0
+			b.transition(class, e, BreakerClosed, "probe_succeeded")
+		}
+	}
+	if changed {
+		b.save()
+	}
+}
+
+// State returns the current state of class's breaker.
+func (b *CircuitBreakers) State(class string) BreakerState {
+	if b == nil {
+		return BreakerClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.entry(class).State
+}
+
+func (b *CircuitBreakers) entry(class string) *breakerEntry {
+	e, ok := b.entries[class]
+	if !ok {
+		e = &breakerEntry{State: BreakerClosed}
+		b.entries[class] = e
+	}
+	return e
+}
+
+func (b *CircuitBreakers) transition(class string, e *breakerEntry, to BreakerState, reason string) {
+	from := e.State
+	e.State = to
+
+	attrs := []any{
+		"endpoint_class", class,
+		"from", from,
+		"to", to,
+		"reason", reason,
+		"consecutive_failures", e.ConsecutiveFailures,
+	}
+	if to == BreakerOpen {
+		attrs = append(attrs,
+			"open_until", e.OpenedAt.Add(b.openDuration).Format(time.RFC3339),
+			"last_error", e.LastError,
+		)
+		b.logger.Warn("circuit_breaker_transition", attrs...)
+		return
+	}
+	b.logger.Info("circuit_breaker_transition", attrs...)
+}
+
+func (b *CircuitBreakers) load() {
+	if b.path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			b.logger.Warn("circuit_breaker_state_unreadable", "path", b.path, "error", err)
+		}
+		return
+	}
+
+	var entries map[string]*breakerEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		b.logger.Warn("circuit_breaker_state_invalid", "path", b.path, "error", err)
+		return
+	}
+	for class, e := range entries {
+		if e == nil {
+			continue
+		}
+		switch e.State {
+		case BreakerOpen, BreakerHalfOpen:
+		default:
+			e.State = BreakerClosed
+		}
+		b.entries[class] = e
+	}
+}
+
+// save persists every breaker atomically; failures are logged, not returned,
+// so a read-only output directory degrades to in-memory breakers.
+func (b *CircuitBreakers) save() {
+	if b.path == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(b.entries, "", "  ")
+	if err != nil {
+		b.logger.Warn("circuit_breaker_state_write_failed", "path", b.path, "error", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(b.path), 0o755); err != nil {
+		b.logger.Warn("circuit_breaker_state_write_failed", "path", b.path, "error", err)
+		return
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		b.logger.Warn("circuit_breaker_state_write_failed", "path", b.path, "error", err)
+		return
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		_ = os.Remove(tmp)
+		b.logger.Warn("circuit_breaker_state_write_failed", "path", b.path, "error", err)
+	}
+}
+
+// isBreakerFailure reports whether err looks like an upstream outage rather
+// than a caller or payload problem.
+func isBreakerFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == 0 {
+		return true
+	}
+	if apiErr != nil {
+		return isRetryable(apiErr.StatusCode, err)
+	}
+
+	return isRetryable(0, err)
+}
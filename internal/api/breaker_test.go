@@ -0,0 +1,209 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+func outageError() error {
+	return &APIError{Endpoint: "https://api.llama.fi/oracles", StatusCode: StatusWebServerDown, Message: "unexpected status: 521"}
+}
+
+func TestCircuitBreakers_OpenHalfOpenClose(t *testing.T) {
+	var logs bytes.Buffer
+	now := time.Unix(1_700_000_000, 0)
+	b := NewCircuitBreakers(config.BreakerConfig{
+		Enabled:           true,
+		FailureThreshold:  2,
+		OpenDuration:      time.Minute,
+		HalfOpenSuccesses: 2,
+	}, slog.New(slog.NewTextHandler(&logs, nil)))
+	b.now = func() time.Time { return now }
+
+	b.Record(EndpointClassOracles, outageError())
+	if b.State(EndpointClassOracles) != BreakerClosed {
+		t.Fatalf("expected breaker to stay closed below threshold")
+	}
+	b.Record(EndpointClassOracles, outageError())
+	if allowed, _ := b.Allow(EndpointClassOracles); allowed || b.State(EndpointClassOracles) != BreakerOpen {
+		t.Fatalf("expected breaker open after threshold, state=%s", b.State(EndpointClassOracles))
+	}
+	if allowed, _ := b.Allow(EndpointClassProtocols); !allowed {
+		t.Fatalf("expected other endpoint classes to be unaffected")
+	}
+
+	now = now.Add(time.Minute)
+	allowed, probe := b.Allow(EndpointClassOracles)
+	if !allowed || !probe || b.State(EndpointClassOracles) != BreakerHalfOpen {
+		t.Fatalf("expected half-open probe after open duration, got allowed=%v probe=%v state=%s", allowed, probe, b.State(EndpointClassOracles))
+	}
+
+	b.Record(EndpointClassOracles, nil)
+	if b.State(EndpointClassOracles) != BreakerHalfOpen {
+		t.Fatalf("expected breaker to need two probe successes")
+	}
+	b.Record(EndpointClassOracles, nil)
+	if b.State(EndpointClassOracles) != BreakerClosed {
+		t.Fatalf("expected breaker closed after probe successes, got %s", b.State(EndpointClassOracles))
+	}
+
+	out := logs.String()
+	for _, want := range []string{"to=open", "to=half_open", "to=closed", "endpoint_class=oracles"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected transition log %q, got: %s", want, out)
+		}
+	}
+}
+
+func TestCircuitBreakers_FailedProbeReopens(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	b := NewCircuitBreakers(config.BreakerConfig{Enabled: true, FailureThreshold: 1, OpenDuration: time.Minute}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.now = func() time.Time { return now }
+
+	b.Record(EndpointClassProtocolTVL, outageError())
+	now = now.Add(2 * time.Minute)
+	if allowed, probe := b.Allow(EndpointClassProtocolTVL); !allowed || !probe {
+		t.Fatalf("expected probe to be allowed")
+	}
+
+	b.Record(EndpointClassProtocolTVL, outageError())
+	if allowed, _ := b.Allow(EndpointClassProtocolTVL); allowed {
+		t.Fatalf("expected failed probe to reopen the breaker")
+	}
+}
+
+func TestCircuitBreakers_HalfOpenAllowsOneProbeInFlight(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	b := NewCircuitBreakers(config.BreakerConfig{Enabled: true, FailureThreshold: 1, OpenDuration: time.Minute, HalfOpenSuccesses: 2}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.now = func() time.Time { return now }
+
+	b.Record(EndpointClassProtocolTVL, outageError())
+	now = now.Add(time.Minute)
+	if allowed, probe := b.Allow(EndpointClassProtocolTVL); !allowed || !probe {
+		t.Fatalf("expected first request to probe")
+	}
+	for range 3 {
+		if allowed, _ := b.Allow(EndpointClassProtocolTVL); allowed {
+			t.Fatalf("expected requests to be rejected while the probe is in flight")
+		}
+	}
+
+	b.Record(EndpointClassProtocolTVL, context.Canceled)
+	if allowed, probe := b.Allow(EndpointClassProtocolTVL); !allowed || !probe {
+		t.Fatalf("expected an ignored outcome to release the probe")
+	}
+	b.Record(EndpointClassProtocolTVL, nil)
+	if allowed, probe := b.Allow(EndpointClassProtocolTVL); !allowed || !probe {
+		t.Fatalf("expected the next probe after a success")
+	}
+	if allowed, _ := b.Allow(EndpointClassProtocolTVL); allowed {
+		t.Fatalf("expected the second probe to be exclusive too")
+	}
+	b.Record(EndpointClassProtocolTVL, nil)
+	if b.State(EndpointClassProtocolTVL) != BreakerClosed {
+		t.Fatalf("expected breaker closed, got %s", b.State(EndpointClassProtocolTVL))
+	}
+	if allowed, probe := b.Allow(EndpointClassProtocolTVL); !allowed || probe {
+		t.Fatalf("expected closed breaker to allow without probing")
+	}
+}
+
+func TestCircuitBreakers_IgnoresNonOutageErrors(t *testing.T) {
+	b := NewCircuitBreakers(config.BreakerConfig{Enabled: true, FailureThreshold: 1}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	b.Record(EndpointClassOracles, &APIError{StatusCode: http.StatusBadRequest})
+	b.Record(EndpointClassOracles, context.Canceled)
+	b.Record(EndpointClassOracles, errors.New("decode response: unexpected EOF"))
+	if b.State(EndpointClassOracles) != BreakerClosed {
+		t.Fatalf("expected non-outage errors to leave breaker closed")
+	}
+
+	b.Record(EndpointClassOracles, &APIError{StatusCode: 0, Message: "execute request: connection refused"})
+	if b.State(EndpointClassOracles) != BreakerOpen {
+		t.Fatalf("expected network failure to open breaker")
+	}
+}
+
+func TestCircuitBreakers_PersistsState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "breaker-state.json")
+	cfg := config.BreakerConfig{Enabled: true, FailureThreshold: 1, OpenDuration: time.Hour, StateFile: path}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	first := NewCircuitBreakers(cfg, logger)
+	first.Record(EndpointClassProtocols, outageError())
+
+	second := NewCircuitBreakers(cfg, logger)
+	if second.State(EndpointClassProtocols) != BreakerOpen {
+		t.Fatalf("expected open state to be restored from %s", path)
+	}
+	if allowed, _ := second.Allow(EndpointClassProtocols); allowed {
+		t.Fatalf("expected restored breaker to block requests")
+	}
+
+	if NewCircuitBreakers(config.BreakerConfig{}, logger) != nil {
+		t.Fatalf("expected nil breakers when disabled")
+	}
+}
+
+func TestClient_OpenBreakerSkipsRequestAndUsesCache(t *testing.T) {
+	prevInterval := oraclesMinInterval
+	oraclesMinInterval = 0
+	t.Cleanup(func() { oraclesMinInterval = prevInterval })
+
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(StatusWebServerDown)
+	}))
+	t.Cleanup(server.Close)
+
+	var logs bytes.Buffer
+	client := NewClient(&config.APIConfig{
+		OraclesURL: server.URL,
+		Timeout:    time.Second,
+		Cache:      config.CacheConfig{Directory: t.TempDir()},
+		Breaker:    config.BreakerConfig{Enabled: true, FailureThreshold: 1, OpenDuration: time.Hour},
+	}, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	cached := &OracleAPIResponse{Oracles: map[string][]string{"Switchboard": {"solend"}}}
+	if err := client.cache.Store(cacheKeyOracles, server.URL, cached, cacheMeta{}); err != nil {
+		t.Fatalf("seed cache: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.FetchOracles(context.Background())
+		if err != nil {
+			t.Fatalf("FetchOracles #%d error: %v", i+1, err)
+		}
+		if len(resp.Oracles["Switchboard"]) != 1 {
+			t.Fatalf("expected cached oracles, got %+v", resp.Oracles)
+		}
+	}
+
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("expected only the first fetch to reach upstream, got %d requests", got)
+	}
+	if !strings.Contains(logs.String(), "oracles_breaker_open_cache_used") {
+		t.Fatalf("expected breaker cache log, got: %s", logs.String())
+	}
+
+	client.breakers.Record(EndpointClassProtocolTVL, outageError())
+	if _, err := client.FetchProtocolTVL(context.Background(), "solend"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen without a cached TVL entry, got %v", err)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("expected open protocol_tvl breaker to skip upstream, got %d requests", got)
+	}
+}
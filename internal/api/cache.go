@@ -0,0 +1,268 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+// DefaultCacheDirectory is used when api.cache.directory is unset.
+const DefaultCacheDirectory = "api-cache"
+
+// Cache keys for the datasets the client persists. Protocol TVL entries live
+// under protocols/{slug} and chain TVL histories under chains/{chain}.
+const (
+	cacheKeyOracles   = "oracles"
+	cacheKeyProtocols = "lite-protocols2"
+	cacheKeyChains    = "v2-chains"
+)
+
+// protocolsCacheKey scopes the protocols entry to an oracle filter. The cached
+// body and its validators hold only the protocols the filter kept, so another
+// filter must not reuse them.
+func protocolsCacheKey(oracles []string) string {
+	if len(oracles) == 0 {
+		return cacheKeyProtocols
+	}
+	names := make([]string, 0, len(oracles))
+	for _, name := range oracles {
+		name = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return '-'
+		}, strings.TrimSpace(name))
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return cacheKeyProtocols + "." + strings.Join(slices.Compact(names), "_")
+}
+
+func protocolTVLCacheKey(slug string) string {
+	return "protocols/" + slug
+}
+
+func historicalChainTVLCacheKey(chain string) string {
+	return "chains/" + chain
+}
+
+// ErrCacheTooStale is returned when a cached entry exists but is older than
+// the configured max staleness for fallback use.
+var ErrCacheTooStale = errors.New("cached entry exceeds max staleness")
+
+// CacheEntry describes the provenance of a cached response.
+type CacheEntry struct {
+	Key       string
+	URL       string
+	FetchedAt time.Time
+	Age       time.Duration
+}
+
+// cacheMeta is stored next to each cached body as <key>.meta.json. It records
+// where and when the body was fetched plus the validators DefiLlama returned,
+// so the next request can be made conditional.
+type cacheMeta struct {
+	URL          string    `json:"url"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+}
+
+// Cache persists decoded API responses on disk with provenance metadata.
+// Entries younger than maxAge are served without a network request; entries
+// older than maxStaleness are refused as a fallback. Zero disables either bound.
+type Cache struct {
+	dir          string
+	maxAge       time.Duration
+	maxStaleness time.Duration
+	logger       *slog.Logger
+	now          func() time.Time
+}
+
+// NewCache constructs a Cache from configuration. Nil logger falls back to slog.Default().
+func NewCache(cfg config.CacheConfig, logger *slog.Logger) *Cache {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	dir := cfg.Directory
+	if strings.TrimSpace(dir) == "" {
+		dir = DefaultCacheDirectory
+	}
+
+	return &Cache{
+		dir:          dir,
+		maxAge:       cfg.MaxAge,
+		maxStaleness: cfg.MaxStaleness,
+		logger:       logger,
+		now:          time.Now,
+	}
+}
+
+// Dir returns the directory holding cached entries.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Path returns the file holding the cached body for key.
+func (c *Cache) Path(key string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(key)+".json")
+}
+
+func (c *Cache) metaPath(key string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(key)+".meta.json")
+}
+
+// Load decodes the cached body for key into target and reports its
+// provenance. Entries written before metadata existed use the file's
+// modification time as fetched_at.
+func (c *Cache) Load(key string, target any) (CacheEntry, error) {
+	path := c.Path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return CacheEntry{}, err
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return CacheEntry{}, fmt.Errorf("decode cache %s: %w", path, err)
+	}
+
+	entry := CacheEntry{Key: key}
+	if meta, ok := c.loadMeta(key); ok {
+		entry.URL = meta.URL
+		entry.FetchedAt = meta.FetchedAt
+	}
+	if entry.FetchedAt.IsZero() {
+		if info, err := os.Stat(path); err == nil {
+			entry.FetchedAt = info.ModTime()
+		}
+	}
+	entry.Age = c.now().Sub(entry.FetchedAt)
+
+	return entry, nil
+}
+
+// LoadFresh serves key from cache when the entry is younger than max age.
+func (c *Cache) LoadFresh(key string, target any) (CacheEntry, bool) {
+	if c.maxAge <= 0 {
+		return CacheEntry{}, false
+	}
+
+	entry, err := c.Load(key, target)
+	if err != nil || entry.Age >= c.maxAge {
+		return CacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+// LoadFallback serves key from cache after a failed fetch, refusing entries
+// older than max staleness with ErrCacheTooStale.
+func (c *Cache) LoadFallback(key string, target any) (CacheEntry, error) {
+	entry, err := c.Load(key, target)
+	if err != nil {
+		return CacheEntry{}, err
+	}
+
+	if c.maxStaleness > 0 && entry.Age > c.maxStaleness {
+		return entry, fmt.Errorf("%w: %s is %s old (max %s)", ErrCacheTooStale, key, entry.Age.Truncate(time.Second), c.maxStaleness)
+	}
+
+	return entry, nil
+}
+
+// Store writes value under key together with its source URL, fetch time and
+// validators. Failures are logged and returned.
+func (c *Cache) Store(key, url string, value any, meta cacheMeta) error {
+	if value == nil {
+		return fmt.Errorf("nil cache value for %s", key)
+	}
+
+	path := c.Path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		c.logger.Warn("cache_write_failed", "key", key, "path", path, "error", err)
+		return err
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		c.logger.Warn("cache_marshal_failed", "key", key, "error", err)
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		c.logger.Warn("cache_write_failed", "key", key, "path", path, "error", err)
+		return err
+	}
+
+	meta.URL = url
+	meta.FetchedAt = c.now().UTC()
+	c.saveMeta(key, meta)
+
+	c.logger.Debug("cache_updated", "key", key, "path", path)
+	return nil
+}
+
+// Touch marks key as revalidated now, e.g. after a 304 Not Modified.
+func (c *Cache) Touch(key string) {
+	meta, ok := c.loadMeta(key)
+	if !ok {
+		return
+	}
+
+	meta.FetchedAt = c.now().UTC()
+	c.saveMeta(key, meta)
+}
+
+// Validators returns the stored validators for key when they were issued for
+// url and the cached body they describe still exists.
+func (c *Cache) Validators(key, url string) cacheMeta {
+	if _, err := os.Stat(c.Path(key)); err != nil {
+		return cacheMeta{}
+	}
+
+	meta, ok := c.loadMeta(key)
+	if !ok || !meta.hasValidators(url) {
+		return cacheMeta{}
+	}
+
+	return meta
+}
+
+func (c *Cache) loadMeta(key string) (cacheMeta, bool) {
+	path := c.metaPath(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cacheMeta{}, false
+	}
+
+	var meta cacheMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		c.logger.Warn("cache_meta_invalid", "path", path, "error", err)
+		return cacheMeta{}, false
+	}
+
+	return meta, true
+}
+
+func (c *Cache) saveMeta(key string, meta cacheMeta) {
+	path := c.metaPath(key)
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		c.logger.Warn("cache_meta_marshal_failed", "key", key, "error", err)
+		return
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		c.logger.Warn("cache_meta_write_failed", "path", path, "error", err)
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+func newTestCache(t *testing.T, maxAge, maxStaleness time.Duration) *Cache {
+	t.Helper()
+
+	return NewCache(config.CacheConfig{
+		Directory:    t.TempDir(),
+		MaxAge:       maxAge,
+		MaxStaleness: maxStaleness,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestCache_StoreRecordsProvenance(t *testing.T) {
+	cache := newTestCache(t, 0, 0)
+	fetchedAt := time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return fetchedAt }
+
+	if err := cache.Store(cacheKeyOracles, "https://api.llama.fi/oracles", map[string]int{"a": 1}, cacheMeta{ETag: `"v1"`}); err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+
+	cache.now = func() time.Time { return fetchedAt.Add(30 * time.Minute) }
+	var got map[string]int
+	entry, err := cache.Load(cacheKeyOracles, &got)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if got["a"] != 1 {
+		t.Fatalf("unexpected cached body %v", got)
+	}
+	if entry.URL != "https://api.llama.fi/oracles" || !entry.FetchedAt.Equal(fetchedAt) || entry.Age != 30*time.Minute {
+		t.Fatalf("unexpected provenance %+v", entry)
+	}
+	if v := cache.Validators(cacheKeyOracles, "https://api.llama.fi/oracles"); v.ETag != `"v1"` {
+		t.Fatalf("expected stored validators, got %+v", v)
+	}
+	if v := cache.Validators(cacheKeyOracles, "https://other.example/oracles"); v.ETag != "" {
+		t.Fatalf("expected validators scoped to URL, got %+v", v)
+	}
+}
+
+func TestCache_LegacyEntryUsesModTime(t *testing.T) {
+	cache := newTestCache(t, 0, 0)
+	path := cache.Path(cacheKeyOracles)
+	if err := os.WriteFile(path, []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatalf("write legacy cache: %v", err)
+	}
+	old := time.Now().Add(-5 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	var got map[string]int
+	entry, err := cache.Load(cacheKeyOracles, &got)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if entry.Age < 5*time.Hour-time.Minute {
+		t.Fatalf("expected age from file mtime, got %v", entry.Age)
+	}
+}
+
+func TestCache_FallbackRefusesStaleEntries(t *testing.T) {
+	cache := newTestCache(t, 0, time.Hour)
+	start := time.Now()
+	cache.now = func() time.Time { return start }
+	if err := cache.Store(cacheKeyProtocols, "u", []int{1}, cacheMeta{}); err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+
+	var got []int
+	cache.now = func() time.Time { return start.Add(30 * time.Minute) }
+	if _, err := cache.LoadFallback(cacheKeyProtocols, &got); err != nil {
+		t.Fatalf("expected fallback within staleness, got %v", err)
+	}
+
+	cache.now = func() time.Time { return start.Add(2 * time.Hour) }
+	if _, err := cache.LoadFallback(cacheKeyProtocols, &got); !errors.Is(err, ErrCacheTooStale) {
+		t.Fatalf("expected ErrCacheTooStale, got %v", err)
+	}
+}
+
+func TestCache_LoadFreshHonorsMaxAge(t *testing.T) {
+	cache := newTestCache(t, 10*time.Minute, 0)
+	start := time.Now()
+	cache.now = func() time.Time { return start }
+	if err := cache.Store(cacheKeyOracles, "u", []int{1}, cacheMeta{}); err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+
+	var got []int
+	cache.now = func() time.Time { return start.Add(5 * time.Minute) }
+	if _, ok := cache.LoadFresh(cacheKeyOracles, &got); !ok {
+		t.Fatalf("expected fresh entry within max age")
+	}
+
+	cache.now = func() time.Time { return start.Add(20 * time.Minute) }
+	if _, ok := cache.LoadFresh(cacheKeyOracles, &got); ok {
+		t.Fatalf("expected entry older than max age to be refetched")
+	}
+}
+
+func TestFetchAll_ReportsCacheFallbackProvenance(t *testing.T) {
+	prevInterval := oraclesMinInterval
+	oraclesMinInterval = 0
+	t.Cleanup(func() { oraclesMinInterval = prevInterval })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oracles" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(loadFixture(t, "protocol_response.json"))
+	}))
+	t.Cleanup(server.Close)
+
+	cacheDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cacheDir, "oracles.json"), loadFixture(t, "oracle_response.json"), 0o644); err != nil {
+		t.Fatalf("write cache: %v", err)
+	}
+
+	client := NewClient(&config.APIConfig{
+		OraclesURL:   server.URL + "/oracles",
+		ProtocolsURL: server.URL + "/lite/protocols2",
+		Timeout:      time.Second,
+		Cache:        config.CacheConfig{Directory: cacheDir},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	result, err := client.FetchAll(context.Background())
+	if err != nil {
+		t.Fatalf("FetchAll error: %v", err)
+	}
+	if len(result.CacheEntries) != 1 || result.CacheEntries[0].Key != cacheKeyOracles {
+		t.Fatalf("expected oracle cache fallback to be reported, got %+v", result.CacheEntries)
+	}
+}
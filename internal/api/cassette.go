@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cassettePath maps a request URL onto a file inside a cassette directory. The
+// URL path is mirrored as a relative file path with a ".json" suffix, so
+// /oracles becomes oracles.json and /protocol/{slug} becomes
+// protocol/{slug}.json. Hosts and query strings are ignored so recordings
+// replay regardless of which base URL produced them.
+func cassettePath(dir, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("parse cassette url: %w", err)
+	}
+
+	rel := strings.Trim(u.Path, "/")
+	if rel == "" {
+		rel = "index"
+	}
+
+	path := filepath.Join(dir, filepath.FromSlash(rel)+".json")
+	within, err := filepath.Rel(dir, path)
+	if err != nil || within == ".." || strings.HasPrefix(within, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("cassette path %q escapes directory %s", rel, dir)
+	}
+
+	return path, nil
+}
+
+// recordResponse saves a raw response body into the record directory. Failures
+// are logged and otherwise ignored so recording never breaks a live run.
+func (c *Client) recordResponse(rawURL string, body []byte) {
+	path, err := cassettePath(c.recordDir, rawURL)
+	if err != nil {
+		c.logger.Warn("cassette_record_failed", "url", rawURL, "error", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		c.logger.Warn("cassette_record_failed", "path", path, "error", err)
+		return
+	}
+
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		c.logger.Warn("cassette_record_failed", "path", path, "error", err)
+		return
+	}
+
+	c.logger.Debug("cassette_response_recorded", "url", rawURL, "path", path, "bytes", len(body))
+}
+
+// replayResponse decodes the recorded response for rawURL into target.
+func (c *Client) replayResponse(rawURL string, target any) error {
+	path, err := readCassette(c.replayDir, rawURL, target)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Debug("cassette_response_replayed", "url", rawURL, "path", path)
+	return nil
+}
+
+// readCassette decodes the file that cassettePath maps rawURL onto and returns
+// its path. A missing file is reported as a 404 APIError so callers treat it
+// exactly like an upstream "not found" without touching the network.
+func readCassette(dir, rawURL string, target any) (string, error) {
+	path, err := cassettePath(dir, rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return path, &APIError{
+				Endpoint:   rawURL,
+				StatusCode: http.StatusNotFound,
+				Message:    fmt.Sprintf("no recorded response at %s", path),
+				Err:        err,
+			}
+		}
+		return path, fmt.Errorf("read cassette %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return path, fmt.Errorf("decode cassette %s: %w", path, err)
+	}
+
+	return path, nil
+}
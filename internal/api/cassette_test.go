@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+func TestCassettePath_MirrorsURLPath(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://api.llama.fi/oracles", want: filepath.Join(dir, "oracles.json")},
+		{url: "https://api.llama.fi/lite/protocols2?b=2", want: filepath.Join(dir, "lite", "protocols2.json")},
+		{url: "https://api.llama.fi/protocol/rain.fi", want: filepath.Join(dir, "protocol", "rain.fi.json")},
+		{url: "http://127.0.0.1:8080/", want: filepath.Join(dir, "index.json")},
+	}
+
+	for _, tt := range tests {
+		got, err := cassettePath(dir, tt.url)
+		if err != nil {
+			t.Fatalf("cassettePath(%q) error: %v", tt.url, err)
+		}
+		if got != tt.want {
+			t.Fatalf("cassettePath(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestCassettePath_RejectsTraversal(t *testing.T) {
+	if _, err := cassettePath(t.TempDir(), "https://api.llama.fi/protocol/..%2F..%2Fetc%2Fpasswd"); err == nil {
+		t.Fatalf("expected traversal to be rejected")
+	}
+}
+
+func TestCassette_RecordThenReplay(t *testing.T) {
+	prevInterval := oraclesMinInterval
+	oraclesMinInterval = 0
+	t.Cleanup(func() { oraclesMinInterval = prevInterval })
+
+	oracleFixture := loadFixture(t, "oracle_response.json")
+	protocolFixture := loadFixture(t, "protocol_response.json")
+	tvlFixture := loadFixture(t, "protocol_tvl_response.json")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oracles", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(oracleFixture) })
+	mux.HandleFunc("/lite/protocols2", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(protocolFixture) })
+	mux.HandleFunc("/protocol/", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(tvlFixture) })
+	server := httptest.NewServer(mux)
+
+	cassette := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.APIConfig{
+		OraclesURL:   server.URL + "/oracles",
+		ProtocolsURL: server.URL + "/lite/protocols2?b=2",
+		Timeout:      2 * time.Second,
+		RecordDir:    cassette,
+		Cache:        config.CacheConfig{Directory: t.TempDir()},
+	}
+
+	recorder := NewClient(cfg, logger)
+	recorder.protocolTVLEndpointTemplate = server.URL + "/protocol/%s"
+
+	live, err := recorder.FetchAll(context.Background())
+	if err != nil {
+		t.Fatalf("record FetchAll error: %v", err)
+	}
+	if _, err := recorder.FetchProtocolTVL(context.Background(), "kamino-lend"); err != nil {
+		t.Fatalf("record FetchProtocolTVL error: %v", err)
+	}
+
+	recorded, err := os.ReadFile(filepath.Join(cassette, "oracles.json"))
+	if err != nil {
+		t.Fatalf("expected oracles cassette: %v", err)
+	}
+	if string(recorded) != string(oracleFixture) {
+		t.Fatalf("expected raw oracle body to be recorded verbatim")
+	}
+
+	// Replay must not reach the network.
+	server.Close()
+
+	replayCfg := *cfg
+	replayCfg.RecordDir = ""
+	replayCfg.ReplayDir = cassette
+	replayer := NewClient(&replayCfg, logger)
+	replayer.protocolTVLEndpointTemplate = server.URL + "/protocol/%s"
+
+	replayed, err := replayer.FetchAll(context.Background())
+	if err != nil {
+		t.Fatalf("replay FetchAll error: %v", err)
+	}
+	if len(replayed.Protocols) != len(live.Protocols) {
+		t.Fatalf("expected %d replayed protocols, got %d", len(live.Protocols), len(replayed.Protocols))
+	}
+	if len(replayed.OracleResponse.OraclesTVS) != len(live.OracleResponse.OraclesTVS) {
+		t.Fatalf("expected replayed oraclesTVS to match live response")
+	}
+
+	tvl, err := replayer.FetchProtocolTVL(context.Background(), "kamino-lend")
+	if err != nil || tvl == nil {
+		t.Fatalf("expected replayed TVL, got %v, %v", tvl, err)
+	}
+
+	missing, err := replayer.FetchProtocolTVL(context.Background(), "never-recorded")
+	if err != nil || missing != nil {
+		t.Fatalf("expected missing cassette to behave like not found, got %v, %v", missing, err)
+	}
+}
+
+func TestCassette_ReplayMissingSkipsCacheFallback(t *testing.T) {
+	cacheDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cacheDir, "oracles.json"), loadFixture(t, "oracle_response.json"), 0o644); err != nil {
+		t.Fatalf("write cache: %v", err)
+	}
+
+	client := NewClient(&config.APIConfig{
+		OraclesURL: "https://api.llama.fi/oracles",
+		Timeout:    time.Second,
+		ReplayDir:  t.TempDir(),
+		Cache:      config.CacheConfig{Directory: cacheDir},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	_, err := client.FetchOracles(context.Background())
+	if err == nil {
+		t.Fatalf("expected error for missing cassette")
+	}
+	if !strings.Contains(err.Error(), "no recorded response") {
+		t.Fatalf("expected missing cassette error, got %v", err)
+	}
+}
+
+func TestCassette_RecordBypassesFreshCache(t *testing.T) {
+	prevInterval := oraclesMinInterval
+	oraclesMinInterval = 0
+	t.Cleanup(func() { oraclesMinInterval = prevInterval })
+
+	oracleFixture := loadFixture(t, "oracle_response.json")
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write(oracleFixture)
+	}))
+	t.Cleanup(server.Close)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.APIConfig{
+		OraclesURL: server.URL + "/oracles",
+		Timeout:    2 * time.Second,
+		Cache:      config.CacheConfig{Directory: t.TempDir(), MaxAge: time.Hour},
+	}
+	if _, err := NewClient(cfg, logger).FetchOracles(context.Background()); err != nil {
+		t.Fatalf("warm cache: %v", err)
+	}
+
+	cassette := t.TempDir()
+	recordCfg := *cfg
+	recordCfg.RecordDir = cassette
+	if _, err := NewClient(&recordCfg, logger).FetchOracles(context.Background()); err != nil {
+		t.Fatalf("record FetchOracles error: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected the recorder to request despite a fresh cache entry, got %d requests", requests)
+	}
+	if _, err := os.Stat(filepath.Join(cassette, "oracles.json")); err != nil {
+		t.Fatalf("expected oracles cassette: %v", err)
+	}
+}
+
+func TestCassette_RecordFailsInsteadOfCacheFallback(t *testing.T) {
+	prevInterval := oraclesMinInterval
+	oraclesMinInterval = 0
+	t.Cleanup(func() { oraclesMinInterval = prevInterval })
+
+	oracleFixture := loadFixture(t, "oracle_response.json")
+	healthy := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(oracleFixture)
+	}))
+	t.Cleanup(server.Close)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.APIConfig{
+		OraclesURL: server.URL + "/oracles",
+		Timeout:    2 * time.Second,
+		Cache:      config.CacheConfig{Directory: t.TempDir()},
+		Breaker:    config.BreakerConfig{Enabled: true, FailureThreshold: 1, OpenDuration: time.Hour, HalfOpenSuccesses: 1},
+	}
+	if _, err := NewClient(cfg, logger).FetchOracles(context.Background()); err != nil {
+		t.Fatalf("warm cache: %v", err)
+	}
+
+	healthy = false
+	recordCfg := *cfg
+	recordCfg.RecordDir = t.TempDir()
+	recorder := NewClient(&recordCfg, logger)
+
+	if _, err := recorder.FetchOracles(context.Background()); err == nil {
+		t.Fatalf("expected failed request to error while recording, not fall back to the cache")
+	}
+	_, err := recorder.FetchOracles(context.Background())
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen while recording with an open breaker, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+func TestFetchChainsAndHistoricalChainTVL(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		switch r.URL.Path {
+		case "/v2/chains":
+			_, _ = w.Write([]byte(`[{"gecko_id":"sui","tvl":1500000000,"tokenSymbol":"SUI","name":"Sui","chainId":null}]`))
+		case "/v2/historicalChainTvl/OP Mainnet":
+			_, _ = w.Write([]byte(`[{"date":1700000000,"tvl":100},{"date":1700086400,"tvl":110}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&config.APIConfig{
+		ChainsURL:   server.URL + "/v2/chains",
+		ChainTVLURL: server.URL + "/v2/historicalChainTvl/%s",
+		Timeout:     time.Second,
+		Cache:       config.CacheConfig{Directory: t.TempDir()},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	chains, err := client.FetchChains(context.Background())
+	if err != nil {
+		t.Fatalf("FetchChains error: %v", err)
+	}
+	if len(chains) != 1 || chains[0].Name != "Sui" || chains[0].TVL != 1.5e9 || chains[0].GeckoID != "sui" {
+		t.Fatalf("unexpected chains: %+v", chains)
+	}
+
+	points, err := client.FetchHistoricalChainTVL(context.Background(), "OP Mainnet")
+	if err != nil {
+		t.Fatalf("FetchHistoricalChainTVL error: %v", err)
+	}
+	if len(points) != 2 || points[1] != (ChainTVLPoint{Date: 1700086400, TVL: 110}) {
+		t.Fatalf("unexpected points: %+v", points)
+	}
+	if paths[1] != "/v2/historicalChainTvl/OP%20Mainnet" {
+		t.Fatalf("expected escaped chain name in path, got %s", paths[1])
+	}
+
+	missing, err := client.FetchHistoricalChainTVL(context.Background(), "Nowhere")
+	if err != nil || missing != nil {
+		t.Fatalf("expected (nil, nil) for unknown chain, got %v, %v", missing, err)
+	}
+}
+
+func TestFetchHistoricalChainTVL_FreshCacheSkipsRateLimit(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`[{"date":1700000000,"tvl":100}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&config.APIConfig{
+		ChainTVLURL: server.URL + "/v2/historicalChainTvl/%s",
+		Timeout:     time.Second,
+		Cache:       config.CacheConfig{Directory: t.TempDir(), MaxAge: time.Hour},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	// One token per hour: a second request would wait past the deadline.
+	client.protocolLimiter = NewRateLimiter(1.0/3600, 1)
+
+	if _, err := client.FetchHistoricalChainTVL(context.Background(), "Solana"); err != nil {
+		t.Fatalf("first fetch error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	points, err := client.FetchHistoricalChainTVL(ctx, "Solana")
+	if err != nil {
+		t.Fatalf("expected fresh cache hit without a rate-limit token, got %v", err)
+	}
+	if len(points) != 1 || requests != 1 {
+		t.Fatalf("expected 1 cached point and 1 request, got %+v and %d requests", points, requests)
+	}
+}
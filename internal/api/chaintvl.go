@@ -0,0 +1,36 @@
+package api
+
+import "strings"
+
+// chainTVLExtras are the breakdown categories DefiLlama reports next to plain
+// chain TVL, either alone ("borrowed") or suffixed to a chain ("Solana-borrowed").
+var chainTVLExtras = map[string]struct{}{
+	"borrowed":       {},
+	"staking":        {},
+	"pool2":          {},
+	"doublecounted":  {},
+	"liquidstaking":  {},
+	"dcandlsoverlap": {},
+	"vesting":        {},
+	"offers":         {},
+	"treasury":       {},
+}
+
+// SplitChainTVLKey splits a chainTvls/currentChainTvls key into its chain and
+// breakdown category. "Solana" yields ("Solana", ""), "Solana-borrowed"
+// yields ("Solana", "borrowed") and "staking" yields ("", "staking"). Chain
+// names that merely contain a hyphen are returned whole.
+func SplitChainTVLKey(key string) (chain, extra string) {
+	if _, ok := chainTVLExtras[strings.ToLower(key)]; ok {
+		return "", strings.ToLower(key)
+	}
+
+	if i := strings.LastIndex(key, "-"); i > 0 {
+		suffix := strings.ToLower(key[i+1:])
+		if _, ok := chainTVLExtras[suffix]; ok {
+			return key[:i], suffix
+		}
+	}
+
+	return key, ""
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -9,8 +10,7 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
 	"sync"
 	"time"
 
@@ -18,20 +18,15 @@ import (
 
 	"github.com/switchboard-xyz/defillama-extract/internal/config"
 	"golang.org/x/net/http2"
-	"golang.org/x/sync/errgroup"
 )
 
 const userAgentValue = "defillama-extract/1.0"
 
-var oraclesCachePath = filepath.Join("api-cache", "oracles.json")
-var protocolsCachePath = filepath.Join("api-cache", "lite-protocols2.json")
-var protocolTVLCacheDir = filepath.Join("api-cache", "protocols")
 var oraclesMinInterval = 4 * time.Second
 
 type contextKey string
 
 const attemptContextKey contextKey = "api_attempt"
-const protocolRateLimitInterval = 200 * time.Millisecond
 
 // Client wraps http.Client with configuration needed for DefiLlama requests.
 type Client struct {
@@ -39,19 +34,31 @@ type Client struct {
 	oraclesURL                  string
 	protocolsURL                string
 	protocolTVLEndpointTemplate string
+	chainsURL                   string
+	chainTVLEndpointTemplate    string
 	userAgent                   string
 	maxRetries                  int
 	retryDelay                  time.Duration
 	logger                      *slog.Logger
 	rng                         *rand.Rand
 	rngMu                       sync.Mutex
-	protocolRateMu              sync.Mutex
-	nextProtocolAllowedAt       time.Time
+	protocolLimiter             *RateLimiter
 	oracleRateMu                sync.Mutex
 	nextOracleAllowedAt         time.Time
 	minOracleInterval           time.Duration
+	maxRetryAfter               time.Duration
 	headerRandomizer            *HeaderRandomizer
 	randomizeHeaders            bool
+	cache                       *Cache
+	outcomeMu                   sync.Mutex
+	outcomes                    map[string]fetchOutcome
+	oracleFilter                []string
+	protocolIndex               *ProtocolIndex
+	schema                      *SchemaChecker
+	breakers                    *CircuitBreakers
+	metrics                     fetchMetrics
+	recordDir                   string
+	replayDir                   string
 }
 
 // NewClient constructs a Client using API configuration. Nil logger falls back to slog.Default().
@@ -70,25 +77,75 @@ func NewClient(cfg *config.APIConfig, logger *slog.Logger) *Client {
 		protocolsURL = ProtocolsEndpoint
 	}
 
-	// Use browser-like transport for better compatibility with Cloudflare
+	protocolTVLTemplate := cfg.ProtocolTVLURL
+	if protocolTVLTemplate == "" {
+		protocolTVLTemplate = ProtocolTVLEndpointTemplate
+	}
+
+	chainsURL := cfg.ChainsURL
+	if chainsURL == "" {
+		chainsURL = ChainsEndpoint
+	}
+
+	chainTVLTemplate := cfg.ChainTVLURL
+	if chainTVLTemplate == "" {
+		chainTVLTemplate = HistoricalChainTVLEndpointTemplate
+	}
+
+	protocolRPS := cfg.ProtocolRPS
+	if protocolRPS == 0 {
+		protocolRPS = DefaultProtocolRPS
+	}
+	protocolBurst := cfg.ProtocolBurst
+	if protocolBurst == 0 {
+		protocolBurst = DefaultProtocolBurst
+	}
+
+	maxRetryAfter := cfg.MaxRetryAfter
+	if maxRetryAfter == 0 {
+		maxRetryAfter = DefaultMaxRetryAfter
+	}
+
+	schema, err := newSchemaCheckerFromConfig(cfg.Schema)
+	if err != nil {
+		logger.Warn("schema_manifest_invalid", "path", cfg.Schema.ManifestPath, "error", err)
+	}
+
+	// The browser-like transport (default) gives better compatibility with Cloudflare
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		logger.Error("transport_config_invalid", "transport", cfg.Transport, "error", err)
+		transport = errTransport{err: err}
+	} else if cfg.ProxyURL != "" {
+		logger.Debug("transport_proxy_configured", "transport", cfg.Transport, "proxy", redactURL(cfg.ProxyURL))
+	}
 	httpClient := &http.Client{
 		Timeout:   cfg.Timeout,
-		Transport: NewBrowserTransport(),
+		Transport: newFailoverTransport(transport, cfg, logger),
 	}
 
 	return &Client{
 		httpClient:                  httpClient,
 		oraclesURL:                  oraclesURL,
 		protocolsURL:                protocolsURL,
-		protocolTVLEndpointTemplate: ProtocolTVLEndpointTemplate,
+		protocolTVLEndpointTemplate: protocolTVLTemplate,
+		chainsURL:                   chainsURL,
+		chainTVLEndpointTemplate:    chainTVLTemplate,
 		userAgent:                   userAgentValue,
 		maxRetries:                  cfg.MaxRetries,
 		retryDelay:                  cfg.RetryDelay,
 		logger:                      logger,
 		rng:                         rand.New(rand.NewSource(time.Now().UnixNano())),
 		minOracleInterval:           oraclesMinInterval,
+		maxRetryAfter:               maxRetryAfter,
+		cache:                       NewCache(cfg.Cache, logger),
+		schema:                      schema,
+		breakers:                    NewCircuitBreakers(cfg.Breaker, logger),
+		protocolLimiter:             NewRateLimiter(protocolRPS, protocolBurst),
 		headerRandomizer:            NewHeaderRandomizer(),
 		randomizeHeaders:            cfg.RandomizeHeaders,
+		recordDir:                   cfg.RecordDir,
+		replayDir:                   cfg.ReplayDir,
 	}
 }
 
@@ -104,18 +161,46 @@ func attemptFromContext(ctx context.Context) int {
 
 // doRequest performs a GET request with User-Agent injection and JSON decoding.
 func (c *Client) doRequest(ctx context.Context, url string, target any) error {
+	_, err := c.doConditionalRequest(ctx, url, target, cacheMeta{})
+	return err
+}
+
+// conditionalResult describes the outcome of doConditionalRequest.
+type conditionalResult struct {
+	notModified bool
+	meta        cacheMeta
+}
+
+// doConditionalRequest performs doRequest, sending the validators in cond when
+// they apply to url. A 304 response leaves target untouched and reports
+// notModified; otherwise the response's own validators are returned. Each
+// attempt is traced and recorded in the client's fetch report under the
+// endpoint class carried by ctx.
+func (c *Client) doConditionalRequest(ctx context.Context, url string, target any, cond cacheMeta) (_ conditionalResult, err error) {
 	start := time.Now()
 	if url == c.oraclesURL {
 		if err := c.waitForOracleRateLimit(ctx); err != nil {
-			return err
+			return conditionalResult{}, err
+		}
+	} else if endpointClassFromContext(ctx) == EndpointClassChainTVL {
+		// Taken here rather than by the caller so a fresh cache hit or an
+		// open breaker does not spend a protocol token.
+		if err := c.waitForProtocolRateLimit(ctx); err != nil {
+			return conditionalResult{}, err
 		}
 	}
 	attempt := attemptFromContext(ctx)
 	method := http.MethodGet
 
+	ctx, trace := newRequestTrace(ctx)
+	responded := false
+	defer func() {
+		c.metrics.recordRequest(endpointClassFromContext(ctx), trace.finish(), err != nil, responded)
+	}()
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return conditionalResult{}, fmt.Errorf("create request: %w", err)
 	}
 
 	if c.randomizeHeaders {
@@ -123,6 +208,9 @@ func (c *Client) doRequest(ctx context.Context, url string, target any) error {
 	} else {
 		req.Header.Set("User-Agent", c.userAgent)
 	}
+	if cond.hasValidators(url) {
+		cond.apply(req)
+	}
 
 	c.logger.Debug("starting API request",
 		"url", url,
@@ -138,7 +226,7 @@ func (c *Client) doRequest(ctx context.Context, url string, target any) error {
 			"duration_ms", duration.Milliseconds(),
 			"error", err,
 		)
-		return &APIError{
+		return conditionalResult{}, &APIError{
 			Endpoint:   url,
 			StatusCode: 0,
 			Message:    fmt.Sprintf("execute request: %v", err),
@@ -146,9 +234,20 @@ func (c *Client) doRequest(ctx context.Context, url string, target any) error {
 		}
 	}
 	defer resp.Body.Close()
+	responded = true
 
 	duration := time.Since(start)
 
+	if resp.StatusCode == http.StatusNotModified && cond.hasValidators(url) {
+		c.logger.Info("API request completed", append([]any{
+			"url", url,
+			"status", resp.StatusCode,
+			"duration_ms", duration.Milliseconds(),
+			"not_modified", true,
+		}, trace.connectionAttrs()...)...)
+		return conditionalResult{notModified: true, meta: cond}, nil
+	}
+
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
 		err := fmt.Errorf("unexpected status: %d", resp.StatusCode)
 		logAttrs := []any{
@@ -161,149 +260,67 @@ func (c *Client) doRequest(ctx context.Context, url string, target any) error {
 		if resp.StatusCode == StatusWebServerDown {
 			logAttrs = append(logAttrs, "geo_blocked", true)
 		}
+		retryAfter := parseRetryAfter(resp.StatusCode, resp.Header, time.Now())
+		if retryAfter > 0 {
+			logAttrs = append(logAttrs, "retry_after_ms", retryAfter.Milliseconds())
+		}
 		c.logger.Warn("API request failed", logAttrs...)
-		return &APIError{
+		return conditionalResult{}, &APIError{
 			Endpoint:   url,
 			StatusCode: resp.StatusCode,
 			Message:    err.Error(),
 			Err:        err,
+			RetryAfter: retryAfter,
 		}
 	}
 
-	c.logger.Info("API request completed",
+	c.logger.Info("API request completed", append([]any{
 		"url", url,
 		"status", resp.StatusCode,
 		"duration_ms", duration.Milliseconds(),
-	)
-
-	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
-		return fmt.Errorf("decode response: %w", err)
-	}
-
-	return nil
-}
-
-func (c *Client) loadOraclesCache() (*OracleAPIResponse, error) {
-	data, err := os.ReadFile(oraclesCachePath)
-	if err != nil {
-		return nil, err
-	}
-
-	var resp OracleAPIResponse
-	if err := json.Unmarshal(data, &resp); err != nil {
-		return nil, err
-	}
-
-	return &resp, nil
-}
-
-func (c *Client) saveOraclesCache(resp *OracleAPIResponse) error {
-	if resp == nil {
-		return errors.New("nil oracles response")
-	}
-
-	if err := os.MkdirAll(filepath.Dir(oraclesCachePath), 0o755); err != nil {
-		c.logger.Warn("oracles_cache_write_failed", "path", oraclesCachePath, "error", err)
-		return err
-	}
-
-	data, err := json.MarshalIndent(resp, "", "  ")
-	if err != nil {
-		c.logger.Warn("oracles_cache_marshal_failed", "error", err)
-		return err
-	}
-
-	if err := os.WriteFile(oraclesCachePath, data, 0o644); err != nil {
-		c.logger.Warn("oracles_cache_write_failed", "path", oraclesCachePath, "error", err)
-		return err
-	}
-
-	c.logger.Debug("oracles_cache_updated", "path", oraclesCachePath)
-	return nil
-}
-
-func (c *Client) loadProtocolsCache() ([]Protocol, error) {
-	data, err := os.ReadFile(protocolsCachePath)
-	if err != nil {
-		return nil, err
-	}
+	}, trace.connectionAttrs()...)...)
 
-	var protocols protocolList
-	if err := json.Unmarshal(data, &protocols); err != nil {
-		return nil, err
-	}
-
-	return []Protocol(protocols), nil
-}
-
-func (c *Client) saveProtocolsCache(protocols []Protocol) error {
-	if protocols == nil {
-		return errors.New("nil protocols response")
-	}
-
-	if err := os.MkdirAll(filepath.Dir(protocolsCachePath), 0o755); err != nil {
-		c.logger.Warn("protocols_cache_write_failed", "path", protocolsCachePath, "error", err)
-		return err
-	}
-
-	data, err := json.MarshalIndent(protocolList(protocols), "", "  ")
-	if err != nil {
-		c.logger.Warn("protocols_cache_marshal_failed", "error", err)
-		return err
-	}
-
-	if err := os.WriteFile(protocolsCachePath, data, 0o644); err != nil {
-		c.logger.Warn("protocols_cache_write_failed", "path", protocolsCachePath, "error", err)
-		return err
-	}
+	observe := c.schemaObserver(target)
+	sd, streaming := target.(streamDecoder)
+	observeBody := observe != nil && !streaming
 
-	c.logger.Debug("protocols_cache_updated", "path", protocolsCachePath)
-	return nil
-}
-
-func protocolTVLCachePath(slug string) string {
-	return filepath.Join(protocolTVLCacheDir, slug+".json")
-}
-
-func (c *Client) loadProtocolTVLCache(slug string) (*ProtocolTVLResponse, error) {
-	cachePath := protocolTVLCachePath(slug)
-	data, err := os.ReadFile(cachePath)
-	if err != nil {
-		return nil, err
-	}
-
-	var resp ProtocolTVLResponse
-	if err := json.Unmarshal(data, &resp); err != nil {
-		return nil, err
+	bodyStart := time.Now()
+	metered := &meteredReader{r: resp.Body}
+	var body io.Reader = metered
+	var recorded bytes.Buffer
+	if c.recordDir != "" || observeBody {
+		body = io.TeeReader(metered, &recorded)
 	}
 
-	return &resp, nil
-}
-
-func (c *Client) saveProtocolTVLCache(slug string, resp *ProtocolTVLResponse) error {
-	if resp == nil {
-		return errors.New("nil protocol TVL response")
+	dec := json.NewDecoder(body)
+	if streaming {
+		err = sd.decodeStream(dec, observe)
+	} else {
+		err = dec.Decode(target)
 	}
-
-	cachePath := protocolTVLCachePath(slug)
-	if err := os.MkdirAll(filepath.Dir(cachePath), 0o755); err != nil {
-		c.logger.Warn("protocol_tvl_cache_write_failed", "path", cachePath, "error", err)
-		return err
+	// Observe before reporting decode errors: a changed field type usually
+	// surfaces as one, and the drift report should say which field moved.
+	if observeBody {
+		_, _ = io.Copy(io.Discard, body)
+		observe(recorded.Bytes())
 	}
-
-	data, err := json.MarshalIndent(resp, "", "  ")
+	trace.bodyRead(metered, time.Since(bodyStart))
+	c.logger.Debug("API response decoded",
+		"url", url,
+		"bytes", metered.n,
+		"body_read_ms", metered.elapsed.Milliseconds(),
+		"decode_ms", (time.Since(bodyStart) - metered.elapsed).Milliseconds(),
+	)
 	if err != nil {
-		c.logger.Warn("protocol_tvl_cache_marshal_failed", "slug", slug, "error", err)
-		return err
+		return conditionalResult{}, fmt.Errorf("decode response: %w", err)
 	}
 
-	if err := os.WriteFile(cachePath, data, 0o644); err != nil {
-		c.logger.Warn("protocol_tvl_cache_write_failed", "path", cachePath, "error", err)
-		return err
+	if c.recordDir != "" {
+		_, _ = io.Copy(io.Discard, body)
+		c.recordResponse(url, recorded.Bytes())
 	}
 
-	c.logger.Debug("protocol_tvl_cache_updated", "path", cachePath)
-	return nil
+	return conditionalResult{meta: metaFromResponse(url, resp)}, nil
 }
 
 func (c *Client) waitForOracleRateLimit(ctx context.Context) error {
@@ -331,6 +348,18 @@ func (c *Client) waitForOracleRateLimit(ctx context.Context) error {
 	}
 }
 
+// deferOracleRequests pushes the oracle pacing window out to until when the
+// server asks us to back off, so the next oracle request waits for the later of
+// the advised delay and minOracleInterval.
+func (c *Client) deferOracleRequests(until time.Time) {
+	c.oracleRateMu.Lock()
+	defer c.oracleRateMu.Unlock()
+
+	if until.After(c.nextOracleAllowedAt) {
+		c.nextOracleAllowedAt = until
+	}
+}
+
 // StatusWebServerDown is Cloudflare's 521 status code for origin server down/geo-blocked.
 const StatusWebServerDown = 521
 
@@ -386,32 +415,10 @@ func isNotFoundAPIError(err error) bool {
 	return false
 }
 
+// waitForProtocolRateLimit takes a token from the protocol limiter shared by
+// every caller of this client, so concurrent TVL workers respect one budget.
 func (c *Client) waitForProtocolRateLimit(ctx context.Context) error {
-	c.protocolRateMu.Lock()
-	now := time.Now()
-	waitUntil := now
-
-	if c.nextProtocolAllowedAt.After(now) {
-		waitUntil = c.nextProtocolAllowedAt
-	}
-
-	c.nextProtocolAllowedAt = waitUntil.Add(protocolRateLimitInterval)
-	c.protocolRateMu.Unlock()
-
-	wait := time.Until(waitUntil)
-	if wait <= 0 {
-		return nil
-	}
-
-	timer := time.NewTimer(wait)
-	defer timer.Stop()
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-timer.C:
-		return nil
-	}
+	return c.protocolLimiter.Wait(ctx)
 }
 
 func (c *Client) calculateBackoff(attempt int, baseDelay time.Duration) time.Duration {
@@ -469,11 +476,25 @@ func (c *Client) doWithRetry(ctx context.Context, fn func(context.Context) error
 		}
 
 		backoff := c.calculateBackoff(attempt, c.retryDelay)
+		backoffSource := "exponential"
+		if apiErr != nil && apiErr.RetryAfter > 0 {
+			backoff = apiErr.RetryAfter
+			backoffSource = "server"
+			if c.maxRetryAfter > 0 && backoff > c.maxRetryAfter {
+				backoff = c.maxRetryAfter
+				backoffSource = "server_capped"
+			}
+			if lastEndpoint == c.oraclesURL {
+				c.deferOracleRequests(time.Now().Add(backoff))
+			}
+		}
+		c.metrics.recordRetry(endpointClassFromContext(ctx))
 		c.logger.Warn("retrying API request",
 			"url", lastEndpoint,
 			"attempt", attempt+1,
 			"max_attempts", maxAttempts,
 			"backoff_ms", backoff.Milliseconds(),
+			"backoff_source", backoffSource,
 			"error", err,
 		)
 
@@ -487,62 +508,260 @@ func (c *Client) doWithRetry(ctx context.Context, fn func(context.Context) error
 	return lastErr
 }
 
-// FetchOracles retrieves oracle TVS data from DefiLlama /oracles endpoint.
-func (c *Client) FetchOracles(ctx context.Context) (*OracleAPIResponse, error) {
-	var response OracleAPIResponse
-	if err := c.doWithRetry(ctx, func(ctx context.Context) error {
-		return c.doRequest(ctx, c.oraclesURL, &response)
-	}); err != nil {
-		cached, cacheErr := c.loadOraclesCache()
+// doGuarded runs fn under class's circuit breaker and records the outcome. A
+// half-open probe makes a single attempt rather than spending the full retry
+// budget on an endpoint that was just failing.
+func (c *Client) doGuarded(ctx context.Context, class string, probe bool, fn func(context.Context) error) error {
+	var err error
+	if probe {
+		err = fn(context.WithValue(ctx, attemptContextKey, 1))
+	} else {
+		err = c.doWithRetry(ctx, fn)
+	}
+	c.breakers.Record(class, err)
+	return err
+}
+
+// fetchOutcome records how a cached dataset was obtained on the last fetch.
+type fetchOutcome struct {
+	notModified bool
+	fromCache   bool
+	entry       CacheEntry
+}
+
+// fetchCached fetches url into target through the cache: fresh entries are
+// served without a request, validators make the request conditional, a 304
+// reuses the cached body, and failures fall back to entries within max
+// staleness. dataset prefixes log event names (e.g. oracles_fallback_cache_used)
+// and names the circuit breaker guarding the request; while it is open the
+// request is skipped and the cache fallback is used directly.
+func (c *Client) fetchCached(ctx context.Context, dataset, key, url string, target any) error {
+	c.setOutcome(key, fetchOutcome{})
+	ctx = withEndpointClass(ctx, dataset)
+	// A recording run must capture every response; serving any dataset from
+	// the cache would leave a hole in the cassette.
+	recording := c.recordDir != ""
+
+	if !recording {
+		if entry, ok := c.cache.LoadFresh(key, target); ok {
+			c.logger.Info(dataset+"_fresh_cache_used",
+				"path", c.cache.Path(key),
+				"cache_age_seconds", int64(entry.Age.Seconds()),
+			)
+			c.setOutcome(key, fetchOutcome{fromCache: true, entry: entry})
+			return nil
+		}
+	}
+
+	allowed, probe := c.breakers.Allow(dataset)
+	if !allowed {
+		if recording {
+			return fmt.Errorf("%w for %s: cannot record while the breaker is open", ErrCircuitOpen, dataset)
+		}
+		entry, cacheErr := c.cache.LoadFallback(key, target)
+		if cacheErr != nil {
+			return fmt.Errorf("%w for %s: %v", ErrCircuitOpen, dataset, cacheErr)
+		}
+		c.metrics.recordCacheFallback(dataset)
+		c.logger.Warn(dataset+"_breaker_open_cache_used",
+			"path", c.cache.Path(key),
+			"cache_age_seconds", int64(entry.Age.Seconds()),
+		)
+		c.setOutcome(key, fetchOutcome{fromCache: true, entry: entry})
+		return nil
+	}
+
+	cond := c.conditionalValidators(key, url)
+	var result conditionalResult
+	err := c.doGuarded(ctx, dataset, probe, func(ctx context.Context) error {
+		var err error
+		result, err = c.doConditionalRequest(ctx, url, target, cond)
+		return err
+	})
+	if err == nil && result.notModified {
+		entry, loadErr := c.cache.Load(key, target)
+		if loadErr == nil {
+			c.cache.Touch(key)
+			c.logger.Info(dataset+"_unchanged_upstream", "path", c.cache.Path(key))
+			c.setOutcome(key, fetchOutcome{notModified: true, entry: entry})
+			return nil
+		}
+
+		// The cached body vanished after validators were read; fall back to a
+		// full download so a 304 never leaves the run without data.
+		c.logger.Warn(dataset+"_cache_missing_after_not_modified", "path", c.cache.Path(key), "error", loadErr)
+		err = c.doWithRetry(ctx, func(ctx context.Context) error {
+			var err error
+			result, err = c.doConditionalRequest(ctx, url, target, cacheMeta{})
+			return err
+		})
+	}
+	if err != nil {
+		if recording {
+			return err
+		}
+		entry, cacheErr := c.cache.LoadFallback(key, target)
 		if cacheErr == nil {
-			c.logger.Warn("oracles_fallback_cache_used",
-				"path", oraclesCachePath,
+			c.metrics.recordCacheFallback(dataset)
+			c.logger.Warn(dataset+"_fallback_cache_used",
+				"path", c.cache.Path(key),
+				"cache_age_seconds", int64(entry.Age.Seconds()),
 				"error", err,
 			)
-			return cached, nil
+			c.setOutcome(key, fetchOutcome{fromCache: true, entry: entry})
+			return nil
+		}
+		if errors.Is(cacheErr, ErrCacheTooStale) {
+			c.logger.Warn(dataset+"_fallback_cache_too_stale", "path", c.cache.Path(key), "error", cacheErr)
 		}
 
-		return nil, fmt.Errorf("fetch oracles: %w", err)
+		return err
+	}
+
+	_ = c.cache.Store(key, url, target, result.meta)
+	return nil
+}
+
+func (c *Client) setOutcome(key string, outcome fetchOutcome) {
+	c.outcomeMu.Lock()
+	defer c.outcomeMu.Unlock()
+
+	if c.outcomes == nil {
+		c.outcomes = make(map[string]fetchOutcome)
+	}
+	c.outcomes[key] = outcome
+}
+
+func (c *Client) outcome(key string) fetchOutcome {
+	c.outcomeMu.Lock()
+	defer c.outcomeMu.Unlock()
+
+	return c.outcomes[key]
+}
+
+// FetchOracles retrieves oracle TVS data from DefiLlama /oracles endpoint.
+func (c *Client) FetchOracles(ctx context.Context) (*OracleAPIResponse, error) {
+	var response OracleAPIResponse
+	if c.replayDir != "" {
+		if err := c.replayResponse(c.oraclesURL, &response); err != nil {
+			return nil, fmt.Errorf("fetch oracles: %w", err)
+		}
+		return &response, nil
 	}
 
-	_ = c.saveOraclesCache(&response)
+	if err := c.fetchCached(ctx, "oracles", cacheKeyOracles, c.oraclesURL, &response); err != nil {
+		return nil, fmt.Errorf("fetch oracles: %w", err)
+	}
 
 	return &response, nil
 }
 
-// FetchProtocols retrieves protocol metadata from DefiLlama /lite/protocols2 endpoint.
+// SetOracleFilter restricts FetchProtocols to protocols using one of names
+// (case-insensitive). The full listing is still indexed by slug and name.
+// Each filter caches its protocols under its own entry.
+func (c *Client) SetOracleFilter(names ...string) {
+	c.oracleFilter = names
+}
+
+// ProtocolIndex returns the slug/name index built by the last FetchProtocols.
+func (c *Client) ProtocolIndex() *ProtocolIndex {
+	c.outcomeMu.Lock()
+	defer c.outcomeMu.Unlock()
+
+	return c.protocolIndex
+}
+
+// FetchProtocols retrieves protocol metadata from DefiLlama /lite/protocols2
+// endpoint, stream-decoding the listing and keeping only protocols that match
+// the oracle filter.
 func (c *Client) FetchProtocols(ctx context.Context) ([]Protocol, error) {
-	var protocols protocolList
-	if err := c.doWithRetry(ctx, func(ctx context.Context) error {
-		return c.doRequest(ctx, c.protocolsURL, &protocols)
-	}); err != nil {
-		cached, cacheErr := c.loadProtocolsCache()
-		if cacheErr == nil {
-			c.logger.Warn("protocols_fallback_cache_used",
-				"path", protocolsCachePath,
-				"error", err,
-			)
-			return cached, nil
+	stream := newProtocolStream(c.oracleFilter)
+	if c.replayDir != "" {
+		if err := c.replayResponse(c.protocolsURL, stream); err != nil {
+			return nil, fmt.Errorf("fetch protocols: %w", err)
 		}
-
+	} else if err := c.fetchCached(ctx, "protocols", protocolsCacheKey(c.oracleFilter), c.protocolsURL, stream); err != nil {
 		return nil, fmt.Errorf("fetch protocols: %w", err)
 	}
 
-	_ = c.saveProtocolsCache([]Protocol(protocols))
+	index := NewProtocolIndex(stream.Index)
+	c.outcomeMu.Lock()
+	c.protocolIndex = index
+	c.outcomeMu.Unlock()
+
+	c.logger.Debug("protocols_stream_decoded",
+		"indexed", index.Len(),
+		"kept", len(stream.Protocols),
+		"oracle_filter", c.oracleFilter,
+	)
 
-	return []Protocol(protocols), nil
+	return stream.Protocols, nil
 }
 
 // FetchProtocolTVL retrieves historical TVL data for a protocol from DefiLlama /protocol/{slug} endpoint.
 func (c *Client) FetchProtocolTVL(ctx context.Context, slug string) (*ProtocolTVLResponse, error) {
+	url := fmt.Sprintf(c.protocolTVLEndpointTemplate, slug)
+	var response ProtocolTVLResponse
+
+	if c.replayDir != "" {
+		if err := c.replayResponse(url, &response); err != nil {
+			if isNotFoundAPIError(err) {
+				c.logger.Warn("protocol_not_found",
+					"slug", slug,
+					"status_code", http.StatusNotFound,
+					"replay", true,
+				)
+				return nil, nil
+			}
+			return nil, fmt.Errorf("fetch protocol TVL %s: %w", slug, err)
+		}
+		return &response, nil
+	}
+
+	key := protocolTVLCacheKey(slug)
+	ctx = withEndpointClass(ctx, EndpointClassProtocolTVL)
+	// Same cache policy as fetchCached: fresh entries skip the request, and
+	// a recording run never serves from the cache.
+	recording := c.recordDir != ""
+	if !recording {
+		var cached ProtocolTVLResponse
+		if entry, ok := c.cache.LoadFresh(key, &cached); ok {
+			c.logger.Info("protocol_tvl_fresh_cache_used",
+				"slug", slug,
+				"path", c.cache.Path(key),
+				"cache_age_seconds", int64(entry.Age.Seconds()),
+			)
+			return &cached, nil
+		}
+	}
+
+	allowed, probe := c.breakers.Allow(EndpointClassProtocolTVL)
+	if !allowed {
+		if recording {
+			return nil, fmt.Errorf("fetch protocol TVL %s: %w: cannot record while the breaker is open", slug, ErrCircuitOpen)
+		}
+		var cached ProtocolTVLResponse
+		entry, cacheErr := c.cache.LoadFallback(key, &cached)
+		if cacheErr != nil {
+			return nil, fmt.Errorf("fetch protocol TVL %s: %w: %v", slug, ErrCircuitOpen, cacheErr)
+		}
+		c.metrics.recordCacheFallback(EndpointClassProtocolTVL)
+		c.logger.Warn("protocol_tvl_breaker_open_cache_used",
+			"slug", slug,
+			"path", c.cache.Path(key),
+			"cache_age_seconds", int64(entry.Age.Seconds()),
+		)
+		return &cached, nil
+	}
+
 	if err := c.waitForProtocolRateLimit(ctx); err != nil {
+		if probe {
+			c.breakers.Record(EndpointClassProtocolTVL, err)
+		}
 		return nil, err
 	}
 
-	url := fmt.Sprintf(c.protocolTVLEndpointTemplate, slug)
-	var response ProtocolTVLResponse
-
-	err := c.doWithRetry(ctx, func(ctx context.Context) error {
+	err := c.doGuarded(ctx, EndpointClassProtocolTVL, probe, func(ctx context.Context) error {
 		return c.doRequest(ctx, url, &response)
 	})
 	if err != nil {
@@ -558,77 +777,139 @@ func (c *Client) FetchProtocolTVL(ctx context.Context, slug string) (*ProtocolTV
 			return nil, nil
 		}
 
+		if recording {
+			return nil, fmt.Errorf("fetch protocol TVL %s: %w", slug, err)
+		}
+
 		// Try cache fallback
-		cached, cacheErr := c.loadProtocolTVLCache(slug)
+		var cached ProtocolTVLResponse
+		entry, cacheErr := c.cache.LoadFallback(key, &cached)
 		if cacheErr == nil {
+			c.metrics.recordCacheFallback(EndpointClassProtocolTVL)
 			c.logger.Warn("protocol_tvl_fallback_cache_used",
 				"slug", slug,
-				"path", protocolTVLCachePath(slug),
+				"path", c.cache.Path(key),
+				"cache_age_seconds", int64(entry.Age.Seconds()),
 				"error", err,
 			)
-			return cached, nil
+			return &cached, nil
+		}
+		if errors.Is(cacheErr, ErrCacheTooStale) {
+			c.logger.Warn("protocol_tvl_fallback_cache_too_stale", "slug", slug, "error", cacheErr)
 		}
 
 		return nil, fmt.Errorf("fetch protocol TVL %s: %w", slug, err)
 	}
 
-	_ = c.saveProtocolTVLCache(slug, &response)
+	_ = c.cache.Store(key, url, &response, cacheMeta{})
 
 	return &response, nil
 }
 
-// FetchAll retrieves oracle and protocol data concurrently using errgroup.
-func (c *Client) FetchAll(ctx context.Context) (*FetchResult, error) {
-	start := time.Now()
-	var (
-		oracleResp       *OracleAPIResponse
-		protocols        []Protocol
-		oracleDuration   time.Duration
-		protocolDuration time.Duration
-	)
+// FetchChains retrieves current TVL for every chain from DefiLlama /v2/chains.
+func (c *Client) FetchChains(ctx context.Context) ([]ChainInfo, error) {
+	var chains []ChainInfo
+	if c.replayDir != "" {
+		if err := c.replayResponse(c.chainsURL, &chains); err != nil {
+			return nil, fmt.Errorf("fetch chains: %w", err)
+		}
+		return chains, nil
+	}
 
-	g, ctx := errgroup.WithContext(ctx)
+	if err := c.fetchCached(ctx, EndpointClassChains, cacheKeyChains, c.chainsURL, &chains); err != nil {
+		return nil, fmt.Errorf("fetch chains: %w", err)
+	}
 
-	g.Go(func() error {
-		fetchStart := time.Now()
-		resp, err := c.FetchOracles(ctx)
-		oracleDuration = time.Since(fetchStart)
-		if err != nil {
-			return err
+	return chains, nil
+}
+
+// FetchHistoricalChainTVL retrieves the daily TVL history of one chain from
+// DefiLlama /v2/historicalChainTvl/{chain}. Requests that reach the network
+// share the per-protocol rate limit since a cycle issues one per active chain;
+// fresh cache hits do not.
+func (c *Client) FetchHistoricalChainTVL(ctx context.Context, chain string) ([]ChainTVLPoint, error) {
+	endpoint := fmt.Sprintf(c.chainTVLEndpointTemplate, url.PathEscape(chain))
+	var points []ChainTVLPoint
+
+	var err error
+	if c.replayDir != "" {
+		err = c.replayResponse(endpoint, &points)
+	} else {
+		err = c.fetchCached(ctx, EndpointClassChainTVL, historicalChainTVLCacheKey(chain), endpoint, &points)
+	}
+	if err != nil {
+		if isNotFoundAPIError(err) {
+			c.logger.Warn("chain_not_found", "chain", chain, "status_code", http.StatusNotFound)
+			return nil, nil
 		}
-		oracleResp = resp
-		return nil
-	})
+		return nil, fmt.Errorf("fetch historical chain TVL %s: %w", chain, err)
+	}
 
-	g.Go(func() error {
-		fetchStart := time.Now()
-		resp, err := c.FetchProtocols(ctx)
-		protocolDuration = time.Since(fetchStart)
-		if err != nil {
-			return err
+	return points, nil
+}
+
+// FetchAll retrieves oracle and protocol data concurrently using errgroup.
+// The result reports which datasets were revalidated with a 304 and which
+// were served from the local cache instead of DefiLlama.
+func (c *Client) FetchAll(ctx context.Context) (*FetchResult, error) {
+	result, err := FetchAll(ctx, c, c, c.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	oracles := c.outcome(cacheKeyOracles)
+	protocols := c.outcome(protocolsCacheKey(c.oracleFilter))
+	result.ProtocolIndex = c.ProtocolIndex()
+	result.SchemaDrift = c.SchemaDrift()
+	result.OraclesUnchanged = oracles.notModified
+	result.ProtocolsUnchanged = protocols.notModified
+	result.FetchReport = c.FetchReport()
+	for _, o := range []fetchOutcome{oracles, protocols} {
+		if o.fromCache {
+			result.CacheEntries = append(result.CacheEntries, o.entry)
 		}
-		protocols = resp
+	}
+
+	return result, nil
+}
+
+// FetchReport summarises every request this client has made so far by
+// endpoint class: counts, errors, retries, cache fallbacks, latency
+// percentiles and average phase timings. It returns nil before any request.
+func (c *Client) FetchReport() *FetchReport {
+	return c.metrics.report()
+}
+
+// SchemaDrift compares every payload received from DefiLlama so far with the
+// schema manifest. It returns nil when drift checking is disabled.
+func (c *Client) SchemaDrift() *SchemaDriftReport {
+	if c.schema == nil {
 		return nil
-	})
+	}
+	return c.schema.Report()
+}
 
-	if err := g.Wait(); err != nil {
-		total := time.Since(start)
-		c.logger.Error("parallel fetch failed",
-			"error", err,
-			"total_duration_ms", total.Milliseconds(),
-		)
-		return nil, err
+// schemaObserver returns the drift hook for target's dataset, or nil when
+// drift checking is disabled or target is not a checked payload. Only bodies
+// received over the network are observed; cached copies are re-encoded and
+// would not reflect the upstream shape.
+func (c *Client) schemaObserver(target any) func([]byte) {
+	if c.schema == nil {
+		return nil
+	}
+	dataset := schemaDataset(target)
+	if dataset == "" {
+		return nil
 	}
+	return func(raw []byte) { c.schema.observe(dataset, raw) }
+}
 
-	total := time.Since(start)
-	c.logger.Info("parallel fetch completed",
-		"oracle_duration_ms", oracleDuration.Milliseconds(),
-		"protocol_duration_ms", protocolDuration.Milliseconds(),
-		"total_duration_ms", total.Milliseconds(),
-	)
+// conditionalValidators returns the stored validators for key, or none while
+// recording so cassettes always capture full response bodies.
+func (c *Client) conditionalValidators(key, url string) cacheMeta {
+	if c.recordDir != "" {
+		return cacheMeta{}
+	}
 
-	return &FetchResult{
-		OracleResponse: oracleResp,
-		Protocols:      protocols,
-	}, nil
+	return c.cache.Validators(key, url)
 }
@@ -371,6 +371,7 @@ func TestFetchAll_LogsDistinctRequests(t *testing.T) {
 		Timeout:      time.Second,
 		OraclesURL:   server.URL + "/oracles",
 		ProtocolsURL: server.URL + "/lite/protocols2?b=2",
+		Cache:        config.CacheConfig{Directory: t.TempDir()},
 	}
 	client := NewClient(cfg, logger)
 
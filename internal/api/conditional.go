@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+)
+
+// hasValidators reports whether meta can make a conditional request for url.
+func (m cacheMeta) hasValidators(url string) bool {
+	return m.URL == url && (m.ETag != "" || m.LastModified != "")
+}
+
+// apply sets If-None-Match / If-Modified-Since on req.
+func (m cacheMeta) apply(req *http.Request) {
+	if m.ETag != "" {
+		req.Header.Set("If-None-Match", m.ETag)
+	}
+	if m.LastModified != "" {
+		req.Header.Set("If-Modified-Since", m.LastModified)
+	}
+}
+
+// metaFromResponse captures the validators of a successful response.
+func metaFromResponse(url string, resp *http.Response) cacheMeta {
+	return cacheMeta{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+func TestFetchAll_ConditionalGetReusesCacheOn304(t *testing.T) {
+	prevInterval := oraclesMinInterval
+	oraclesMinInterval = 0
+	t.Cleanup(func() { oraclesMinInterval = prevInterval })
+	cacheDir := t.TempDir()
+
+	oracleFixture := loadFixture(t, "oracle_response.json")
+	protocolFixture := loadFixture(t, "protocol_response.json")
+
+	var oracleFull, oracleNotModified, protocolNotModified int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oracles", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"oracles-v1"` {
+			atomic.AddInt32(&oracleNotModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&oracleFull, 1)
+		w.Header().Set("ETag", `"oracles-v1"`)
+		_, _ = w.Write(oracleFixture)
+	})
+	mux.HandleFunc("/lite/protocols2", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") == "Mon, 01 Dec 2025 00:00:00 GMT" {
+			atomic.AddInt32(&protocolNotModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", "Mon, 01 Dec 2025 00:00:00 GMT")
+		_, _ = w.Write(protocolFixture)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := NewClient(&config.APIConfig{
+		OraclesURL:   server.URL + "/oracles",
+		ProtocolsURL: server.URL + "/lite/protocols2",
+		Timeout:      2 * time.Second,
+		Cache:        config.CacheConfig{Directory: cacheDir},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	first, err := client.FetchAll(context.Background())
+	if err != nil {
+		t.Fatalf("first FetchAll error: %v", err)
+	}
+	if first.OraclesUnchanged || first.ProtocolsUnchanged {
+		t.Fatalf("first fetch must not report unchanged upstream")
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "oracles.meta.json")); err != nil {
+		t.Fatalf("expected oracle validators next to cache: %v", err)
+	}
+
+	second, err := client.FetchAll(context.Background())
+	if err != nil {
+		t.Fatalf("second FetchAll error: %v", err)
+	}
+	if !second.OraclesUnchanged || !second.ProtocolsUnchanged {
+		t.Fatalf("expected unchanged upstream, got oracles=%v protocols=%v", second.OraclesUnchanged, second.ProtocolsUnchanged)
+	}
+	if atomic.LoadInt32(&oracleFull) != 1 || atomic.LoadInt32(&oracleNotModified) != 1 || atomic.LoadInt32(&protocolNotModified) != 1 {
+		t.Fatalf("unexpected request counts full=%d oracle304=%d protocol304=%d", oracleFull, oracleNotModified, protocolNotModified)
+	}
+	if len(second.Protocols) != len(first.Protocols) {
+		t.Fatalf("expected cached protocols (%d), got %d", len(first.Protocols), len(second.Protocols))
+	}
+	if len(second.OracleResponse.OraclesTVS) != len(first.OracleResponse.OraclesTVS) {
+		t.Fatalf("expected cached oracle response to match first fetch")
+	}
+}
+
+func TestFetchOracles_NotModifiedWithoutCacheRefetches(t *testing.T) {
+	prevInterval := oraclesMinInterval
+	oraclesMinInterval = 0
+	t.Cleanup(func() { oraclesMinInterval = prevInterval })
+	cacheDir := t.TempDir()
+
+	oracleFixture := loadFixture(t, "oracle_response.json")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(oracleFixture)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&config.APIConfig{OraclesURL: server.URL, Timeout: 2 * time.Second, Cache: config.CacheConfig{Directory: cacheDir}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := client.FetchOracles(context.Background()); err != nil {
+		t.Fatalf("first fetch error: %v", err)
+	}
+
+	// Removing the body invalidates the stored validators.
+	if err := os.Remove(filepath.Join(cacheDir, "oracles.json")); err != nil {
+		t.Fatalf("remove cache: %v", err)
+	}
+
+	resp, err := client.FetchOracles(context.Background())
+	if err != nil || resp == nil {
+		t.Fatalf("expected full refetch, got %v, %v", resp, err)
+	}
+	if client.outcome(cacheKeyOracles).notModified {
+		t.Fatalf("expected refetch not to report unchanged upstream")
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+)
+
+// DirectorySource serves DefiLlama datasets from a local folder laid out like a
+// --record cassette: oracles.json, lite/protocols2.json, protocol/{slug}.json,
+// v2/chains.json and v2/historicalChainTvl/{chain}.json.
+// It never touches the network, which makes it suitable for mirrored datasets.
+type DirectorySource struct {
+	dir          string
+	logger       *slog.Logger
+	oracleFilter []string
+	index        *ProtocolIndex
+}
+
+// NewDirectorySource constructs a DirectorySource rooted at dir. Nil logger falls back to slog.Default().
+func NewDirectorySource(dir string, logger *slog.Logger) *DirectorySource {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &DirectorySource{dir: dir, logger: logger}
+}
+
+// FetchOracles decodes oracles.json from the directory.
+func (s *DirectorySource) FetchOracles(ctx context.Context) (*OracleAPIResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var response OracleAPIResponse
+	path, err := readCassette(s.dir, OraclesEndpoint, &response)
+	if err != nil {
+		return nil, fmt.Errorf("fetch oracles: %w", err)
+	}
+
+	s.logger.Debug("directory_source_read", "dataset", "oracles", "path", path)
+	return &response, nil
+}
+
+// SetOracleFilter restricts FetchProtocols to protocols using one of names.
+func (s *DirectorySource) SetOracleFilter(names ...string) {
+	s.oracleFilter = names
+}
+
+// FetchProtocols decodes lite/protocols2.json from the directory, accepting
+// both the bare array and the envelope shape and applying the oracle filter.
+func (s *DirectorySource) FetchProtocols(ctx context.Context) ([]Protocol, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	stream := newProtocolStream(s.oracleFilter)
+	path, err := readCassette(s.dir, ProtocolsEndpoint, stream)
+	if err != nil {
+		return nil, fmt.Errorf("fetch protocols: %w", err)
+	}
+	s.index = NewProtocolIndex(stream.Index)
+
+	s.logger.Debug("directory_source_read", "dataset", "protocols", "path", path, "count", len(stream.Protocols), "indexed", s.index.Len())
+	return stream.Protocols, nil
+}
+
+// FetchProtocolTVL decodes protocol/{slug}.json from the directory. Missing
+// files yield (nil, nil), mirroring a 404 from the live endpoint.
+func (s *DirectorySource) FetchProtocolTVL(ctx context.Context, slug string) (*ProtocolTVLResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var response ProtocolTVLResponse
+	path, err := readCassette(s.dir, fmt.Sprintf(ProtocolTVLEndpointTemplate, slug), &response)
+	if err != nil {
+		if isNotFoundAPIError(err) {
+			s.logger.Warn("protocol_not_found", "slug", slug, "path", path)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fetch protocol TVL %s: %w", slug, err)
+	}
+
+	return &response, nil
+}
+
+// FetchChains decodes v2/chains.json from the directory.
+func (s *DirectorySource) FetchChains(ctx context.Context) ([]ChainInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var chains []ChainInfo
+	path, err := readCassette(s.dir, ChainsEndpoint, &chains)
+	if err != nil {
+		return nil, fmt.Errorf("fetch chains: %w", err)
+	}
+
+	s.logger.Debug("directory_source_read", "dataset", "chains", "path", path, "count", len(chains))
+	return chains, nil
+}
+
+// FetchHistoricalChainTVL decodes v2/historicalChainTvl/{chain}.json from the
+// directory. Missing files yield (nil, nil).
+func (s *DirectorySource) FetchHistoricalChainTVL(ctx context.Context, chain string) ([]ChainTVLPoint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var points []ChainTVLPoint
+	path, err := readCassette(s.dir, fmt.Sprintf(HistoricalChainTVLEndpointTemplate, url.PathEscape(chain)), &points)
+	if err != nil {
+		if isNotFoundAPIError(err) {
+			s.logger.Warn("chain_not_found", "chain", chain, "path", path)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fetch historical chain TVL %s: %w", chain, err)
+	}
+
+	return points, nil
+}
+
+// FetchAll reads oracle and protocol datasets concurrently.
+func (s *DirectorySource) FetchAll(ctx context.Context) (*FetchResult, error) {
+	result, err := FetchAll(ctx, s, s, s.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	result.ProtocolIndex = s.index
+	return result, nil
+}
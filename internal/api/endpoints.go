@@ -2,7 +2,9 @@ package api
 
 // DefiLlama API endpoints.
 const (
-	OraclesEndpoint             = "https://api.llama.fi/oracles"
-	ProtocolsEndpoint           = "https://api.llama.fi/lite/protocols2?b=2"
-	ProtocolTVLEndpointTemplate = "https://api.llama.fi/protocol/%s"
+	OraclesEndpoint                    = "https://api.llama.fi/oracles"
+	ProtocolsEndpoint                  = "https://api.llama.fi/lite/protocols2?b=2"
+	ProtocolTVLEndpointTemplate        = "https://api.llama.fi/protocol/%s"
+	ChainsEndpoint                     = "https://api.llama.fi/v2/chains"
+	HistoricalChainTVLEndpointTemplate = "https://api.llama.fi/v2/historicalChainTvl/%s"
 )
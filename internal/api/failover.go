@@ -0,0 +1,278 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+// APIKeyPlaceholder is replaced by the Pro API key in api.hosts base URLs.
+const APIKeyPlaceholder = "{api_key}"
+
+// DefaultAPIKeyEnv names the environment variable read for the Pro API key
+// when api.pro.api_key_env is unset.
+const DefaultAPIKeyEnv = "DEFILLAMA_API_KEY"
+
+// redactedKey stands in for the Pro API key wherever it could reach a log.
+const redactedKey = "REDACTED"
+
+const endpointClassContextKey contextKey = "api_endpoint_class"
+
+// withEndpointClass tags ctx with the endpoint class a request belongs to so
+// the failover transport can pick that class's host list.
+func withEndpointClass(ctx context.Context, class string) context.Context {
+	return context.WithValue(ctx, endpointClassContextKey, class)
+}
+
+func endpointClassFromContext(ctx context.Context) string {
+	class, _ := ctx.Value(endpointClassContextKey).(string)
+	return class
+}
+
+// LoadAPIKey returns the Pro API key from cfg.APIKeyFile, or from the
+// environment variable cfg.APIKeyEnv (DEFILLAMA_API_KEY when empty). An empty
+// key without error means none is configured.
+func LoadAPIKey(cfg config.ProAPIConfig) (string, error) {
+	if path := strings.TrimSpace(cfg.APIKeyFile); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read api key file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	env := strings.TrimSpace(cfg.APIKeyEnv)
+	if env == "" {
+		env = DefaultAPIKeyEnv
+	}
+	return strings.TrimSpace(os.Getenv(env)), nil
+}
+
+// upstreamHost is one base URL requests can be sent to, with its own rate
+// limit and throttling deadline.
+type upstreamHost struct {
+	name    string
+	baseURL *url.URL
+	limiter *RateLimiter
+
+	mu           sync.Mutex
+	blockedUntil time.Time
+}
+
+// resolve maps a canonical request URL onto this host by appending its path
+// and query to the host's base URL.
+func (h *upstreamHost) resolve(canonical *url.URL) *url.URL {
+	u := *h.baseURL
+	u.Path = strings.TrimSuffix(h.baseURL.Path, "/") + canonical.Path
+	if canonical.RawPath != "" {
+		u.RawPath = strings.TrimSuffix(h.baseURL.EscapedPath(), "/") + canonical.RawPath
+	} else {
+		u.RawPath = ""
+	}
+	u.RawQuery = canonical.RawQuery
+	return &u
+}
+
+func (h *upstreamHost) blocked(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return now.Before(h.blockedUntil)
+}
+
+func (h *upstreamHost) block(until time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if until.After(h.blockedUntil) {
+		h.blockedUntil = until
+	}
+}
+
+// failoverTransport sends each request to the hosts configured for its
+// endpoint class in order, moving to the next host when one answers 5xx or
+// 521, fails at the network level, or is throttling. The client only ever
+// sees canonical URLs, so cache metadata, cassettes and request logs never
+// contain the Pro API key.
+type failoverTransport struct {
+	next     http.RoundTripper
+	hosts    []*upstreamHost
+	routes   map[string][]*upstreamHost
+	apiKey   string
+	logger   *slog.Logger
+	now      func() time.Time
+	maxBlock time.Duration
+}
+
+// newFailoverTransport wraps next with host failover when cfg.Hosts is set and
+// returns next unchanged otherwise. Hosts whose base URL needs the API key are
+// dropped, with a warning, when no key is available.
+func newFailoverTransport(next http.RoundTripper, cfg *config.APIConfig, logger *slog.Logger) http.RoundTripper {
+	if len(cfg.Hosts) == 0 {
+		return next
+	}
+
+	key, err := LoadAPIKey(cfg.Pro)
+	if err != nil {
+		logger.Error("api_key_unavailable", "error", err)
+	}
+
+	t := &failoverTransport{
+		next:     next,
+		routes:   make(map[string][]*upstreamHost),
+		apiKey:   key,
+		logger:   logger,
+		now:      time.Now,
+		maxBlock: cfg.MaxRetryAfter,
+	}
+	if t.maxBlock <= 0 {
+		t.maxBlock = DefaultMaxRetryAfter
+	}
+
+	byName := make(map[string]*upstreamHost, len(cfg.Hosts))
+	for _, hc := range cfg.Hosts {
+		raw := hc.BaseURL
+		if strings.Contains(raw, APIKeyPlaceholder) {
+			if key == "" {
+				logger.Warn("upstream_host_skipped", "host", hc.Name, "reason", "no api key configured")
+				continue
+			}
+			raw = strings.ReplaceAll(raw, APIKeyPlaceholder, url.PathEscape(key))
+		}
+		base, err := url.Parse(raw)
+		if err != nil {
+			logger.Warn("upstream_host_skipped", "host", hc.Name, "reason", "invalid base url")
+			continue
+		}
+
+		h := &upstreamHost{
+			name:    hc.Name,
+			baseURL: base,
+			limiter: NewRateLimiter(hc.RPS, hc.Burst),
+		}
+		t.hosts = append(t.hosts, h)
+		byName[hc.Name] = h
+	}
+
+	for class, names := range cfg.Failover {
+		for _, name := range names {
+			if h, ok := byName[name]; ok {
+				t.routes[class] = append(t.routes[class], h)
+			}
+		}
+	}
+
+	return t
+}
+
+// candidates returns the ordered hosts for class, defaulting to every host.
+func (t *failoverTransport) candidates(class string) []*upstreamHost {
+	if hosts, ok := t.routes[class]; ok {
+		return hosts
+	}
+	return t.hosts
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	class := endpointClassFromContext(ctx)
+	hosts := t.candidates(class)
+	if len(hosts) == 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	for i, h := range hosts {
+		last := i == len(hosts)-1
+		if !last && h.blocked(t.now()) {
+			t.logger.Info("upstream_host_skipped", "endpoint_class", class, "host", h.name, "reason", "rate_limited")
+			continue
+		}
+		if err := h.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		out := req.Clone(ctx)
+		out.URL = h.resolve(req.URL)
+		out.Host = out.URL.Host
+
+		start := t.now()
+		resp, err := t.next.RoundTrip(out)
+		if err != nil {
+			err = t.redact(err)
+		}
+
+		reason := ""
+		switch {
+		case err != nil:
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil, err
+			}
+			reason = err.Error()
+		case resp.StatusCode == http.StatusTooManyRequests:
+			if delay := parseRetryAfter(resp.StatusCode, resp.Header, t.now()); delay > 0 {
+				h.block(t.now().Add(min(delay, t.maxBlock)))
+			}
+			reason = resp.Status
+		case resp.StatusCode >= http.StatusInternalServerError:
+			reason = resp.Status
+		}
+
+		if reason == "" || last {
+			if err == nil {
+				t.logger.Info("upstream_host_served",
+					"endpoint_class", class,
+					"host", h.name,
+					"status", resp.StatusCode,
+					"duration_ms", t.now().Sub(start).Milliseconds(),
+				)
+			}
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		t.logger.Warn("upstream_host_failover",
+			"endpoint_class", class,
+			"host", h.name,
+			"next_host", hosts[i+1].name,
+			"reason", reason,
+		)
+	}
+
+	// Unreachable: the last host always returns above.
+	return nil, errors.New("no upstream host available")
+}
+
+// redact hides the API key in err's message while keeping it unwrappable.
+func (t *failoverTransport) redact(err error) error {
+	if t.apiKey == "" || !strings.Contains(err.Error(), t.apiKey) {
+		return err
+	}
+	return &redactedError{err: err, key: t.apiKey}
+}
+
+// redactedError masks the API key in a wrapped error's message.
+type redactedError struct {
+	err error
+	key string
+}
+
+func (e *redactedError) Error() string {
+	msg := strings.ReplaceAll(e.err.Error(), e.key, redactedKey)
+	return strings.ReplaceAll(msg, url.PathEscape(e.key), redactedKey)
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
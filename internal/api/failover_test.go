@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+const testProtocolsBody = `[{"id":"1","name":"A","slug":"a","category":"Dexs","tvl":1,"chains":["Solana"],"oracles":["Switchboard"]}]`
+
+func TestFailover_MovesToNextHostOn5xxAndRedactsKey(t *testing.T) {
+	const key = "sekret-key"
+	t.Setenv("TEST_LLAMA_KEY", key)
+
+	var proPaths []string
+	pro := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proPaths = append(proPaths, r.URL.Path)
+		w.WriteHeader(StatusWebServerDown)
+	}))
+	t.Cleanup(pro.Close)
+
+	var publicHits atomic.Int32
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		publicHits.Add(1)
+		if r.URL.Path != "/lite/protocols2" || r.URL.Query().Get("b") != "2" {
+			t.Errorf("unexpected public request %s", r.URL.String())
+		}
+		_, _ = w.Write([]byte(testProtocolsBody))
+	}))
+	t.Cleanup(public.Close)
+
+	var logs bytes.Buffer
+	client := NewClient(&config.APIConfig{
+		Timeout: time.Second,
+		Cache:   config.CacheConfig{Directory: t.TempDir()},
+		Pro:     config.ProAPIConfig{APIKeyEnv: "TEST_LLAMA_KEY"},
+		Hosts: []config.HostConfig{
+			{Name: "pro", BaseURL: pro.URL + "/{api_key}/api"},
+			{Name: "public", BaseURL: public.URL},
+		},
+	}, slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	protocols, err := client.FetchProtocols(context.Background())
+	if err != nil {
+		t.Fatalf("FetchProtocols error: %v", err)
+	}
+	if len(protocols) != 1 {
+		t.Fatalf("expected protocol from public host, got %+v", protocols)
+	}
+
+	if len(proPaths) != 1 || proPaths[0] != "/"+key+"/api/lite/protocols2" {
+		t.Fatalf("expected key in pro request path, got %v", proPaths)
+	}
+	if publicHits.Load() != 1 {
+		t.Fatalf("expected one public request, got %d", publicHits.Load())
+	}
+
+	out := logs.String()
+	if !strings.Contains(out, "upstream_host_failover") || !strings.Contains(out, "upstream_host_served") || !strings.Contains(out, "host=public") {
+		t.Fatalf("expected failover and serving host in logs, got: %s", out)
+	}
+	if strings.Contains(out, key) {
+		t.Fatalf("API key leaked into logs: %s", out)
+	}
+}
+
+func TestFailover_HonorsPerHostThrottlingAndRoutes(t *testing.T) {
+	var primaryHits, secondaryHits atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryHits.Add(1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(primary.Close)
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secondaryHits.Add(1)
+		_, _ = w.Write([]byte(`[{"date":1700000000,"tvl":1}]`))
+	}))
+	t.Cleanup(secondary.Close)
+
+	client := NewClient(&config.APIConfig{
+		Timeout:     time.Second,
+		ChainTVLURL: "https://api.llama.fi/v2/historicalChainTvl/%s",
+		Cache:       config.CacheConfig{Directory: t.TempDir()},
+		Hosts: []config.HostConfig{
+			{Name: "primary", BaseURL: primary.URL},
+			{Name: "secondary", BaseURL: secondary.URL},
+		},
+		Failover: map[string][]string{EndpointClassChains: {"secondary"}},
+	}, nil)
+
+	for _, chain := range []string{"Sui", "Aptos"} {
+		if _, err := client.FetchHistoricalChainTVL(context.Background(), chain); err != nil {
+			t.Fatalf("FetchHistoricalChainTVL %s error: %v", chain, err)
+		}
+	}
+	if primaryHits.Load() != 1 || secondaryHits.Load() != 2 {
+		t.Fatalf("expected throttled primary to be skipped after one 429, got primary=%d secondary=%d", primaryHits.Load(), secondaryHits.Load())
+	}
+
+	// /v2/chains is routed to the secondary host only.
+	if _, err := client.FetchChains(context.Background()); err != nil {
+		t.Fatalf("FetchChains error: %v", err)
+	}
+	if primaryHits.Load() != 1 || secondaryHits.Load() != 3 {
+		t.Fatalf("expected chains request on secondary only, got primary=%d secondary=%d", primaryHits.Load(), secondaryHits.Load())
+	}
+}
+
+func TestLoadAPIKey_FileWinsOverEnv(t *testing.T) {
+	t.Setenv(DefaultAPIKeyEnv, "from-env")
+
+	key, err := LoadAPIKey(config.ProAPIConfig{})
+	if err != nil || key != "from-env" {
+		t.Fatalf("expected key from default env var, got %q, %v", key, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	key, err = LoadAPIKey(config.ProAPIConfig{APIKeyFile: path})
+	if err != nil || key != "from-file" {
+		t.Fatalf("expected trimmed key from file, got %q, %v", key, err)
+	}
+
+	if _, err := LoadAPIKey(config.ProAPIConfig{APIKeyFile: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Fatalf("expected error for missing key file")
+	}
+}
@@ -19,15 +19,9 @@ import (
 
 func newFetchAllClient(t *testing.T, oracleHandler, protocolHandler http.HandlerFunc, timeout time.Duration) (*Client, func()) {
 	t.Helper()
-	oldOraclePath := oraclesCachePath
-	oldProtocolPath := protocolsCachePath
-	oraclesCachePath = filepath.Join(t.TempDir(), "oracles.json")
-	protocolsCachePath = filepath.Join(t.TempDir(), "protocols.json")
 	prevInterval := oraclesMinInterval
 	oraclesMinInterval = 0
 	t.Cleanup(func() {
-		oraclesCachePath = oldOraclePath
-		protocolsCachePath = oldProtocolPath
 		oraclesMinInterval = prevInterval
 	})
 
@@ -40,6 +34,7 @@ func newFetchAllClient(t *testing.T, oracleHandler, protocolHandler http.Handler
 		Timeout:      timeout,
 		MaxRetries:   0,
 		RetryDelay:   10 * time.Millisecond,
+		Cache:        config.CacheConfig{Directory: t.TempDir()},
 	}
 
 	client := NewClient(cfg, slog.Default())
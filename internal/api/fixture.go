@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+// FixtureSource serves a cassette-layout directory over a local HTTP server and
+// fetches from it with the regular Client, so the full HTTP path (retries,
+// decoding, recording) is exercised without reaching DefiLlama.
+type FixtureSource struct {
+	*Client
+	server   *http.Server
+	listener net.Listener
+	cacheDir string
+}
+
+// NewFixtureSource starts a fixture server for dir on addr (127.0.0.1:0 when
+// empty) and returns a source backed by a Client pointed at it.
+func NewFixtureSource(dir, addr string, apiCfg *config.APIConfig, logger *slog.Logger) (*FixtureSource, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if strings.TrimSpace(addr) == "" {
+		addr = "127.0.0.1:0"
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("start fixture server: %w", err)
+	}
+
+	server := &http.Server{
+		Handler:           fixtureHandler(dir),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("fixture_server_failed", "error", err)
+		}
+	}()
+
+	baseURL := "http://" + listener.Addr().String()
+	clientCfg := config.APIConfig{Timeout: 30 * time.Second}
+	if apiCfg != nil {
+		clientCfg = *apiCfg
+	}
+	clientCfg.OraclesURL = baseURL + "/oracles"
+	clientCfg.ProtocolsURL = baseURL + "/lite/protocols2"
+	clientCfg.ProtocolTVLURL = baseURL + "/protocol/%s"
+	clientCfg.ChainsURL = baseURL + "/v2/chains"
+	clientCfg.ChainTVLURL = baseURL + "/v2/historicalChainTvl/%s"
+	clientCfg.ReplayDir = ""
+	// The fixture server is local; never route it through an egress proxy
+	// or fail over to upstream hosts.
+	clientCfg.ProxyURL = ""
+	clientCfg.Hosts = nil
+	clientCfg.Failover = nil
+	// Fixture responses must not mix with the live cache or breaker state:
+	// cache into a private directory removed on Close, without a fresh
+	// window, and run without breakers.
+	cacheDir, err := os.MkdirTemp("", "fixture-cache-")
+	if err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("create fixture cache directory: %w", err)
+	}
+	clientCfg.Cache = config.CacheConfig{Directory: cacheDir}
+	clientCfg.Breaker = config.BreakerConfig{}
+
+	logger.Info("fixture_server_started", "address", listener.Addr().String(), "directory", dir)
+
+	return &FixtureSource{
+		Client:   NewClient(&clientCfg, logger),
+		server:   server,
+		listener: listener,
+		cacheDir: cacheDir,
+	}, nil
+}
+
+// URL returns the base URL of the fixture server.
+func (s *FixtureSource) URL() string {
+	return "http://" + s.listener.Addr().String()
+}
+
+// Close shuts down the fixture server and removes its cache directory.
+func (s *FixtureSource) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.server.Shutdown(ctx)
+	if rmErr := os.RemoveAll(s.cacheDir); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// fixtureHandler serves the file cassettePath maps each request onto, or 404.
+func fixtureHandler(dir string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, err := cassettePath(dir, r.URL.String())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	})
+}
@@ -3,9 +3,11 @@ package api
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"math/rand"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -60,10 +62,17 @@ type utlsRoundTripper struct {
 	h2Transport   *http2.Transport
 	h1Transport   *http.Transport
 	tlsConfigSpec utls.ClientHelloID
+	proxy         *url.URL
+	rootCAs       *x509.CertPool
+	certificates  []utls.Certificate
 }
 
 // NewUtlsRoundTripper creates a new round tripper with Chrome TLS fingerprint.
 func NewUtlsRoundTripper() *utlsRoundTripper {
+	return newUtlsRoundTripper(transportOptions{})
+}
+
+func newUtlsRoundTripper(opts transportOptions) *utlsRoundTripper {
 	dialer := &net.Dialer{
 		Timeout:   30 * time.Second,
 		KeepAlive: 30 * time.Second,
@@ -72,6 +81,15 @@ func NewUtlsRoundTripper() *utlsRoundTripper {
 	rt := &utlsRoundTripper{
 		dialer:        dialer,
 		tlsConfigSpec: utls.HelloChrome_131,
+		proxy:         opts.proxy,
+		rootCAs:       opts.rootCAs,
+	}
+	if opts.clientCert != nil {
+		rt.certificates = []utls.Certificate{{
+			Certificate: opts.clientCert.Certificate,
+			PrivateKey:  opts.clientCert.PrivateKey,
+			Leaf:        opts.clientCert.Leaf,
+		}}
 	}
 
 	// HTTP/2 transport for HTTPS
@@ -88,14 +106,19 @@ func NewUtlsRoundTripper() *utlsRoundTripper {
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
 		DisableCompression:    false,
+		TLSClientConfig:       opts.tlsConfig(),
+	}
+	if opts.proxy != nil {
+		rt.h1Transport.Proxy = http.ProxyURL(opts.proxy)
 	}
 
 	return rt
 }
 
-// dialTLSContext creates a TLS connection with Chrome's fingerprint for HTTP/2.
+// dialTLSContext creates a TLS connection with Chrome's fingerprint for HTTP/2,
+// tunnelling through the configured proxy when one is set.
 func (rt *utlsRoundTripper) dialTLSContext(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
-	conn, err := rt.dialer.DialContext(ctx, network, addr)
+	conn, err := dialThroughProxy(ctx, rt.dialer, rt.proxy, rt.rootCAs, network, addr)
 	if err != nil {
 		return nil, err
 	}
@@ -109,9 +132,11 @@ func (rt *utlsRoundTripper) dialTLSContext(ctx context.Context, network, addr st
 		ServerName:         host,
 		InsecureSkipVerify: false,
 		NextProtos:         []string{"h2", "http/1.1"},
+		RootCAs:            rt.rootCAs,
+		Certificates:       rt.certificates,
 	}, rt.tlsConfigSpec)
 
-	if err := tlsConn.HandshakeContext(ctx); err != nil {
+	if err := traceTLSHandshake(ctx, func() error { return tlsConn.HandshakeContext(ctx) }); err != nil {
 		conn.Close()
 		return nil, err
 	}
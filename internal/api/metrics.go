@@ -0,0 +1,306 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RequestTiming breaks one request's latency into phases. Phases that did not
+// happen (DNS, connect and TLS on a reused connection) stay zero.
+type RequestTiming struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// TTFB runs from sending the request to the first response byte.
+	TTFB time.Duration
+	// BodyRead is the time spent waiting on the response body; Decode is the
+	// rest of the body phase, spent in JSON decoding and schema checks.
+	BodyRead time.Duration
+	Decode   time.Duration
+	Total    time.Duration
+	// BytesReceived counts response body bytes as read by the decoder.
+	BytesReceived int64
+}
+
+// requestTrace collects httptrace callbacks for one request. Callbacks may
+// run on transport goroutines, so fields are guarded by mu.
+type requestTrace struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timing       RequestTiming
+}
+
+// newRequestTrace returns ctx carrying an httptrace.ClientTrace that records
+// into the returned requestTrace, with the clock started now.
+func newRequestTrace(ctx context.Context) (context.Context, *requestTrace) {
+	t := &requestTrace{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.elapsed(t.dnsStart, &t.timing.DNS) },
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// Dual-stack dialing may race several connects; time the first.
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				t.elapsed(t.connectStart, &t.timing.Connect)
+			}
+		},
+		TLSHandshakeStart:    func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.elapsed(t.tlsStart, &t.timing.TLSHandshake) },
+		GotFirstResponseByte: func() { t.elapsed(t.start, &t.timing.TTFB) },
+	}
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+func (t *requestTrace) mark(at *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*at = time.Now()
+}
+
+func (t *requestTrace) elapsed(from time.Time, into *time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !from.IsZero() {
+		*into = time.Since(from)
+	}
+}
+
+// bodyRead records the response body phase: body.elapsed spent in Read out of
+// phase in total.
+func (t *requestTrace) bodyRead(body *meteredReader, phase time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timing.BodyRead = body.elapsed
+	t.timing.Decode = max(phase-body.elapsed, 0)
+	t.timing.BytesReceived = body.n
+}
+
+// finish stops the clock and returns the recorded timing.
+func (t *requestTrace) finish() RequestTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timing.Total = time.Since(t.start)
+	return t.timing
+}
+
+// connectionAttrs returns the connection phases as log attributes.
+func (t *requestTrace) connectionAttrs() []any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return []any{
+		"dns_ms", t.timing.DNS.Milliseconds(),
+		"connect_ms", t.timing.Connect.Milliseconds(),
+		"tls_ms", t.timing.TLSHandshake.Milliseconds(),
+		"ttfb_ms", t.timing.TTFB.Milliseconds(),
+	}
+}
+
+// traceTLSHandshake reports a handshake performed outside net/http (the
+// browser transport's uTLS dial) to the request's httptrace hooks.
+func traceTLSHandshake(ctx context.Context, handshake func() error) error {
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.TLSHandshakeStart != nil {
+		trace.TLSHandshakeStart()
+	}
+	err := handshake()
+	if trace != nil && trace.TLSHandshakeDone != nil {
+		trace.TLSHandshakeDone(tls.ConnectionState{HandshakeComplete: err == nil}, err)
+	}
+	return err
+}
+
+// meteredReader counts bytes read from r and the time spent waiting on it.
+type meteredReader struct {
+	r       io.Reader
+	n       int64
+	elapsed time.Duration
+}
+
+func (m *meteredReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := m.r.Read(p)
+	m.elapsed += time.Since(start)
+	m.n += int64(n)
+	return n, err
+}
+
+// FetchReport summarises the requests a client made, per endpoint class.
+type FetchReport struct {
+	Endpoints []EndpointReport `json:"endpoints"`
+}
+
+// EndpointReport aggregates the requests made to one endpoint class. Latency
+// percentiles cover every HTTP attempt, failed ones included; phase averages
+// cover attempts that received a response.
+type EndpointReport struct {
+	Endpoint       string `json:"endpoint"`
+	Requests       int    `json:"requests"`
+	Errors         int    `json:"errors"`
+	Retries        int    `json:"retries"`
+	CacheFallbacks int    `json:"cache_fallbacks"`
+	BytesReceived  int64  `json:"bytes_received"`
+
+	P50Ms int64 `json:"p50_ms"`
+	P95Ms int64 `json:"p95_ms"`
+
+	AvgDNSMs      float64 `json:"avg_dns_ms"`
+	AvgConnectMs  float64 `json:"avg_connect_ms"`
+	AvgTLSMs      float64 `json:"avg_tls_ms"`
+	AvgTTFBMs     float64 `json:"avg_ttfb_ms"`
+	AvgBodyReadMs float64 `json:"avg_body_read_ms"`
+	AvgDecodeMs   float64 `json:"avg_decode_ms"`
+}
+
+// Endpoint returns the report for class, or nil when nothing was recorded.
+func (r *FetchReport) Endpoint(class string) *EndpointReport {
+	if r == nil {
+		return nil
+	}
+	for i := range r.Endpoints {
+		if r.Endpoints[i].Endpoint == class {
+			return &r.Endpoints[i]
+		}
+	}
+	return nil
+}
+
+// endpointMetrics accumulates the raw samples behind an EndpointReport.
+type endpointMetrics struct {
+	requests  int
+	errors    int
+	retries   int
+	fallbacks int
+	bytes     int64
+	durations []time.Duration
+	responses int
+	sum       RequestTiming
+}
+
+// fetchMetrics records per-endpoint request metrics for a client. It is safe
+// for concurrent use; the zero value is ready to use.
+type fetchMetrics struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointMetrics
+}
+
+func (m *fetchMetrics) endpoint(class string) *endpointMetrics {
+	if class == "" {
+		class = "other"
+	}
+	if m.endpoints == nil {
+		m.endpoints = make(map[string]*endpointMetrics)
+	}
+	e, ok := m.endpoints[class]
+	if !ok {
+		e = &endpointMetrics{}
+		m.endpoints[class] = e
+	}
+	return e
+}
+
+// recordRequest adds one HTTP attempt. responded is false when no response
+// arrived, so its phases are left out of the averages.
+func (m *fetchMetrics) recordRequest(class string, timing RequestTiming, failed, responded bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.endpoint(class)
+	e.requests++
+	if failed {
+		e.errors++
+	}
+	e.durations = append(e.durations, timing.Total)
+	e.bytes += timing.BytesReceived
+	if responded {
+		e.responses++
+		e.sum.DNS += timing.DNS
+		e.sum.Connect += timing.Connect
+		e.sum.TLSHandshake += timing.TLSHandshake
+		e.sum.TTFB += timing.TTFB
+		e.sum.BodyRead += timing.BodyRead
+		e.sum.Decode += timing.Decode
+	}
+}
+
+func (m *fetchMetrics) recordRetry(class string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.endpoint(class).retries++
+}
+
+func (m *fetchMetrics) recordCacheFallback(class string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.endpoint(class).fallbacks++
+}
+
+// report returns a snapshot sorted by endpoint class, or nil when nothing has
+// been recorded.
+func (m *fetchMetrics) report() *FetchReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.endpoints) == 0 {
+		return nil
+	}
+
+	report := &FetchReport{Endpoints: make([]EndpointReport, 0, len(m.endpoints))}
+	for class, e := range m.endpoints {
+		r := EndpointReport{
+			Endpoint:       class,
+			Requests:       e.requests,
+			Errors:         e.errors,
+			Retries:        e.retries,
+			CacheFallbacks: e.fallbacks,
+			BytesReceived:  e.bytes,
+			P50Ms:          percentile(e.durations, 50).Milliseconds(),
+			P95Ms:          percentile(e.durations, 95).Milliseconds(),
+		}
+		if e.responses > 0 {
+			n := float64(e.responses)
+			r.AvgDNSMs = durationMs(e.sum.DNS) / n
+			r.AvgConnectMs = durationMs(e.sum.Connect) / n
+			r.AvgTLSMs = durationMs(e.sum.TLSHandshake) / n
+			r.AvgTTFBMs = durationMs(e.sum.TTFB) / n
+			r.AvgBodyReadMs = durationMs(e.sum.BodyRead) / n
+			r.AvgDecodeMs = durationMs(e.sum.Decode) / n
+		}
+		report.Endpoints = append(report.Endpoints, r)
+	}
+	sort.Slice(report.Endpoints, func(i, j int) bool {
+		return report.Endpoints[i].Endpoint < report.Endpoints[j].Endpoint
+	})
+	return report
+}
+
+// percentile returns the nearest-rank pth percentile of durations.
+func percentile(durations []time.Duration, p int) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
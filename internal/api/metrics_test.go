@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+func TestFetchReport_CountsRequestsRetriesAndFallbacks(t *testing.T) {
+	const body = `[{"name":"Solana","tvl":100}]`
+
+	var calls atomic.Int32
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 || down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&config.APIConfig{
+		ChainsURL:  server.URL,
+		Timeout:    time.Second,
+		MaxRetries: 1,
+		RetryDelay: time.Millisecond,
+		Cache:      config.CacheConfig{Directory: t.TempDir()},
+	}, nil)
+
+	if client.FetchReport() != nil {
+		t.Fatalf("expected nil report before any request")
+	}
+	if _, err := client.FetchChains(context.Background()); err != nil {
+		t.Fatalf("FetchChains error: %v", err)
+	}
+
+	got := client.FetchReport().Endpoint(EndpointClassChains)
+	if got == nil {
+		t.Fatalf("expected chains endpoint in report")
+	}
+	if got.Requests != 2 || got.Errors != 1 || got.Retries != 1 || got.CacheFallbacks != 0 {
+		t.Fatalf("unexpected counts after retry: %+v", got)
+	}
+	if got.BytesReceived != int64(len(body)) {
+		t.Fatalf("expected %d bytes received, got %d", len(body), got.BytesReceived)
+	}
+
+	down.Store(true)
+	if _, err := client.FetchChains(context.Background()); err != nil {
+		t.Fatalf("FetchChains with cache fallback error: %v", err)
+	}
+	got = client.FetchReport().Endpoint(EndpointClassChains)
+	if got.Requests != 4 || got.Errors != 3 || got.Retries != 2 || got.CacheFallbacks != 1 {
+		t.Fatalf("unexpected counts after fallback: %+v", got)
+	}
+}
+
+func TestRequestTrace_RecordsConnectionPhases(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caPath, caPEM, 0o600); err != nil {
+		t.Fatalf("write CA: %v", err)
+	}
+
+	for _, transport := range []string{TransportBrowser, TransportStandard} {
+		t.Run(transport, func(t *testing.T) {
+			rt, err := NewTransport(&config.APIConfig{Transport: transport, CABundlePath: caPath})
+			if err != nil {
+				t.Fatalf("NewTransport error: %v", err)
+			}
+
+			ctx, trace := newRequestTrace(context.Background())
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+			resp, err := (&http.Client{Transport: rt, Timeout: 5 * time.Second}).Do(req)
+			if err != nil {
+				t.Fatalf("request error: %v", err)
+			}
+			resp.Body.Close()
+
+			timing := trace.finish()
+			if timing.Connect <= 0 || timing.TLSHandshake <= 0 || timing.TTFB <= 0 {
+				t.Fatalf("expected connect, TLS and TTFB timings, got %+v", timing)
+			}
+			if timing.Total < timing.TTFB {
+				t.Fatalf("total %v shorter than TTFB %v", timing.Total, timing.TTFB)
+			}
+		})
+	}
+}
+
+func TestPercentile_NearestRank(t *testing.T) {
+	durations := make([]time.Duration, 0, 20)
+	for i := 20; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+
+	if got := percentile(durations, 50); got != 10*time.Millisecond {
+		t.Fatalf("p50 = %v, want 10ms", got)
+	}
+	if got := percentile(durations, 95); got != 19*time.Millisecond {
+		t.Fatalf("p95 = %v, want 19ms", got)
+	}
+	if got := percentile(nil, 95); got != 0 {
+		t.Fatalf("p95 of no samples = %v, want 0", got)
+	}
+	if durations[0] != 20*time.Millisecond {
+		t.Fatalf("percentile must not reorder its input")
+	}
+}
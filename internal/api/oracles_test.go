@@ -24,13 +24,18 @@ func newTestClient(oraclesURL string) *Client {
 	return NewClient(cfg, nil)
 }
 
+// withCacheDir points the client's response cache at dir so tests never touch
+// the working directory's api-cache.
+func withCacheDir(c *Client, dir string) *Client {
+	c.cache = NewCache(config.CacheConfig{Directory: dir}, c.logger)
+	return c
+}
+
 func TestFetchOracles_Success(t *testing.T) {
-	oldPath := oraclesCachePath
-	oraclesCachePath = filepath.Join(t.TempDir(), "oracles.json")
+	cacheDir := t.TempDir()
 	prevInterval := oraclesMinInterval
 	oraclesMinInterval = 0
 	t.Cleanup(func() {
-		oraclesCachePath = oldPath
 		oraclesMinInterval = prevInterval
 	})
 
@@ -46,7 +51,7 @@ func TestFetchOracles_Success(t *testing.T) {
 	}))
 	t.Cleanup(server.Close)
 
-	client := newTestClient(server.URL)
+	client := withCacheDir(newTestClient(server.URL), cacheDir)
 
 	resp, err := client.FetchOracles(context.Background())
 	if err != nil {
@@ -63,12 +68,10 @@ func TestFetchOracles_Success(t *testing.T) {
 }
 
 func TestFetchOracles_SetsUserAgent(t *testing.T) {
-	oldPath := oraclesCachePath
-	oraclesCachePath = filepath.Join(t.TempDir(), "oracles.json")
+	cacheDir := t.TempDir()
 	prevInterval := oraclesMinInterval
 	oraclesMinInterval = 0
 	t.Cleanup(func() {
-		oraclesCachePath = oldPath
 		oraclesMinInterval = prevInterval
 	})
 
@@ -81,7 +84,7 @@ func TestFetchOracles_SetsUserAgent(t *testing.T) {
 	}))
 	t.Cleanup(server.Close)
 
-	client := newTestClient(server.URL)
+	client := withCacheDir(newTestClient(server.URL), cacheDir)
 
 	if _, err := client.FetchOracles(context.Background()); err != nil {
 		t.Fatalf("FetchOracles returned error: %v", err)
@@ -93,12 +96,10 @@ func TestFetchOracles_SetsUserAgent(t *testing.T) {
 }
 
 func TestFetchOracles_StatusErrors(t *testing.T) {
-	oldPath := oraclesCachePath
-	oraclesCachePath = filepath.Join(t.TempDir(), "oracles.json")
+	cacheDir := t.TempDir()
 	prevInterval := oraclesMinInterval
 	oraclesMinInterval = 0
 	t.Cleanup(func() {
-		oraclesCachePath = oldPath
 		oraclesMinInterval = prevInterval
 	})
 
@@ -110,7 +111,7 @@ func TestFetchOracles_StatusErrors(t *testing.T) {
 		}))
 		t.Cleanup(server.Close)
 
-		client := newTestClient(server.URL)
+		client := withCacheDir(newTestClient(server.URL), cacheDir)
 
 		_, err := client.FetchOracles(context.Background())
 		if err == nil {
@@ -124,12 +125,10 @@ func TestFetchOracles_StatusErrors(t *testing.T) {
 }
 
 func TestFetchOracles_MalformedJSON(t *testing.T) {
-	oldPath := oraclesCachePath
-	oraclesCachePath = filepath.Join(t.TempDir(), "oracles.json")
+	cacheDir := t.TempDir()
 	prevInterval := oraclesMinInterval
 	oraclesMinInterval = 0
 	t.Cleanup(func() {
-		oraclesCachePath = oldPath
 		oraclesMinInterval = prevInterval
 	})
 
@@ -139,7 +138,7 @@ func TestFetchOracles_MalformedJSON(t *testing.T) {
 	}))
 	t.Cleanup(server.Close)
 
-	client := newTestClient(server.URL)
+	client := withCacheDir(newTestClient(server.URL), cacheDir)
 
 	_, err := client.FetchOracles(context.Background())
 	if err == nil {
@@ -152,12 +151,10 @@ func TestFetchOracles_MalformedJSON(t *testing.T) {
 }
 
 func TestFetchOracles_ContextCancellation(t *testing.T) {
-	oldPath := oraclesCachePath
-	oraclesCachePath = filepath.Join(t.TempDir(), "oracles.json")
+	cacheDir := t.TempDir()
 	prevInterval := oraclesMinInterval
 	oraclesMinInterval = 0
 	t.Cleanup(func() {
-		oraclesCachePath = oldPath
 		oraclesMinInterval = prevInterval
 	})
 
@@ -166,7 +163,7 @@ func TestFetchOracles_ContextCancellation(t *testing.T) {
 	}))
 	t.Cleanup(server.Close)
 
-	client := newTestClient(server.URL)
+	client := withCacheDir(newTestClient(server.URL), cacheDir)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
@@ -185,18 +182,15 @@ func TestFetchOracles_ContextCancellation(t *testing.T) {
 }
 
 func TestFetchOracles_FallbackCache(t *testing.T) {
-	// point cache to temp file
-	oldPath := oraclesCachePath
-	t.Cleanup(func() { oraclesCachePath = oldPath })
-	tmpDir := t.TempDir()
-	oraclesCachePath = filepath.Join(tmpDir, "oracles.json")
+	cacheDir := t.TempDir()
+	cachePath := filepath.Join(cacheDir, "oracles.json")
 
 	fixturePath := filepath.Join("..", "..", "testdata", "oracle_response.json")
 	data, err := os.ReadFile(fixturePath)
 	if err != nil {
 		t.Fatalf("read fixture: %v", err)
 	}
-	if err := os.WriteFile(oraclesCachePath, data, 0o644); err != nil {
+	if err := os.WriteFile(cachePath, data, 0o644); err != nil {
 		t.Fatalf("write cache: %v", err)
 	}
 
@@ -206,7 +200,7 @@ func TestFetchOracles_FallbackCache(t *testing.T) {
 	}))
 	t.Cleanup(server.Close)
 
-	client := newTestClient(server.URL)
+	client := withCacheDir(newTestClient(server.URL), cacheDir)
 
 	resp, err := client.FetchOracles(context.Background())
 	if err != nil {
@@ -218,10 +212,8 @@ func TestFetchOracles_FallbackCache(t *testing.T) {
 }
 
 func TestFetchOracles_WritesCacheOnSuccess(t *testing.T) {
-	oldPath := oraclesCachePath
-	t.Cleanup(func() { oraclesCachePath = oldPath })
-	tmpDir := t.TempDir()
-	oraclesCachePath = filepath.Join(tmpDir, "oracles.json")
+	cacheDir := t.TempDir()
+	cachePath := filepath.Join(cacheDir, "oracles.json")
 
 	fixturePath := filepath.Join("..", "..", "testdata", "oracle_response.json")
 	fixture, err := os.ReadFile(fixturePath)
@@ -235,12 +227,12 @@ func TestFetchOracles_WritesCacheOnSuccess(t *testing.T) {
 	}))
 	t.Cleanup(server.Close)
 
-	client := newTestClient(server.URL)
+	client := withCacheDir(newTestClient(server.URL), cacheDir)
 	if _, err := client.FetchOracles(context.Background()); err != nil {
 		t.Fatalf("FetchOracles returned error: %v", err)
 	}
 
-	info, err := os.Stat(oraclesCachePath)
+	info, err := os.Stat(cachePath)
 	if err != nil {
 		t.Fatalf("expected cache file, got error: %v", err)
 	}
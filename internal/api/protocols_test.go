@@ -37,7 +37,7 @@ func TestFetchProtocols_Success(t *testing.T) {
 	}))
 	t.Cleanup(server.Close)
 
-	client := newProtocolTestClient(server.URL)
+	client := withCacheDir(newProtocolTestClient(server.URL), t.TempDir())
 
 	resp, err := client.FetchProtocols(context.Background())
 	if err != nil {
@@ -63,7 +63,7 @@ func TestFetchProtocols_SetsUserAgent(t *testing.T) {
 	}))
 	t.Cleanup(server.Close)
 
-	client := newProtocolTestClient(server.URL)
+	client := withCacheDir(newProtocolTestClient(server.URL), t.TempDir())
 
 	if _, err := client.FetchProtocols(context.Background()); err != nil {
 		t.Fatalf("FetchProtocols returned error: %v", err)
@@ -75,10 +75,8 @@ func TestFetchProtocols_SetsUserAgent(t *testing.T) {
 }
 
 func TestFetchProtocols_StatusErrors(t *testing.T) {
-	// Temporarily override cache path to ensure no fallback during error tests
-	origCachePath := protocolsCachePath
-	protocolsCachePath = filepath.Join(t.TempDir(), "nonexistent", "protocols.json")
-	t.Cleanup(func() { protocolsCachePath = origCachePath })
+	// Point the cache at an empty directory to ensure no fallback during error tests
+	cacheDir := filepath.Join(t.TempDir(), "nonexistent")
 
 	cases := []int{http.StatusInternalServerError, http.StatusNotFound}
 
@@ -88,7 +86,7 @@ func TestFetchProtocols_StatusErrors(t *testing.T) {
 		}))
 		t.Cleanup(server.Close)
 
-		client := newProtocolTestClient(server.URL)
+		client := withCacheDir(newProtocolTestClient(server.URL), cacheDir)
 
 		_, err := client.FetchProtocols(context.Background())
 		if err == nil {
@@ -102,10 +100,8 @@ func TestFetchProtocols_StatusErrors(t *testing.T) {
 }
 
 func TestFetchProtocols_MalformedJSON(t *testing.T) {
-	// Temporarily override cache path to ensure no fallback during error tests
-	origCachePath := protocolsCachePath
-	protocolsCachePath = filepath.Join(t.TempDir(), "nonexistent", "protocols.json")
-	t.Cleanup(func() { protocolsCachePath = origCachePath })
+	// Point the cache at an empty directory to ensure no fallback during error tests
+	cacheDir := filepath.Join(t.TempDir(), "nonexistent")
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -113,7 +109,7 @@ func TestFetchProtocols_MalformedJSON(t *testing.T) {
 	}))
 	t.Cleanup(server.Close)
 
-	client := newProtocolTestClient(server.URL)
+	client := withCacheDir(newProtocolTestClient(server.URL), cacheDir)
 
 	_, err := client.FetchProtocols(context.Background())
 	if err == nil {
@@ -126,17 +122,15 @@ func TestFetchProtocols_MalformedJSON(t *testing.T) {
 }
 
 func TestFetchProtocols_ContextCancellation(t *testing.T) {
-	// Temporarily override cache path to ensure no fallback during error tests
-	origCachePath := protocolsCachePath
-	protocolsCachePath = filepath.Join(t.TempDir(), "nonexistent", "protocols.json")
-	t.Cleanup(func() { protocolsCachePath = origCachePath })
+	// Point the cache at an empty directory to ensure no fallback during error tests
+	cacheDir := filepath.Join(t.TempDir(), "nonexistent")
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		<-r.Context().Done()
 	}))
 	t.Cleanup(server.Close)
 
-	client := newProtocolTestClient(server.URL)
+	client := withCacheDir(newProtocolTestClient(server.URL), cacheDir)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
@@ -161,7 +155,7 @@ func TestFetchProtocols_EmptyArray(t *testing.T) {
 	}))
 	t.Cleanup(server.Close)
 
-	client := newProtocolTestClient(server.URL)
+	client := withCacheDir(newProtocolTestClient(server.URL), t.TempDir())
 
 	resp, err := client.FetchProtocols(context.Background())
 	if err != nil {
@@ -176,11 +170,8 @@ func TestFetchProtocols_EmptyArray(t *testing.T) {
 func TestFetchProtocols_FallbackCache(t *testing.T) {
 	// Set up temp cache directory with a valid cache file
 	tmpDir := t.TempDir()
-	cachePath := filepath.Join(tmpDir, "protocols.json")
-
-	origCachePath := protocolsCachePath
-	protocolsCachePath = cachePath
-	t.Cleanup(func() { protocolsCachePath = origCachePath })
+	cacheDir := tmpDir
+	cachePath := filepath.Join(tmpDir, "lite-protocols2.json")
 
 	// Write cache file
 	cacheData := `[{"id":"cached-proto","name":"Cached Protocol","symbol":"CACHE","category":"Cache"}]`
@@ -194,7 +185,7 @@ func TestFetchProtocols_FallbackCache(t *testing.T) {
 	}))
 	t.Cleanup(server.Close)
 
-	client := newProtocolTestClient(server.URL)
+	client := withCacheDir(newProtocolTestClient(server.URL), cacheDir)
 
 	resp, err := client.FetchProtocols(context.Background())
 	if err != nil {
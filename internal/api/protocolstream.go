@@ -0,0 +1,228 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ProtocolRef is the lightweight identity of a /lite/protocols2 entry kept for
+// every protocol, including those dropped by the oracle filter.
+type ProtocolRef struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+// ProtocolIndex resolves slugs and names against the full protocol listing
+// without retaining full Protocol values.
+type ProtocolIndex struct {
+	refs   []ProtocolRef
+	bySlug map[string]int
+	byName map[string]int
+}
+
+// NewProtocolIndex builds an index over refs. Slug and name lookups are
+// case-insensitive; the first entry wins on duplicates.
+func NewProtocolIndex(refs []ProtocolRef) *ProtocolIndex {
+	idx := &ProtocolIndex{
+		refs:   refs,
+		bySlug: make(map[string]int, len(refs)),
+		byName: make(map[string]int, len(refs)),
+	}
+	for i, ref := range refs {
+		if key := strings.ToLower(strings.TrimSpace(ref.Slug)); key != "" {
+			if _, ok := idx.bySlug[key]; !ok {
+				idx.bySlug[key] = i
+			}
+		}
+		if key := strings.ToLower(strings.TrimSpace(ref.Name)); key != "" {
+			if _, ok := idx.byName[key]; !ok {
+				idx.byName[key] = i
+			}
+		}
+	}
+
+	return idx
+}
+
+// Len returns the number of indexed protocols.
+func (i *ProtocolIndex) Len() int {
+	if i == nil {
+		return 0
+	}
+	return len(i.refs)
+}
+
+// Refs returns every indexed protocol in listing order.
+func (i *ProtocolIndex) Refs() []ProtocolRef {
+	if i == nil {
+		return nil
+	}
+	return i.refs
+}
+
+// LookupSlug finds a protocol by slug.
+func (i *ProtocolIndex) LookupSlug(slug string) (ProtocolRef, bool) {
+	if i == nil {
+		return ProtocolRef{}, false
+	}
+	pos, ok := i.bySlug[strings.ToLower(strings.TrimSpace(slug))]
+	if !ok {
+		return ProtocolRef{}, false
+	}
+	return i.refs[pos], true
+}
+
+// LookupName finds a protocol by display name.
+func (i *ProtocolIndex) LookupName(name string) (ProtocolRef, bool) {
+	if i == nil {
+		return ProtocolRef{}, false
+	}
+	pos, ok := i.byName[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return ProtocolRef{}, false
+	}
+	return i.refs[pos], true
+}
+
+// streamDecoder is implemented by targets that decode a response body
+// incrementally instead of through a single Decode call. A non-nil observe
+// receives each raw record as it is decoded.
+type streamDecoder interface {
+	decodeStream(dec *json.Decoder, observe func([]byte)) error
+}
+
+// protocolStream decodes /lite/protocols2 token by token, keeping full
+// Protocol values only for entries that use one of the configured oracles and
+// a ProtocolRef for every entry. It accepts the bare array, the "protocols"
+// envelope, and its own cached form, which adds the "index" of all entries.
+type protocolStream struct {
+	oracles   []string
+	Protocols []Protocol    `json:"protocols"`
+	Index     []ProtocolRef `json:"index"`
+}
+
+func newProtocolStream(oracles []string) *protocolStream {
+	return &protocolStream{oracles: oracles}
+}
+
+// keep reports whether p uses any configured oracle. No configured oracles
+// keeps everything.
+func (s *protocolStream) keep(p Protocol) bool {
+	if len(s.oracles) == 0 {
+		return true
+	}
+	for _, name := range s.oracles {
+		if strings.EqualFold(p.Oracle, name) {
+			return true
+		}
+		for _, o := range p.Oracles {
+			if strings.EqualFold(o, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UnmarshalJSON lets cached and replayed bodies go through the same filter.
+func (s *protocolStream) UnmarshalJSON(data []byte) error {
+	return s.decodeStream(json.NewDecoder(bytes.NewReader(data)), nil)
+}
+
+func (s *protocolStream) decodeStream(dec *json.Decoder, observe func([]byte)) error {
+	s.Protocols = s.Protocols[:0]
+	s.Index = s.Index[:0]
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('['):
+		return s.decodeArray(dec, observe)
+	case json.Delim('{'):
+		return s.decodeEnvelope(dec, observe)
+	default:
+		return fmt.Errorf("protocols: unexpected token %v", tok)
+	}
+}
+
+// decodeEnvelope walks an object, streaming the "protocols" array and taking
+// a stored "index" verbatim; every other field is skipped.
+func (s *protocolStream) decodeEnvelope(dec *json.Decoder, observe func([]byte)) error {
+	var storedIndex []ProtocolRef
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := keyTok.(string)
+
+		switch key {
+		case "protocols":
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if tok == nil {
+				continue
+			}
+			if tok != json.Delim('[') {
+				return fmt.Errorf("protocols: expected array, got %v", tok)
+			}
+			if err := s.decodeArray(dec, observe); err != nil {
+				return err
+			}
+		case "index":
+			if err := dec.Decode(&storedIndex); err != nil {
+				return err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	if storedIndex != nil {
+		s.Index = storedIndex
+	}
+	return nil
+}
+
+// decodeArray consumes array elements after the opening bracket, one
+// Protocol at a time, and the closing bracket. With observe set each element
+// is first captured raw so its shape can be inspected.
+func (s *protocolStream) decodeArray(dec *json.Decoder, observe func([]byte)) error {
+	for dec.More() {
+		var p Protocol
+		if observe != nil {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return err
+			}
+			observe(raw)
+			if err := json.Unmarshal(raw, &p); err != nil {
+				return err
+			}
+		} else if err := dec.Decode(&p); err != nil {
+			return err
+		}
+		s.Index = append(s.Index, ProtocolRef{ID: p.ID, Name: p.Name, Slug: p.Slug, Category: p.Category})
+		if s.keep(p) {
+			s.Protocols = append(s.Protocols, p)
+		}
+	}
+
+	_, err := dec.Token()
+	return err
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+const streamFixture = `[
+	{"id":"1","name":"Alpha","slug":"alpha","category":"Lending","oracles":["Switchboard"]},
+	{"id":"2","name":"Beta","slug":"beta","category":"CDP","oracle":"switchboard"},
+	{"id":"3","name":"Gamma","slug":"gamma","category":"Dexs","oracles":["Pyth"]}
+]`
+
+func TestProtocolStream_FiltersArrayAndIndexesAll(t *testing.T) {
+	stream := newProtocolStream([]string{"Switchboard"})
+	if err := json.Unmarshal([]byte(streamFixture), stream); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	if len(stream.Protocols) != 2 || stream.Protocols[0].Slug != "alpha" || stream.Protocols[1].Slug != "beta" {
+		t.Fatalf("expected alpha and beta kept, got %+v", stream.Protocols)
+	}
+	if len(stream.Index) != 3 {
+		t.Fatalf("expected all 3 protocols indexed, got %d", len(stream.Index))
+	}
+}
+
+func TestProtocolStream_EnvelopeSkipsUnknownFields(t *testing.T) {
+	body := `{"chains":["Solana"],"protocols":` + streamFixture + `,"parentProtocols":[{"id":"p"}]}`
+
+	stream := newProtocolStream([]string{"Pyth"})
+	if err := json.Unmarshal([]byte(body), stream); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	if len(stream.Protocols) != 1 || stream.Protocols[0].Slug != "gamma" {
+		t.Fatalf("expected only gamma kept, got %+v", stream.Protocols)
+	}
+	if len(stream.Index) != 3 {
+		t.Fatalf("expected 3 indexed protocols, got %d", len(stream.Index))
+	}
+}
+
+func TestProtocolStream_CachedFormKeepsFullIndex(t *testing.T) {
+	stream := newProtocolStream([]string{"Switchboard"})
+	if err := json.Unmarshal([]byte(streamFixture), stream); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	cached, err := json.Marshal(stream)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	reloaded := newProtocolStream([]string{"Switchboard"})
+	if err := json.Unmarshal(cached, reloaded); err != nil {
+		t.Fatalf("reload error: %v", err)
+	}
+	if len(reloaded.Protocols) != 2 || len(reloaded.Index) != 3 {
+		t.Fatalf("expected 2 protocols and 3 index entries after reload, got %d and %d", len(reloaded.Protocols), len(reloaded.Index))
+	}
+}
+
+func TestProtocolStream_DecodesEnvelopeAndBareArray(t *testing.T) {
+	list := `[
+		{"id": "one", "name": "One", "slug": "one", "category": "Lending"},
+		{"id": "two", "name": "Two", "slug": "two", "category": "DEX", "oracle": "Switchboard"}
+	]`
+
+	for name, body := range map[string]string{
+		"envelope":   `{"protocols": ` + list + `}`,
+		"bare array": list,
+	} {
+		t.Run(name, func(t *testing.T) {
+			stream := newProtocolStream(nil)
+			if err := json.Unmarshal([]byte(body), stream); err != nil {
+				t.Fatalf("unexpected error decoding %s: %v", name, err)
+			}
+
+			if len(stream.Protocols) != 2 {
+				t.Fatalf("expected 2 protocols, got %d", len(stream.Protocols))
+			}
+			if stream.Protocols[1].Oracle != "Switchboard" || stream.Protocols[1].Category != "DEX" {
+				t.Fatalf("unexpected decoded protocol: %+v", stream.Protocols[1])
+			}
+		})
+	}
+}
+
+func TestProtocolStream_RejectsScalar(t *testing.T) {
+	if err := json.Unmarshal([]byte(`"nope"`), newProtocolStream(nil)); err == nil {
+		t.Fatalf("expected error for non-container body")
+	}
+}
+
+func TestProtocolIndex_Lookups(t *testing.T) {
+	idx := NewProtocolIndex([]ProtocolRef{
+		{ID: "1", Name: "Kamino Lend", Slug: "kamino-lend"},
+		{ID: "2", Name: "Kamino Lend", Slug: "kamino-lend-v2"},
+	})
+
+	if ref, ok := idx.LookupSlug("KAMINO-LEND"); !ok || ref.ID != "1" {
+		t.Fatalf("expected case-insensitive slug lookup, got %+v %v", ref, ok)
+	}
+	if ref, ok := idx.LookupName("kamino lend"); !ok || ref.ID != "1" {
+		t.Fatalf("expected first entry to win name lookup, got %+v %v", ref, ok)
+	}
+	if _, ok := idx.LookupSlug("missing"); ok {
+		t.Fatalf("expected missing slug lookup to fail")
+	}
+
+	var nilIdx *ProtocolIndex
+	if nilIdx.Len() != 0 {
+		t.Fatalf("expected nil index to be empty")
+	}
+}
+
+func TestFetchProtocols_AppliesOracleFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, strings.NewReader(streamFixture))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&config.APIConfig{
+		ProtocolsURL: server.URL,
+		Timeout:      time.Second,
+		Cache:        config.CacheConfig{Directory: t.TempDir()},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client.SetOracleFilter("Switchboard")
+
+	protocols, err := client.FetchProtocols(context.Background())
+	if err != nil {
+		t.Fatalf("FetchProtocols error: %v", err)
+	}
+	if len(protocols) != 2 {
+		t.Fatalf("expected 2 filtered protocols, got %d", len(protocols))
+	}
+	if _, ok := client.ProtocolIndex().LookupSlug("gamma"); !ok {
+		t.Fatalf("expected filtered-out protocol to remain resolvable via index")
+	}
+}
+
+func TestFetchProtocols_CacheIsScopedToOracleFilter(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = io.Copy(w, strings.NewReader(streamFixture))
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	newClient := func(oracles ...string) *Client {
+		client := NewClient(&config.APIConfig{
+			ProtocolsURL: server.URL,
+			Timeout:      time.Second,
+			Cache:        config.CacheConfig{Directory: dir, MaxAge: time.Hour},
+		}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		client.SetOracleFilter(oracles...)
+		return client
+	}
+
+	if _, err := newClient("Switchboard").FetchProtocols(context.Background()); err != nil {
+		t.Fatalf("FetchProtocols error: %v", err)
+	}
+	protocols, err := newClient("Pyth").FetchProtocols(context.Background())
+	if err != nil {
+		t.Fatalf("FetchProtocols error: %v", err)
+	}
+	if len(protocols) != 1 || protocols[0].Slug != "gamma" {
+		t.Fatalf("expected gamma for the Pyth filter, got %+v", protocols)
+	}
+	if requests != 2 {
+		t.Fatalf("expected each filter to fetch once, got %d requests", requests)
+	}
+
+	protocols, err = newClient("switchboard").FetchProtocols(context.Background())
+	if err != nil {
+		t.Fatalf("FetchProtocols error: %v", err)
+	}
+	if len(protocols) != 2 || requests != 2 {
+		t.Fatalf("expected fresh cache hit for the Switchboard filter, got %d protocols after %d requests", len(protocols), requests)
+	}
+}
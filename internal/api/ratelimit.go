@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Default protocol TVL pacing: 5 requests per second with no burst, which
+// matches the historical fixed 200ms spacing between /protocol/{slug} calls.
+const (
+	DefaultProtocolRPS   = 5.0
+	DefaultProtocolBurst = 1
+)
+
+// RateLimiter is a token bucket safe for concurrent use. Tokens refill at rps
+// per second up to burst; Wait blocks until a token is available. A nil
+// RateLimiter never blocks.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rps    float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter builds a token bucket that starts full. Non-positive rps
+// disables limiting and returns nil; burst below one is treated as one.
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if rps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rps:    rps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait reserves one token, sleeping until it becomes available or ctx ends.
+// Reservations are taken under the lock so concurrent callers are spaced
+// fairly rather than all waking at the same refill instant.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rps
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rps * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
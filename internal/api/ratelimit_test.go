@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_BurstThenPaced(t *testing.T) {
+	limiter := NewRateLimiter(20, 2)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("wait %d error: %v", i, err)
+		}
+	}
+	elapsed := time.Since(start)
+
+	// Two tokens are available immediately; the remaining two need 50ms each.
+	if elapsed < 90*time.Millisecond {
+		t.Fatalf("expected pacing after burst, elapsed %v", elapsed)
+	}
+}
+
+func TestRateLimiter_SharedAcrossGoroutines(t *testing.T) {
+	limiter := NewRateLimiter(50, 1)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = limiter.Wait(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	// Five requests at 50 rps with burst 1 span at least four 20ms intervals.
+	if elapsed := time.Since(start); elapsed < 75*time.Millisecond {
+		t.Fatalf("expected shared limiter to pace goroutines, elapsed %v", elapsed)
+	}
+}
+
+func TestRateLimiter_ContextCancel(t *testing.T) {
+	limiter := NewRateLimiter(1, 1)
+	_ = limiter.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := limiter.Wait(ctx); err == nil {
+		t.Fatalf("expected context error while waiting for token")
+	}
+}
+
+func TestRateLimiter_NilNeverBlocks(t *testing.T) {
+	limiter := NewRateLimiter(0, 0)
+	if limiter != nil {
+		t.Fatalf("expected nil limiter for non-positive rps")
+	}
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("nil limiter returned error: %v", err)
+	}
+}
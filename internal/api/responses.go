@@ -1,9 +1,9 @@
 package api
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // OracleAPIResponse represents the payload returned by GET /oracles.
@@ -25,13 +25,24 @@ type Protocol struct {
 	Oracles  []string `json:"oracles,omitempty"`
 	Oracle   string   `json:"oracle,omitempty"`
 	URL      string   `json:"url,omitempty"`
+	// ParentProtocol is the "parent#<slug>" ID of the parent protocol, if any.
+	ParentProtocol string `json:"parentProtocol,omitempty"`
 }
 
 // ProtocolTVLResponse represents the payload from GET /protocol/{slug}.
+// ChainTvls holds the per-chain TVL series keyed like CurrentChainTvls,
+// including DefiLlama's breakdown keys (see SplitChainTVLKey).
 type ProtocolTVLResponse struct {
-	Name             string             `json:"name"`
-	TVL              []TVLDataPoint     `json:"tvl"`
-	CurrentChainTvls map[string]float64 `json:"currentChainTvls"`
+	Name             string              `json:"name"`
+	TVL              []TVLDataPoint      `json:"tvl"`
+	CurrentChainTvls map[string]float64  `json:"currentChainTvls"`
+	ChainTvls        map[string]ChainTVL `json:"chainTvls,omitempty"`
+}
+
+// ChainTVL is one entry of ProtocolTVLResponse.ChainTvls. The token
+// breakdowns DefiLlama sends alongside the series are not decoded.
+type ChainTVL struct {
+	TVL []TVLDataPoint `json:"tvl"`
 }
 
 // TVLDataPoint represents a single point in a protocol's TVL history.
@@ -40,48 +51,53 @@ type TVLDataPoint struct {
 	TotalLiquidityUSD float64 `json:"totalLiquidityUSD"`
 }
 
-// protocolList flexibly unmarshals either a bare array or an envelope containing
-// a top-level "protocols" field. The DefiLlama endpoint has shipped both shapes
-// historically, so decoding must tolerate either form.
-type protocolList []Protocol
-
-func (p *protocolList) UnmarshalJSON(data []byte) error {
-	if len(data) == 0 {
-		return nil
-	}
-
-	if data[0] == '{' {
-		var envelope struct {
-			Protocols []Protocol `json:"protocols"`
-		}
-		if err := json.Unmarshal(data, &envelope); err != nil {
-			return err
-		}
-		*p = envelope.Protocols
-		return nil
-	}
-
-	var arr []Protocol
-	if err := json.Unmarshal(data, &arr); err != nil {
-		return err
-	}
+// ChainInfo represents one chain returned by GET /v2/chains. TVL is the
+// chain's current total DeFi TVL in USD.
+type ChainInfo struct {
+	Name        string  `json:"name"`
+	TVL         float64 `json:"tvl"`
+	GeckoID     string  `json:"gecko_id,omitempty"`
+	TokenSymbol string  `json:"tokenSymbol,omitempty"`
+}
 
-	*p = arr
-	return nil
+// ChainTVLPoint represents one daily point returned by
+// GET /v2/historicalChainTvl/{chain}.
+type ChainTVLPoint struct {
+	Date int64   `json:"date"`
+	TVL  float64 `json:"tvl"`
 }
 
 // FetchResult aggregates oracle and protocol responses from parallel fetch operations.
 type FetchResult struct {
 	OracleResponse *OracleAPIResponse
-	Protocols      []Protocol
+	// Protocols holds only protocols matching the source's oracle filter;
+	// ProtocolIndex covers every protocol in the listing.
+	Protocols     []Protocol
+	ProtocolIndex *ProtocolIndex
+	// OraclesUnchanged and ProtocolsUnchanged are set when DefiLlama answered a
+	// conditional request with 304 and the cached body was reused.
+	OraclesUnchanged   bool
+	ProtocolsUnchanged bool
+	// CacheEntries lists datasets served from the local cache (fresh within
+	// max age, or as a fallback after a failed fetch) with their provenance.
+	CacheEntries []CacheEntry
+	// SchemaDrift compares the payloads received during the fetch with the
+	// schema manifest; nil when drift checking is disabled.
+	SchemaDrift *SchemaDriftReport
+	// FetchReport holds per-endpoint request metrics for the fetch; nil for
+	// sources that make no HTTP requests.
+	FetchReport *FetchReport
 }
 
 // APIError represents an HTTP error response with metadata for retry decisions.
+// RetryAfter holds the server-advised delay from Retry-After or
+// RateLimit-Reset on 429/503 responses; zero when the server gave none.
 type APIError struct {
 	Endpoint   string
 	StatusCode int
 	Message    string
 	Err        error
+	RetryAfter time.Duration
 }
 
 func (e *APIError) Error() string {
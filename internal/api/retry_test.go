@@ -357,3 +357,84 @@ func TestClient_RandomizeHeadersDisabled(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		status int
+		header map[string]string
+		want   time.Duration
+	}{
+		{"retry-after seconds", http.StatusTooManyRequests, map[string]string{"Retry-After": "3"}, 3 * time.Second},
+		{"retry-after http date", http.StatusServiceUnavailable, map[string]string{"Retry-After": now.Add(5 * time.Second).Format(http.TimeFormat)}, 5 * time.Second},
+		{"ratelimit-reset delta", http.StatusTooManyRequests, map[string]string{"RateLimit-Reset": "2"}, 2 * time.Second},
+		{"ratelimit-reset epoch", http.StatusTooManyRequests, map[string]string{"RateLimit-Reset": fmt.Sprint(now.Add(7 * time.Second).Unix())}, 7 * time.Second},
+		{"retry-after wins", http.StatusTooManyRequests, map[string]string{"Retry-After": "1", "RateLimit-Reset": "9"}, time.Second},
+		{"past date clamps to zero", http.StatusTooManyRequests, map[string]string{"Retry-After": now.Add(-time.Minute).Format(http.TimeFormat)}, 0},
+		{"garbage ignored", http.StatusTooManyRequests, map[string]string{"Retry-After": "soon"}, 0},
+		{"other status ignored", http.StatusInternalServerError, map[string]string{"Retry-After": "3"}, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tc.header {
+				header.Set(k, v)
+			}
+			if got := parseRetryAfter(tc.status, header, now); got != tc.want {
+				t.Fatalf("parseRetryAfter = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDoWithRetry_HonorsRetryAfterCappedByMax(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	buf := &bytes.Buffer{}
+	cfg := &config.APIConfig{Timeout: time.Second, MaxRetries: 2, RetryDelay: time.Millisecond, MaxRetryAfter: 120 * time.Millisecond}
+	client := NewClient(cfg, newTestLogger(buf))
+
+	start := time.Now()
+	var payload testPayload
+	err := client.doWithRetry(context.Background(), func(ctx context.Context) error {
+		return client.doRequest(ctx, server.URL, &payload)
+	})
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("expected success after retry, got %v", err)
+	}
+	if elapsed < 100*time.Millisecond {
+		t.Fatalf("expected retry to wait for capped server delay, elapsed %v", elapsed)
+	}
+	if elapsed > 5*time.Second {
+		t.Fatalf("expected server delay to be capped, elapsed %v", elapsed)
+	}
+	if !strings.Contains(buf.String(), "backoff_source=server_capped") {
+		t.Fatalf("expected capped server backoff in logs, got %s", buf.String())
+	}
+}
+
+func TestDeferOracleRequests_OnlyExtendsWindow(t *testing.T) {
+	client := NewClient(&config.APIConfig{Timeout: time.Second}, newTestLogger(&bytes.Buffer{}))
+
+	later := time.Now().Add(time.Hour)
+	client.deferOracleRequests(later)
+	client.deferOracleRequests(time.Now())
+
+	if !client.nextOracleAllowedAt.Equal(later) {
+		t.Fatalf("expected oracle window to stay at %v, got %v", later, client.nextOracleAllowedAt)
+	}
+}
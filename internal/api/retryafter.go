@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxRetryAfter caps how long a single server-advised retry delay may
+// hold up the retry loop when api.max_retry_after is unset.
+const DefaultMaxRetryAfter = 60 * time.Second
+
+// unixTimestampThreshold separates RateLimit-Reset delta-seconds from absolute
+// epoch timestamps, which some gateways emit instead.
+const unixTimestampThreshold = 1_000_000_000
+
+// parseRetryAfter extracts the server-advised delay from a throttling
+// response. Retry-After (delta-seconds or HTTP-date) wins over
+// RateLimit-Reset (delta-seconds or epoch seconds). Only 429 and 503 carry
+// meaningful advice; other statuses and unparsable values return zero.
+func parseRetryAfter(statusCode int, header http.Header, now time.Time) time.Duration {
+	if statusCode != http.StatusTooManyRequests && statusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+
+	if v := strings.TrimSpace(header.Get("Retry-After")); v != "" {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil {
+			return secondsToDuration(secs)
+		}
+		if at, err := http.ParseTime(v); err == nil {
+			return positiveDuration(at.Sub(now))
+		}
+	}
+
+	if v := strings.TrimSpace(header.Get("RateLimit-Reset")); v != "" {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil {
+			if secs >= unixTimestampThreshold {
+				return positiveDuration(time.Unix(int64(secs), 0).Sub(now))
+			}
+			return secondsToDuration(secs)
+		}
+	}
+
+	return 0
+}
+
+func secondsToDuration(secs float64) time.Duration {
+	return positiveDuration(time.Duration(secs * float64(time.Second)))
+}
+
+func positiveDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
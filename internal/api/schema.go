@@ -0,0 +1,428 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+// Datasets checked for schema drift. Protocol listings are checked per entry;
+// the other datasets per response body.
+const (
+	SchemaDatasetOracles     = "oracles"
+	SchemaDatasetProtocols   = "protocols"
+	SchemaDatasetProtocolTVL = "protocol_tvl"
+)
+
+// JSON value kinds used in a SchemaManifest. A trailing "?" marks a field as
+// optional: its absence is not reported as a removal. "any" accepts every
+// kind, for fields that are known but not read.
+const (
+	schemaTypeAny    = "any"
+	schemaTypeObject = "object"
+	schemaTypeArray  = "array"
+	schemaTypeString = "string"
+	schemaTypeNumber = "number"
+	schemaTypeBool   = "bool"
+	schemaTypeNull   = "null"
+)
+
+// schemaRootPath records the kind of a payload that is not a JSON object.
+const schemaRootPath = "$"
+
+// SchemaManifest lists the expected fields per dataset as field path to JSON
+// kind. Fields of objects inside arrays use "parent[].field".
+type SchemaManifest map[string]map[string]string
+
+// DefaultSchemaManifest returns the fields of each DefiLlama payload: those
+// the extractor reads with their kinds, and the rest of what DefiLlama
+// currently returns as optional "any" so only genuinely new fields are
+// reported as added.
+func DefaultSchemaManifest() SchemaManifest {
+	return SchemaManifest{
+		SchemaDatasetOracles: {
+			"oracles":        schemaTypeObject,
+			"chart":          schemaTypeObject,
+			"oraclesTVS":     schemaTypeObject,
+			"chainsByOracle": schemaTypeObject,
+			"chainChart":     schemaTypeAny + "?",
+		},
+		SchemaDatasetProtocols: withKnownFields(map[string]string{
+			"id":             schemaTypeString,
+			"name":           schemaTypeString,
+			"slug":           schemaTypeString,
+			"category":       schemaTypeString,
+			"tvl":            schemaTypeNumber,
+			"chains":         schemaTypeArray,
+			"oracles":        schemaTypeArray + "?",
+			"oracle":         schemaTypeString + "?",
+			"url":            schemaTypeString + "?",
+			"parentProtocol": schemaTypeString + "?",
+		},
+			"address", "audit_links", "audit_note", "audits", "chain", "chainTvls",
+			"cmcId", "deadUrl", "defillamaId", "deprecated", "description",
+			"forkedFrom", "geckoId", "gecko_id", "github", "governanceID",
+			"hallmarks", "listedAt", "logo", "mcap", "misrepresentedTokens",
+			"module", "openSource", "oraclesBreakdown", "oraclesByChain",
+			"referralUrl", "rugged", "stablecoins", "symbol", "treasury",
+			"tvlPrevDay", "tvlPrevMonth", "tvlPrevWeek", "twitter",
+			"wrongLiquidity",
+		),
+		SchemaDatasetProtocolTVL: withKnownFields(map[string]string{
+			"name":                    schemaTypeString,
+			"tvl":                     schemaTypeArray,
+			"tvl[].date":              schemaTypeNumber,
+			"tvl[].totalLiquidityUSD": schemaTypeNumber,
+			"currentChainTvls":        schemaTypeObject,
+			"chainTvls":               schemaTypeObject + "?",
+		},
+			"address", "audit_links", "audit_note", "audits", "category", "chain",
+			"chains", "cmcId", "deadUrl", "deprecated", "description",
+			"forkedFrom", "gecko_id", "github", "governanceID", "hallmarks", "id",
+			"isParentProtocol", "listedAt", "logo", "mcap", "methodology",
+			"misrepresentedTokens", "module", "openSource", "oracles",
+			"oraclesBreakdown", "oraclesByChain", "otherProtocols",
+			"parentProtocol", "raises", "referralUrl", "rugged", "slug",
+			"stablecoins", "symbol", "tokens", "tokensInUsd", "treasury",
+			"twitter", "url", "wrongLiquidity",
+		),
+	}
+}
+
+// withKnownFields adds names to fields as optional "any", leaving fields that
+// are already listed untouched.
+func withKnownFields(fields map[string]string, names ...string) map[string]string {
+	for _, name := range names {
+		if _, ok := fields[name]; !ok {
+			fields[name] = schemaTypeAny + "?"
+		}
+	}
+	return fields
+}
+
+// LoadSchemaManifest reads a JSON manifest from path.
+func LoadSchemaManifest(path string) (SchemaManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read schema manifest: %w", err)
+	}
+
+	var manifest SchemaManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parse schema manifest %s: %w", path, err)
+	}
+
+	return manifest, nil
+}
+
+// FieldDrift describes one field that differs from the manifest.
+type FieldDrift struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected,omitempty"`
+	Observed string `json:"observed,omitempty"`
+}
+
+// DatasetDrift holds the differences found for one dataset. Samples counts the
+// payloads (or protocol entries) inspected.
+type DatasetDrift struct {
+	Dataset     string       `json:"dataset"`
+	Samples     int          `json:"samples"`
+	Added       []FieldDrift `json:"added,omitempty"`
+	Removed     []FieldDrift `json:"removed,omitempty"`
+	TypeChanged []FieldDrift `json:"type_changed,omitempty"`
+}
+
+// Breaking reports whether an expected field disappeared or changed kind.
+func (d DatasetDrift) Breaking() bool {
+	return len(d.Removed) > 0 || len(d.TypeChanged) > 0
+}
+
+// SchemaDriftReport compares every dataset observed during a cycle with the
+// manifest.
+type SchemaDriftReport struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Datasets    []DatasetDrift `json:"datasets"`
+}
+
+// HasDrift reports whether any dataset differs from the manifest.
+func (r *SchemaDriftReport) HasDrift() bool {
+	if r == nil {
+		return false
+	}
+	for _, d := range r.Datasets {
+		if d.Breaking() || len(d.Added) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Breaking reports whether any dataset lost an expected field or saw one
+// change kind. Added fields alone are not breaking.
+func (r *SchemaDriftReport) Breaking() bool {
+	if r == nil {
+		return false
+	}
+	for _, d := range r.Datasets {
+		if d.Breaking() {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaObservation accumulates field kinds seen across payloads of a dataset.
+type schemaObservation struct {
+	samples int
+	fields  map[string]map[string]struct{}
+	// elements counts objects seen inside each "parent[]" array so nested
+	// fields are only reported as removed when there was something to inspect.
+	elements map[string]int
+}
+
+// SchemaChecker records the shape of raw DefiLlama payloads and compares it
+// with a SchemaManifest. It is safe for concurrent use.
+type SchemaChecker struct {
+	manifest SchemaManifest
+	mu       sync.Mutex
+	observed map[string]*schemaObservation
+	now      func() time.Time
+}
+
+// NewSchemaChecker builds a checker for manifest. A nil manifest uses
+// DefaultSchemaManifest.
+func NewSchemaChecker(manifest SchemaManifest) *SchemaChecker {
+	if manifest == nil {
+		manifest = DefaultSchemaManifest()
+	}
+
+	return &SchemaChecker{
+		manifest: manifest,
+		observed: make(map[string]*schemaObservation),
+		now:      time.Now,
+	}
+}
+
+// newSchemaCheckerFromConfig returns nil when drift checking is disabled. A
+// manifest that cannot be loaded is returned as an error together with a
+// checker using the built-in manifest.
+func newSchemaCheckerFromConfig(cfg config.SchemaConfig) (*SchemaChecker, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if strings.TrimSpace(cfg.ManifestPath) == "" {
+		return NewSchemaChecker(nil), nil
+	}
+
+	manifest, err := LoadSchemaManifest(cfg.ManifestPath)
+	if err != nil {
+		return NewSchemaChecker(nil), err
+	}
+	return NewSchemaChecker(manifest), nil
+}
+
+// observe records the field kinds of one raw payload of dataset.
+func (s *SchemaChecker) observe(dataset string, raw []byte) {
+	local := newSchemaObservation()
+	local.samples = 1
+
+	var fields map[string]json.RawMessage
+	if kind := jsonKind(raw); kind != schemaTypeObject {
+		local.add(schemaRootPath, kind)
+	} else if err := json.Unmarshal(raw, &fields); err != nil {
+		local.add(schemaRootPath, "invalid")
+	}
+
+	for name, value := range fields {
+		kind := jsonKind(value)
+		local.add(name, kind)
+		if kind != schemaTypeArray || !s.expectsElements(dataset, name) {
+			continue
+		}
+
+		var items []json.RawMessage
+		if err := json.Unmarshal(value, &items); err != nil {
+			continue
+		}
+		parent := name + "[]"
+		for _, item := range items {
+			var sub map[string]json.RawMessage
+			if jsonKind(item) != schemaTypeObject || json.Unmarshal(item, &sub) != nil {
+				continue
+			}
+			local.elements[parent]++
+			for subName, subValue := range sub {
+				local.add(parent+"."+subName, jsonKind(subValue))
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.observed[dataset]
+	if !ok {
+		o = newSchemaObservation()
+		s.observed[dataset] = o
+	}
+	o.samples += local.samples
+	for path, kinds := range local.fields {
+		for kind := range kinds {
+			o.add(path, kind)
+		}
+	}
+	for parent, n := range local.elements {
+		o.elements[parent] += n
+	}
+}
+
+func newSchemaObservation() *schemaObservation {
+	return &schemaObservation{
+		fields:   make(map[string]map[string]struct{}),
+		elements: make(map[string]int),
+	}
+}
+
+func (o *schemaObservation) add(path, kind string) {
+	kinds, ok := o.fields[path]
+	if !ok {
+		kinds = make(map[string]struct{})
+		o.fields[path] = kinds
+	}
+	kinds[kind] = struct{}{}
+}
+
+// expectsElements reports whether the manifest describes fields of objects
+// inside the top-level array field name.
+func (s *SchemaChecker) expectsElements(dataset, name string) bool {
+	prefix := name + "[]."
+	for path := range s.manifest[dataset] {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Report compares everything observed so far with the manifest. Datasets that
+// were never observed are left out. Null values match any expected kind since
+// decoding zero-fills them either way.
+func (s *SchemaChecker) Report() *SchemaDriftReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &SchemaDriftReport{GeneratedAt: s.now().UTC(), Datasets: []DatasetDrift{}}
+
+	datasets := make([]string, 0, len(s.observed))
+	for name := range s.observed {
+		datasets = append(datasets, name)
+	}
+	sort.Strings(datasets)
+
+	for _, name := range datasets {
+		o := s.observed[name]
+		expected := s.manifest[name]
+		drift := DatasetDrift{Dataset: name, Samples: o.samples}
+
+		for path, want := range expected {
+			kind, optional := strings.CutSuffix(want, "?")
+			if parent, _, ok := strings.Cut(path, "[]."); ok && o.elements[parent+"[]"] == 0 {
+				continue
+			}
+
+			seen, ok := o.fields[path]
+			if !ok {
+				if !optional {
+					drift.Removed = append(drift.Removed, FieldDrift{Field: path, Expected: kind})
+				}
+				continue
+			}
+			if other := otherKinds(seen, kind); other != "" {
+				drift.TypeChanged = append(drift.TypeChanged, FieldDrift{Field: path, Expected: kind, Observed: other})
+			}
+		}
+
+		for path, seen := range o.fields {
+			if _, ok := expected[path]; ok {
+				continue
+			}
+			if path == schemaRootPath {
+				drift.TypeChanged = append(drift.TypeChanged, FieldDrift{Field: path, Expected: schemaTypeObject, Observed: otherKinds(seen, schemaTypeObject)})
+				continue
+			}
+			drift.Added = append(drift.Added, FieldDrift{Field: path, Observed: otherKinds(seen, "")})
+		}
+
+		sortFieldDrift(drift.Added)
+		sortFieldDrift(drift.Removed)
+		sortFieldDrift(drift.TypeChanged)
+		report.Datasets = append(report.Datasets, drift)
+	}
+
+	return report
+}
+
+// otherKinds lists the observed kinds other than want and null, sorted and
+// joined with "|".
+func otherKinds(seen map[string]struct{}, want string) string {
+	if want == schemaTypeAny {
+		return ""
+	}
+	var kinds []string
+	for kind := range seen {
+		if kind == want || kind == schemaTypeNull {
+			continue
+		}
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, "|")
+}
+
+func sortFieldDrift(fields []FieldDrift) {
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+}
+
+// jsonKind classifies a raw JSON value by its first significant byte.
+func jsonKind(raw []byte) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return schemaTypeNull
+	}
+
+	switch raw[0] {
+	case '{':
+		return schemaTypeObject
+	case '[':
+		return schemaTypeArray
+	case '"':
+		return schemaTypeString
+	case 't', 'f':
+		return schemaTypeBool
+	case 'n':
+		return schemaTypeNull
+	default:
+		return schemaTypeNumber
+	}
+}
+
+// schemaDataset maps a decode target to the dataset it is checked as.
+func schemaDataset(target any) string {
+	switch target.(type) {
+	case *OracleAPIResponse:
+		return SchemaDatasetOracles
+	case *protocolStream:
+		return SchemaDatasetProtocols
+	case *ProtocolTVLResponse:
+		return SchemaDatasetProtocolTVL
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+func TestSchemaChecker_FixturesMatchDefaultManifest(t *testing.T) {
+	checker := NewSchemaChecker(nil)
+	checker.observe(SchemaDatasetOracles, loadFixture(t, "oracle_response.json"))
+	checker.observe(SchemaDatasetProtocolTVL, loadFixture(t, "protocol_tvl_response.json"))
+
+	stream := newProtocolStream(nil)
+	if err := stream.decodeStream(json.NewDecoder(bytes.NewReader(loadFixture(t, "protocol_response.json"))), func(raw []byte) {
+		checker.observe(SchemaDatasetProtocols, raw)
+	}); err != nil {
+		t.Fatalf("decodeStream error: %v", err)
+	}
+
+	report := checker.Report()
+	if report.HasDrift() {
+		t.Fatalf("expected no drift for fixtures, got %+v", report.Datasets)
+	}
+	if len(report.Datasets) != 3 {
+		t.Fatalf("expected 3 observed datasets, got %d", len(report.Datasets))
+	}
+	for _, d := range report.Datasets {
+		if d.Dataset == SchemaDatasetProtocols && d.Samples != 3 {
+			t.Fatalf("expected one protocol sample per entry, got %d", d.Samples)
+		}
+	}
+}
+
+func TestSchemaChecker_ReportsAddedRemovedAndTypeChanged(t *testing.T) {
+	checker := NewSchemaChecker(nil)
+	checker.observe(SchemaDatasetProtocolTVL, []byte(`{
+		"name": "Drift",
+		"tvl": [{"date": "2024-01-01", "totalLiquidityUSD": null}],
+		"tokenBreakdowns": []
+	}`))
+
+	report := checker.Report()
+	if !report.Breaking() {
+		t.Fatalf("expected breaking drift")
+	}
+
+	d := report.Datasets[0]
+	if len(d.Added) != 1 || d.Added[0].Field != "tokenBreakdowns" || d.Added[0].Observed != "array" {
+		t.Fatalf("unexpected added fields: %+v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].Field != "currentChainTvls" {
+		t.Fatalf("unexpected removed fields: %+v", d.Removed)
+	}
+	if len(d.TypeChanged) != 1 || d.TypeChanged[0] != (FieldDrift{Field: "tvl[].date", Expected: "number", Observed: "string"}) {
+		t.Fatalf("unexpected type changes: %+v", d.TypeChanged)
+	}
+}
+
+func TestSchemaChecker_OptionalAndNestedFields(t *testing.T) {
+	checker := NewSchemaChecker(SchemaManifest{
+		"sample": {
+			"items":      "array",
+			"items[].id": "string",
+			"note":       "string?",
+		},
+	})
+	checker.observe("sample", []byte(`{"items": []}`))
+
+	if report := checker.Report(); report.HasDrift() {
+		t.Fatalf("expected empty arrays and optional fields to pass, got %+v", report.Datasets)
+	}
+
+	checker.observe("sample", []byte(`[1, 2]`))
+	d := checker.Report().Datasets[0]
+	if len(d.TypeChanged) != 1 || d.TypeChanged[0].Field != schemaRootPath || d.TypeChanged[0].Observed != "array" {
+		t.Fatalf("expected root type change, got %+v", d.TypeChanged)
+	}
+}
+
+func TestSchemaChecker_KnownUnreadFieldsAreNotAdded(t *testing.T) {
+	checker := NewSchemaChecker(nil)
+	checker.observe(SchemaDatasetProtocols, []byte(`{
+		"id": "2269", "name": "Kamino Lend", "slug": "kamino-lend", "category": "Lending",
+		"tvl": 1500000000, "chains": ["Solana"], "oracles": ["Switchboard"],
+		"symbol": "KMNO", "logo": "https://icons.llama.fi/kamino.png", "url": "https://kamino.finance",
+		"chainTvls": {"Solana": {"tvl": 1500000000}}, "tvlPrevDay": 1490000000, "tvlPrevWeek": 1400000000,
+		"tvlPrevMonth": 1200000000, "mcap": null, "listedAt": 1697000000, "forkedFrom": [],
+		"defillamaId": "2269", "geckoId": "kamino", "governanceID": ["snapshot:kamino"],
+		"parentProtocol": "parent#kamino", "deprecated": false
+	}`))
+
+	d := checker.Report().Datasets[0]
+	if len(d.Added) != 0 || len(d.Removed) != 0 || len(d.TypeChanged) != 0 {
+		t.Fatalf("expected a current protocols2 entry to match the manifest, got %+v", d)
+	}
+
+	checker.observe(SchemaDatasetProtocols, []byte(`{"id": "1", "name": "A", "slug": "a", "category": "Dexs", "tvl": 1, "chains": [], "symbol": 7, "riskScore": 3}`))
+	d = checker.Report().Datasets[0]
+	if len(d.TypeChanged) != 0 || len(d.Added) != 1 || d.Added[0].Field != "riskScore" {
+		t.Fatalf("expected only the unknown field to be added, got %+v", d)
+	}
+}
+
+func TestLoadSchemaManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := os.WriteFile(path, []byte(`{"oracles": {"oracles": "object"}}`), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+
+	manifest, err := LoadSchemaManifest(path)
+	if err != nil {
+		t.Fatalf("LoadSchemaManifest error: %v", err)
+	}
+	if manifest[SchemaDatasetOracles]["oracles"] != "object" {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+
+	if _, err := newSchemaCheckerFromConfig(config.SchemaConfig{Enabled: true, ManifestPath: filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Fatalf("expected error for missing manifest")
+	}
+}
+
+func TestClient_SchemaDriftObservesNetworkBodiesOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"id": "a", "name": "A", "category": "DEX", "tvl": 1, "chains": [], "riskScore": 3}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&config.APIConfig{
+		ProtocolsURL: server.URL,
+		Timeout:      time.Second,
+		Cache:        config.CacheConfig{Directory: t.TempDir(), MaxAge: time.Hour},
+		Schema:       config.SchemaConfig{Enabled: true},
+	}, nil)
+
+	if _, err := client.FetchProtocols(context.Background()); err != nil {
+		t.Fatalf("FetchProtocols error: %v", err)
+	}
+	// Served from the fresh cache; must not add a sample.
+	if _, err := client.FetchProtocols(context.Background()); err != nil {
+		t.Fatalf("FetchProtocols (cached) error: %v", err)
+	}
+
+	report := client.SchemaDrift()
+	if report == nil || len(report.Datasets) != 1 {
+		t.Fatalf("expected one observed dataset, got %+v", report)
+	}
+	d := report.Datasets[0]
+	if d.Samples != 1 {
+		t.Fatalf("expected cached listing to be skipped, got %d samples", d.Samples)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].Field != "slug" {
+		t.Fatalf("expected slug removal, got %+v", d.Removed)
+	}
+	if len(d.Added) != 1 || d.Added[0].Field != "riskScore" {
+		t.Fatalf("expected riskScore addition, got %+v", d.Added)
+	}
+
+	if disabled := NewClient(&config.APIConfig{Timeout: time.Second}, nil); disabled.SchemaDrift() != nil {
+		t.Fatalf("expected nil report when schema checks are disabled")
+	}
+}
+
+func TestClient_SchemaDriftRecordedWhenDecodeFails(t *testing.T) {
+	prevInterval := oraclesMinInterval
+	oraclesMinInterval = 0
+	t.Cleanup(func() { oraclesMinInterval = prevInterval })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"oracles": [], "chart": {}, "oraclesTVS": {}, "chainsByOracle": {}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&config.APIConfig{
+		OraclesURL: server.URL,
+		Timeout:    time.Second,
+		Cache:      config.CacheConfig{Directory: t.TempDir()},
+		Schema:     config.SchemaConfig{Enabled: true},
+	}, nil)
+
+	if _, err := client.FetchOracles(context.Background()); err == nil {
+		t.Fatalf("expected decode error for retyped field")
+	}
+
+	report := client.SchemaDrift()
+	if !report.Breaking() {
+		t.Fatalf("expected breaking drift, got %+v", report)
+	}
+	if tc := report.Datasets[0].TypeChanged; len(tc) != 1 || tc[0] != (FieldDrift{Field: "oracles", Expected: "object", Observed: "array"}) {
+		t.Fatalf("unexpected type changes: %+v", tc)
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errNoChainTVL is returned by SharedSource when the wrapped source cannot
+// serve chain TVL.
+var errNoChainTVL = errors.New("source does not provide chain TVL")
+
+// SharedSource wraps a Source so several consumers in one cycle (one per
+// oracle in a multi-oracle run) share a single fetch of every dataset. The
+// first call for a dataset starts it; concurrent and later calls wait for and
+// reuse that result, including its error. The fetch runs detached from any
+// one caller's cancellation and each caller stops waiting when its own
+// context ends; only when every waiter has gone is the fetch cancelled and
+// forgotten, so the next call starts afresh. A SharedSource is meant to live
+// for one cycle; create a new one to fetch again.
+type SharedSource struct {
+	source Source
+
+	mu    sync.Mutex
+	calls map[string]*sharedCall
+}
+
+type sharedCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	val     any
+	err     error
+}
+
+// NewSharedSource returns a SharedSource over source.
+func NewSharedSource(source Source) *SharedSource {
+	return &SharedSource{source: source, calls: make(map[string]*sharedCall)}
+}
+
+// do runs fn once for key and returns its result to every caller. fn gets a
+// context that keeps ctx's values but not its cancellation.
+func (s *SharedSource) do(ctx context.Context, key string, fn func(context.Context) (any, error)) (any, error) {
+	s.mu.Lock()
+	call, ok := s.calls[key]
+	if !ok {
+		fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &sharedCall{done: make(chan struct{}), cancel: cancel}
+		s.calls[key] = call
+		go func() {
+			defer cancel()
+			call.val, call.err = fn(fetchCtx)
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done():
+		s.leave(key, call)
+		return nil, ctx.Err()
+	}
+}
+
+// leave drops a waiter that stopped early, cancelling the fetch when it was
+// the last one and the fetch is still running.
+func (s *SharedSource) leave(key string, call *sharedCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+	select {
+	case <-call.done:
+	default:
+		call.cancel()
+		if s.calls[key] == call {
+			delete(s.calls, key)
+		}
+	}
+}
+
+// FetchAll implements Source.
+func (s *SharedSource) FetchAll(ctx context.Context) (*FetchResult, error) {
+	val, err := s.do(ctx, "all", func(ctx context.Context) (any, error) { return s.source.FetchAll(ctx) })
+	result, _ := val.(*FetchResult)
+	return result, err
+}
+
+// FetchOracles implements OracleSource.
+func (s *SharedSource) FetchOracles(ctx context.Context) (*OracleAPIResponse, error) {
+	val, err := s.do(ctx, "oracles", func(ctx context.Context) (any, error) { return s.source.FetchOracles(ctx) })
+	resp, _ := val.(*OracleAPIResponse)
+	return resp, err
+}
+
+// FetchProtocols implements ProtocolSource.
+func (s *SharedSource) FetchProtocols(ctx context.Context) ([]Protocol, error) {
+	val, err := s.do(ctx, "protocols", func(ctx context.Context) (any, error) { return s.source.FetchProtocols(ctx) })
+	protocols, _ := val.([]Protocol)
+	return protocols, err
+}
+
+// FetchProtocolTVL implements ProtocolTVLSource.
+func (s *SharedSource) FetchProtocolTVL(ctx context.Context, slug string) (*ProtocolTVLResponse, error) {
+	val, err := s.do(ctx, "protocol/"+slug, func(ctx context.Context) (any, error) { return s.source.FetchProtocolTVL(ctx, slug) })
+	resp, _ := val.(*ProtocolTVLResponse)
+	return resp, err
+}
+
+// FetchChains implements ChainTVLSource.
+func (s *SharedSource) FetchChains(ctx context.Context) ([]ChainInfo, error) {
+	chains, ok := s.source.(ChainTVLSource)
+	if !ok {
+		return nil, errNoChainTVL
+	}
+	val, err := s.do(ctx, "chains", func(ctx context.Context) (any, error) { return chains.FetchChains(ctx) })
+	result, _ := val.([]ChainInfo)
+	return result, err
+}
+
+// FetchHistoricalChainTVL implements ChainTVLSource.
+func (s *SharedSource) FetchHistoricalChainTVL(ctx context.Context, chain string) ([]ChainTVLPoint, error) {
+	chains, ok := s.source.(ChainTVLSource)
+	if !ok {
+		return nil, errNoChainTVL
+	}
+	val, err := s.do(ctx, "chains/"+chain, func(ctx context.Context) (any, error) { return chains.FetchHistoricalChainTVL(ctx, chain) })
+	points, _ := val.([]ChainTVLPoint)
+	return points, err
+}
+
+// SchemaDrift returns the wrapped source's drift report, or nil when it does
+// not check schemas.
+func (s *SharedSource) SchemaDrift() *SchemaDriftReport {
+	if reporter, ok := s.source.(interface{ SchemaDrift() *SchemaDriftReport }); ok {
+		return reporter.SchemaDrift()
+	}
+	return nil
+}
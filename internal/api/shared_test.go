@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingSource counts calls per dataset and returns canned data.
+type countingSource struct {
+	fetchAll    atomic.Int32
+	protocolTVL atomic.Int32
+	chainTVL    atomic.Int32
+}
+
+func (s *countingSource) FetchAll(ctx context.Context) (*FetchResult, error) {
+	s.fetchAll.Add(1)
+	return &FetchResult{Protocols: []Protocol{{Slug: "kamino"}}}, nil
+}
+
+func (s *countingSource) FetchOracles(ctx context.Context) (*OracleAPIResponse, error) {
+	return &OracleAPIResponse{}, nil
+}
+
+func (s *countingSource) FetchProtocols(ctx context.Context) ([]Protocol, error) {
+	return nil, nil
+}
+
+func (s *countingSource) FetchProtocolTVL(ctx context.Context, slug string) (*ProtocolTVLResponse, error) {
+	s.protocolTVL.Add(1)
+	if slug == "missing" {
+		return nil, errors.New("boom")
+	}
+	return &ProtocolTVLResponse{Name: slug}, nil
+}
+
+func (s *countingSource) FetchChains(ctx context.Context) ([]ChainInfo, error) {
+	return []ChainInfo{{Name: "Solana"}}, nil
+}
+
+func (s *countingSource) FetchHistoricalChainTVL(ctx context.Context, chain string) ([]ChainTVLPoint, error) {
+	s.chainTVL.Add(1)
+	return []ChainTVLPoint{{Date: 1, TVL: 2}}, nil
+}
+
+func TestSharedSource_FetchesEachDatasetOnce(t *testing.T) {
+	src := &countingSource{}
+	shared := NewSharedSource(src)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if result, err := shared.FetchAll(ctx); err != nil || len(result.Protocols) != 1 {
+				t.Errorf("FetchAll = %+v, %v", result, err)
+			}
+			if resp, err := shared.FetchProtocolTVL(ctx, "kamino"); err != nil || resp.Name != "kamino" {
+				t.Errorf("FetchProtocolTVL = %+v, %v", resp, err)
+			}
+			if _, err := shared.FetchHistoricalChainTVL(ctx, "Solana"); err != nil {
+				t.Errorf("FetchHistoricalChainTVL error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if src.fetchAll.Load() != 1 || src.protocolTVL.Load() != 1 || src.chainTVL.Load() != 1 {
+		t.Fatalf("expected one upstream call per dataset, got all=%d tvl=%d chain=%d",
+			src.fetchAll.Load(), src.protocolTVL.Load(), src.chainTVL.Load())
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := shared.FetchProtocolTVL(ctx, "missing"); err == nil {
+			t.Fatalf("expected shared error for missing slug")
+		}
+	}
+	if src.protocolTVL.Load() != 2 {
+		t.Fatalf("expected errors to be shared too, got %d calls", src.protocolTVL.Load())
+	}
+}
+
+func TestSharedSource_WithoutChainTVL(t *testing.T) {
+	shared := NewSharedSource(NewDirectorySource(t.TempDir(), nil))
+	if _, err := shared.FetchChains(context.Background()); err == nil {
+		t.Fatalf("expected error for missing chains cassette")
+	}
+
+	var src Source = struct{ Source }{&countingSource{}}
+	if _, err := NewSharedSource(src).FetchChains(context.Background()); !errors.Is(err, errNoChainTVL) {
+		t.Fatalf("expected errNoChainTVL, got %v", err)
+	}
+}
+
+// blockingSource holds FetchOracles until release is closed or its context
+// ends, reporting how each call finished.
+type blockingSource struct {
+	countingSource
+	started  chan struct{}
+	release  chan struct{}
+	finished chan error
+	calls    atomic.Int32
+}
+
+func (s *blockingSource) FetchOracles(ctx context.Context) (*OracleAPIResponse, error) {
+	s.calls.Add(1)
+	s.started <- struct{}{}
+	select {
+	case <-s.release:
+		s.finished <- nil
+		return &OracleAPIResponse{Oracles: map[string][]string{"Switchboard": nil}}, nil
+	case <-ctx.Done():
+		s.finished <- ctx.Err()
+		return nil, ctx.Err()
+	}
+}
+
+func waitForWaiters(t *testing.T, shared *SharedSource, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		shared.mu.Lock()
+		call := shared.calls[key]
+		waiting := call != nil && call.waiters == n
+		shared.mu.Unlock()
+		if waiting {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d waiters on %s", n, key)
+}
+
+func newBlockingSource() *blockingSource {
+	return &blockingSource{started: make(chan struct{}, 2), release: make(chan struct{}), finished: make(chan error, 2)}
+}
+
+func TestSharedSource_CallerCancelDoesNotFailOtherWaiters(t *testing.T) {
+	src := newBlockingSource()
+	shared := NewSharedSource(src)
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := shared.FetchOracles(firstCtx)
+		firstErr <- err
+	}()
+	<-src.started
+
+	secondResult := make(chan *OracleAPIResponse, 1)
+	go func() {
+		resp, err := shared.FetchOracles(context.Background())
+		if err != nil {
+			t.Errorf("second caller error: %v", err)
+		}
+		secondResult <- resp
+	}()
+
+	waitForWaiters(t, shared, "oracles", 2)
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected first caller to stop on its own cancellation, got %v", err)
+	}
+
+	close(src.release)
+	if resp := <-secondResult; resp == nil || len(resp.Oracles) != 1 {
+		t.Fatalf("expected second caller to get the shared result, got %+v", resp)
+	}
+	if err := <-src.finished; err != nil {
+		t.Fatalf("expected shared fetch to complete, got %v", err)
+	}
+	if src.calls.Load() != 1 {
+		t.Fatalf("expected one upstream call, got %d", src.calls.Load())
+	}
+}
+
+func TestSharedSource_LastWaiterCancelStopsFetch(t *testing.T) {
+	src := newBlockingSource()
+	shared := NewSharedSource(src)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := shared.FetchOracles(ctx)
+		errCh <- err
+	}()
+	<-src.started
+	cancel()
+
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected caller cancellation, got %v", err)
+	}
+	if err := <-src.finished; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected abandoned fetch to be cancelled, got %v", err)
+	}
+
+	close(src.release)
+	go func() { <-src.started }()
+	if resp, err := shared.FetchOracles(context.Background()); err != nil || resp == nil {
+		t.Fatalf("expected a fresh fetch after the abandoned one, got %+v, %v", resp, err)
+	}
+	if src.calls.Load() != 2 {
+		t.Fatalf("expected the dataset to be fetched again, got %d calls", src.calls.Load())
+	}
+}
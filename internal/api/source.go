@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+	"golang.org/x/sync/errgroup"
+)
+
+// OracleSource provides the oracle listing normally served by GET /oracles.
+type OracleSource interface {
+	FetchOracles(ctx context.Context) (*OracleAPIResponse, error)
+}
+
+// ProtocolSource provides the protocol listing normally served by GET /lite/protocols2.
+type ProtocolSource interface {
+	FetchProtocols(ctx context.Context) ([]Protocol, error)
+}
+
+// ProtocolTVLSource provides per-protocol TVL history normally served by
+// GET /protocol/{slug}. Implementations return (nil, nil) when the protocol
+// does not exist.
+type ProtocolTVLSource interface {
+	FetchProtocolTVL(ctx context.Context, slug string) (*ProtocolTVLResponse, error)
+}
+
+// ChainTVLSource provides chain-level TVL normally served by GET /v2/chains
+// and GET /v2/historicalChainTvl/{chain}. FetchHistoricalChainTVL returns
+// (nil, nil) when the chain does not exist.
+type ChainTVLSource interface {
+	FetchChains(ctx context.Context) ([]ChainInfo, error)
+	FetchHistoricalChainTVL(ctx context.Context, chain string) ([]ChainTVLPoint, error)
+}
+
+// OracleFilterable is implemented by sources that can drop protocols not
+// using the configured oracles while decoding the protocol listing.
+type OracleFilterable interface {
+	SetOracleFilter(names ...string)
+}
+
+// Source bundles every dataset the extractor consumes. Client, DirectorySource
+// and FixtureSource all satisfy it.
+type Source interface {
+	OracleSource
+	ProtocolSource
+	ProtocolTVLSource
+	FetchAll(ctx context.Context) (*FetchResult, error)
+}
+
+// Source types accepted in the `source.type` configuration field.
+const (
+	SourceTypeLive      = "live"
+	SourceTypeDirectory = "directory"
+	SourceTypeFixture   = "fixture"
+)
+
+// NewSource builds the Source selected by cfg. Live sources talk to the
+// configured HTTP endpoints; directory sources read a cassette-layout folder;
+// fixture sources serve that folder over a local HTTP server and reuse the
+// live client against it. Callers should Close sources implementing io.Closer.
+//
+// oracleNames, when non-empty, limits the protocol listing to protocols using
+// one of those oracles.
+func NewSource(cfg config.SourceConfig, apiCfg *config.APIConfig, oracleNames []string, logger *slog.Logger) (Source, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	var (
+		source Source
+		err    error
+	)
+	switch strings.ToLower(strings.TrimSpace(cfg.Type)) {
+	case "", SourceTypeLive:
+		source = NewClient(apiCfg, logger)
+	case SourceTypeDirectory:
+		source = NewDirectorySource(cfg.Directory, logger)
+	case SourceTypeFixture:
+		source, err = NewFixtureSource(cfg.Directory, cfg.Address, apiCfg, logger)
+	default:
+		return nil, fmt.Errorf("unknown source type %q", cfg.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if f, ok := source.(OracleFilterable); ok && len(oracleNames) > 0 {
+		f.SetOracleFilter(oracleNames...)
+	}
+
+	return source, nil
+}
+
+// FetchAll retrieves oracle and protocol data concurrently from the given
+// sources using errgroup, logging per-dataset durations.
+func FetchAll(ctx context.Context, oracles OracleSource, protocols ProtocolSource, logger *slog.Logger) (*FetchResult, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	start := time.Now()
+	var (
+		oracleResp       *OracleAPIResponse
+		protocolList     []Protocol
+		oracleDuration   time.Duration
+		protocolDuration time.Duration
+	)
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		fetchStart := time.Now()
+		resp, err := oracles.FetchOracles(ctx)
+		oracleDuration = time.Since(fetchStart)
+		if err != nil {
+			return err
+		}
+		oracleResp = resp
+		return nil
+	})
+
+	g.Go(func() error {
+		fetchStart := time.Now()
+		resp, err := protocols.FetchProtocols(ctx)
+		protocolDuration = time.Since(fetchStart)
+		if err != nil {
+			return err
+		}
+		protocolList = resp
+		return nil
+	})
+
+	if err := g.Wait(); err != nil {
+		total := time.Since(start)
+		logger.Error("parallel fetch failed",
+			"error", err,
+			"total_duration_ms", total.Milliseconds(),
+		)
+		return nil, err
+	}
+
+	total := time.Since(start)
+	logger.Info("parallel fetch completed",
+		"oracle_duration_ms", oracleDuration.Milliseconds(),
+		"protocol_duration_ms", protocolDuration.Milliseconds(),
+		"total_duration_ms", total.Milliseconds(),
+	)
+
+	return &FetchResult{
+		OracleResponse: oracleResp,
+		Protocols:      protocolList,
+	}, nil
+}
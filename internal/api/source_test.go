@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+func writeSourceFixtures(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"oracles.json":              "oracle_response.json",
+		"lite/protocols2.json":      "protocol_response.json",
+		"protocol/kamino-lend.json": "protocol_tvl_response.json",
+	}
+	for dst, fixture := range files {
+		path := filepath.Join(dir, filepath.FromSlash(dst))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, loadFixture(t, fixture), 0o644); err != nil {
+			t.Fatalf("write fixture: %v", err)
+		}
+	}
+
+	return dir
+}
+
+func TestNewSource_SelectsImplementation(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	apiCfg := &config.APIConfig{Timeout: time.Second}
+
+	live, err := NewSource(config.SourceConfig{Type: "live"}, apiCfg, nil, logger)
+	if err != nil {
+		t.Fatalf("live source error: %v", err)
+	}
+	if _, ok := live.(*Client); !ok {
+		t.Fatalf("expected *Client for live source, got %T", live)
+	}
+
+	dir, err := NewSource(config.SourceConfig{Type: "directory", Directory: t.TempDir()}, apiCfg, nil, logger)
+	if err != nil {
+		t.Fatalf("directory source error: %v", err)
+	}
+	if _, ok := dir.(*DirectorySource); !ok {
+		t.Fatalf("expected *DirectorySource, got %T", dir)
+	}
+
+	if _, err := NewSource(config.SourceConfig{Type: "s3"}, apiCfg, nil, logger); err == nil {
+		t.Fatalf("expected error for unknown source type")
+	}
+}
+
+func TestDirectorySource_ReadsCassetteLayout(t *testing.T) {
+	src := NewDirectorySource(writeSourceFixtures(t), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	result, err := src.FetchAll(context.Background())
+	if err != nil {
+		t.Fatalf("FetchAll error: %v", err)
+	}
+	if result.OracleResponse == nil || len(result.OracleResponse.Oracles) == 0 {
+		t.Fatalf("expected oracles from directory source")
+	}
+	if len(result.Protocols) == 0 {
+		t.Fatalf("expected protocols from directory source")
+	}
+
+	tvl, err := src.FetchProtocolTVL(context.Background(), "kamino-lend")
+	if err != nil || tvl == nil {
+		t.Fatalf("expected TVL for kamino-lend, got %v, %v", tvl, err)
+	}
+
+	missing, err := src.FetchProtocolTVL(context.Background(), "unknown")
+	if err != nil || missing != nil {
+		t.Fatalf("expected (nil, nil) for missing protocol, got %v, %v", missing, err)
+	}
+}
+
+func TestFixtureSource_ServesDirectoryOverHTTP(t *testing.T) {
+	prevInterval := oraclesMinInterval
+	oraclesMinInterval = 0
+	t.Cleanup(func() { oraclesMinInterval = prevInterval })
+
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	liveCache := t.TempDir()
+	breakerFile := filepath.Join(t.TempDir(), "breaker-state.json")
+	apiCfg := &config.APIConfig{
+		Timeout: 2 * time.Second,
+		Cache:   config.CacheConfig{Directory: liveCache, MaxAge: time.Hour},
+		Breaker: config.BreakerConfig{Enabled: true, FailureThreshold: 1, OpenDuration: time.Hour, HalfOpenSuccesses: 1, StateFile: breakerFile},
+	}
+
+	src, err := NewFixtureSource(writeSourceFixtures(t), "", apiCfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewFixtureSource error: %v", err)
+	}
+	closed := false
+	t.Cleanup(func() {
+		if !closed {
+			_ = src.Close()
+		}
+	})
+
+	result, err := src.FetchAll(context.Background())
+	if err != nil {
+		t.Fatalf("FetchAll error: %v", err)
+	}
+	if len(result.Protocols) == 0 || len(result.OracleResponse.Oracles) == 0 {
+		t.Fatalf("expected fixture server to serve oracles and protocols")
+	}
+
+	tvl, err := src.FetchProtocolTVL(context.Background(), "kamino-lend")
+	if err != nil || tvl == nil {
+		t.Fatalf("expected TVL from fixture server, got %v, %v", tvl, err)
+	}
+
+	// Fixture data must stay out of the configured cache and breaker state.
+	if entries, _ := os.ReadDir(liveCache); len(entries) != 0 {
+		t.Errorf("fixture run wrote %d entries into the live cache", len(entries))
+	}
+	if _, err := os.Stat(breakerFile); !os.IsNotExist(err) {
+		t.Errorf("fixture run wrote breaker state: %v", err)
+	}
+	cacheDir := src.cache.Dir()
+	if filepath.Dir(cacheDir) != tmp {
+		t.Errorf("fixture cache dir = %s, want a directory under %s", cacheDir, tmp)
+	}
+
+	closed = true
+	if err := src.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Errorf("fixture cache dir not removed on Close: %v", err)
+	}
+}
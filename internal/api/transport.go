@@ -0,0 +1,213 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+	"golang.org/x/net/proxy"
+)
+
+// Transports accepted in the `api.transport` configuration field.
+const (
+	TransportBrowser  = "browser"
+	TransportStandard = "standard"
+)
+
+// transportOptions carries the outbound proxy and TLS trust settings shared by
+// both transports.
+type transportOptions struct {
+	proxy      *url.URL
+	rootCAs    *x509.CertPool
+	clientCert *tls.Certificate
+}
+
+// loadTransportOptions parses the proxy URL and reads the CA bundle and client
+// key pair named in cfg. The CA bundle is added to the system roots so public
+// hosts stay reachable.
+func loadTransportOptions(cfg *config.APIConfig) (transportOptions, error) {
+	var opts transportOptions
+
+	if raw := strings.TrimSpace(cfg.ProxyURL); raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return opts, fmt.Errorf("parse proxy url: %w", err)
+		}
+		opts.proxy = u
+	}
+
+	if path := strings.TrimSpace(cfg.CABundlePath); path != "" {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return opts, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return opts, fmt.Errorf("CA bundle %s contains no PEM certificates", path)
+		}
+		opts.rootCAs = pool
+	}
+
+	if cfg.ClientCertPath != "" || cfg.ClientKeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertPath, cfg.ClientKeyPath)
+		if err != nil {
+			return opts, fmt.Errorf("load client certificate: %w", err)
+		}
+		opts.clientCert = &cert
+	}
+
+	return opts, nil
+}
+
+// tlsConfig returns the crypto/tls settings for these options.
+func (o transportOptions) tlsConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		MaxVersion: tls.VersionTLS13,
+		RootCAs:    o.rootCAs,
+	}
+	if o.clientCert != nil {
+		cfg.Certificates = []tls.Certificate{*o.clientCert}
+	}
+	return cfg
+}
+
+// NewTransport builds the round tripper selected by cfg.Transport (browser
+// when empty) with the configured proxy, CA bundle and client certificate.
+func NewTransport(cfg *config.APIConfig) (http.RoundTripper, error) {
+	opts, err := loadTransportOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(cfg.Transport)) {
+	case "", TransportBrowser:
+		return newUtlsRoundTripper(opts), nil
+	case TransportStandard:
+		t := NewStandardTransport()
+		t.TLSClientConfig = opts.tlsConfig()
+		if opts.proxy != nil {
+			t.Proxy = http.ProxyURL(opts.proxy)
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unknown transport %q", cfg.Transport)
+	}
+}
+
+// redactURL hides any password in raw for logging.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "invalid"
+	}
+	return u.Redacted()
+}
+
+// errTransport fails every request with the error that prevented the
+// configured transport from being built, so a bad CA bundle or key pair
+// surfaces on each fetch instead of silently dropping the setting.
+type errTransport struct {
+	err error
+}
+
+func (t errTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("transport unavailable: %w", t.err)
+}
+
+// dialThroughProxy connects to addr directly, through a SOCKS5 proxy, or
+// through an HTTP(S) proxy using CONNECT.
+func dialThroughProxy(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, rootCAs *x509.CertPool, network, addr string) (net.Conn, error) {
+	if proxyURL == nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		d, err := proxy.FromURL(proxyURL, dialer)
+		if err != nil {
+			return nil, fmt.Errorf("socks5 proxy: %w", err)
+		}
+		if cd, ok := d.(proxy.ContextDialer); ok {
+			return cd.DialContext(ctx, network, addr)
+		}
+		return d.Dial(network, addr)
+	default:
+		return dialConnect(ctx, dialer, proxyURL, rootCAs, addr)
+	}
+}
+
+// dialConnect opens a CONNECT tunnel to addr through an HTTP or HTTPS proxy.
+func dialConnect(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, rootCAs *x509.CertPool, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("dial proxy %s: %w", proxyURL.Redacted(), err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(dialer.Timeout))
+	}
+
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname(), RootCAs: rootCAs, MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy TLS handshake: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT %s: %w", addr, err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT %s: %w", addr, err)
+	}
+	// The body of a successful CONNECT response is the tunnel itself, so it
+	// is deliberately left unread.
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT %s: %s", addr, resp.Status)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+// testPKI is a throwaway CA with a server certificate for 127.0.0.1 and a
+// client certificate, written to PEM files for the transport settings.
+type testPKI struct {
+	caPath         string
+	clientCertPath string
+	clientKeyPath  string
+	pool           *x509.CertPool
+	server         tls.Certificate
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+
+	dir := t.TempDir()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage, ips []net.IP) ([]byte, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("generate key: %v", err)
+		}
+		der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "test"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  ips,
+		}, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatalf("issue certificate: %v", err)
+		}
+		return der, key
+	}
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return path
+	}
+	keyDER := func(key *ecdsa.PrivateKey) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("marshal key: %v", err)
+		}
+		return der
+	}
+
+	serverDER, serverKey := issue(2, x509.ExtKeyUsageServerAuth, []net.IP{net.ParseIP("127.0.0.1")})
+	clientDER, clientKey := issue(3, x509.ExtKeyUsageClientAuth, nil)
+
+	pki := testPKI{
+		caPath:         writePEM("ca.pem", "CERTIFICATE", caDER),
+		clientCertPath: writePEM("client.pem", "CERTIFICATE", clientDER),
+		clientKeyPath:  writePEM("client-key.pem", "PRIVATE KEY", keyDER(clientKey)),
+		pool:           x509.NewCertPool(),
+		server:         tls.Certificate{Certificate: [][]byte{serverDER}, PrivateKey: serverKey},
+	}
+	pki.pool.AddCert(caCert)
+	return pki
+}
+
+// newMTLSServer starts an HTTP/2 TLS server that requires a client
+// certificate issued by the test CA.
+func newMTLSServer(t *testing.T, pki testPKI) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok " + r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.EnableHTTP2 = true
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pki.server},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pki.pool,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newConnectProxy starts an HTTP proxy that only tunnels CONNECT requests and
+// counts them.
+func newConnectProxy(t *testing.T, tunnels *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		tunnels.Add(1)
+		w.WriteHeader(http.StatusOK)
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		go func() {
+			_, _ = io.Copy(upstream, buf)
+			upstream.Close()
+		}()
+		_, _ = io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+func TestNewTransport_ProxyCABundleAndClientCert(t *testing.T) {
+	pki := newTestPKI(t)
+	server := newMTLSServer(t, pki)
+
+	for _, transport := range []string{TransportBrowser, TransportStandard} {
+		t.Run(transport, func(t *testing.T) {
+			var tunnels atomic.Int32
+			proxy := newConnectProxy(t, &tunnels)
+
+			rt, err := NewTransport(&config.APIConfig{
+				Transport:      transport,
+				ProxyURL:       proxy.URL,
+				CABundlePath:   pki.caPath,
+				ClientCertPath: pki.clientCertPath,
+				ClientKeyPath:  pki.clientKeyPath,
+			})
+			if err != nil {
+				t.Fatalf("NewTransport error: %v", err)
+			}
+
+			resp, err := (&http.Client{Transport: rt, Timeout: 5 * time.Second}).Get(server.URL)
+			if err != nil {
+				t.Fatalf("GET through proxy: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if string(body) != "ok test" {
+				t.Fatalf("unexpected body %q", body)
+			}
+			if tunnels.Load() != 1 {
+				t.Fatalf("expected one CONNECT tunnel, got %d", tunnels.Load())
+			}
+		})
+	}
+}
+
+func TestNewTransport_RejectsUntrustedServerAndMissingClientCert(t *testing.T) {
+	pki := newTestPKI(t)
+	server := newMTLSServer(t, pki)
+
+	for _, transport := range []string{TransportBrowser, TransportStandard} {
+		untrusted, err := NewTransport(&config.APIConfig{Transport: transport})
+		if err != nil {
+			t.Fatalf("NewTransport error: %v", err)
+		}
+		if _, err := (&http.Client{Transport: untrusted, Timeout: 5 * time.Second}).Get(server.URL); err == nil {
+			t.Fatalf("%s: expected certificate verification error without CA bundle", transport)
+		}
+
+		noCert, err := NewTransport(&config.APIConfig{Transport: transport, CABundlePath: pki.caPath})
+		if err != nil {
+			t.Fatalf("NewTransport error: %v", err)
+		}
+		if resp, err := (&http.Client{Transport: noCert, Timeout: 5 * time.Second}).Get(server.URL); err == nil {
+			resp.Body.Close()
+			t.Fatalf("%s: expected handshake failure without client certificate", transport)
+		}
+	}
+}
+
+func TestNewClient_InvalidTransportSettingsFailRequests(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	client := NewClient(&config.APIConfig{Timeout: time.Second, CABundlePath: missing}, nil)
+
+	_, err := client.httpClient.Get("https://127.0.0.1:1/")
+	if err == nil || !strings.Contains(err.Error(), "read CA bundle") {
+		t.Fatalf("expected CA bundle error on request, got %v", err)
+	}
+
+	if _, err := NewTransport(&config.APIConfig{Transport: "curl"}); err == nil {
+		t.Fatalf("expected error for unknown transport")
+	}
+}
@@ -30,6 +30,8 @@ func newTestTVLClient(t *testing.T, template string, retries int) *Client {
 	logger := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	client := NewClient(cfg, logger)
 	client.protocolTVLEndpointTemplate = template
+	// An empty per-test cache keeps error tests from falling back to stale files.
+	withCacheDir(client, t.TempDir())
 
 	return client
 }
@@ -89,6 +91,29 @@ func TestFetchProtocolTVL_Success(t *testing.T) {
 	if resp.CurrentChainTvls["Solana"] != 677000000 {
 		t.Fatalf("unexpected chain tvl: %v", resp.CurrentChainTvls)
 	}
+
+	if solana := resp.ChainTvls["Solana"]; len(solana.TVL) != 2 || solana.TVL[1].TotalLiquidityUSD != 155000000 {
+		t.Fatalf("unexpected Solana chain series: %+v", solana)
+	}
+}
+
+func TestSplitChainTVLKey(t *testing.T) {
+	tests := []struct {
+		key, chain, extra string
+	}{
+		{"Solana", "Solana", ""},
+		{"Solana-borrowed", "Solana", "borrowed"},
+		{"Ethereum-pool2", "Ethereum", "pool2"},
+		{"staking", "", "staking"},
+		{"Polygon zkEVM", "Polygon zkEVM", ""},
+		{"X-Layer", "X-Layer", ""},
+	}
+	for _, tt := range tests {
+		chain, extra := SplitChainTVLKey(tt.key)
+		if chain != tt.chain || extra != tt.extra {
+			t.Errorf("SplitChainTVLKey(%q) = (%q, %q), want (%q, %q)", tt.key, chain, extra, tt.chain, tt.extra)
+		}
+	}
 }
 
 func TestFetchProtocolTVL_NotFound(t *testing.T) {
@@ -122,11 +147,6 @@ func TestFetchProtocolTVL_NotFound(t *testing.T) {
 }
 
 func TestFetchProtocolTVL_ServerErrorRetries(t *testing.T) {
-	// Override cache dir to ensure no fallback during error tests
-	origCacheDir := protocolTVLCacheDir
-	protocolTVLCacheDir = filepath.Join(t.TempDir(), "nonexistent")
-	t.Cleanup(func() { protocolTVLCacheDir = origCacheDir })
-
 	attempts := 0
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -153,11 +173,6 @@ func TestFetchProtocolTVL_ServerErrorRetries(t *testing.T) {
 }
 
 func TestFetchProtocolTVL_InvalidJSON(t *testing.T) {
-	// Override cache dir to ensure no fallback during error tests
-	origCacheDir := protocolTVLCacheDir
-	protocolTVLCacheDir = filepath.Join(t.TempDir(), "nonexistent")
-	t.Cleanup(func() { protocolTVLCacheDir = origCacheDir })
-
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte(`{"name":`))
@@ -241,19 +256,6 @@ func TestFetchProtocolTVL_RateLimiting(t *testing.T) {
 }
 
 func TestFetchProtocolTVL_FallbackCache(t *testing.T) {
-	// Set up temp cache directory with a valid cache file
-	tmpDir := t.TempDir()
-	origCacheDir := protocolTVLCacheDir
-	protocolTVLCacheDir = tmpDir
-	t.Cleanup(func() { protocolTVLCacheDir = origCacheDir })
-
-	// Write cache file for slug "cached-proto"
-	cacheData := `{"name":"Cached Protocol","tvl":[{"date":1704067200,"totalLiquidityUSD":12345.67}],"currentChainTvls":{"Ethereum":12345.67}}`
-	cachePath := filepath.Join(tmpDir, "cached-proto.json")
-	if err := os.WriteFile(cachePath, []byte(cacheData), 0o644); err != nil {
-		t.Fatalf("failed to write cache: %v", err)
-	}
-
 	// Server returns error
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -262,6 +264,16 @@ func TestFetchProtocolTVL_FallbackCache(t *testing.T) {
 
 	client := newTestTVLClient(t, server.URL+"/%s", 0)
 
+	// Write cache file for slug "cached-proto"
+	cacheData := `{"name":"Cached Protocol","tvl":[{"date":1704067200,"totalLiquidityUSD":12345.67}],"currentChainTvls":{"Ethereum":12345.67}}`
+	cachePath := client.cache.Path(protocolTVLCacheKey("cached-proto"))
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0o755); err != nil {
+		t.Fatalf("failed to create cache dir: %v", err)
+	}
+	if err := os.WriteFile(cachePath, []byte(cacheData), 0o644); err != nil {
+		t.Fatalf("failed to write cache: %v", err)
+	}
+
 	resp, err := client.FetchProtocolTVL(context.Background(), "cached-proto")
 	if err != nil {
 		t.Fatalf("expected fallback to cache, got error: %v", err)
@@ -275,3 +287,26 @@ func TestFetchProtocolTVL_FallbackCache(t *testing.T) {
 		t.Fatalf("expected cached TVL data, got %+v", resp.TVL)
 	}
 }
+
+func TestFetchProtocolTVL_FreshCacheSkipsRequest(t *testing.T) {
+	fixture := loadFixture(t, "protocol_tvl_response.json")
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write(fixture)
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestTVLClient(t, server.URL+"/%s", 0)
+	client.cache = NewCache(config.CacheConfig{Directory: t.TempDir(), MaxAge: time.Hour}, client.logger)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.FetchProtocolTVL(context.Background(), "kamino-lend")
+		if err != nil || resp == nil || len(resp.TVL) == 0 {
+			t.Fatalf("fetch %d: expected TVL, got %+v, %v", i, resp, err)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expected the second fetch to be served from the fresh cache, got %d requests", requests)
+	}
+}
@@ -0,0 +1,3 @@
+// Package chainregistry canonicalizes chain names and aliases across the
+// DefiLlama datasets and configuration.
+package chainregistry
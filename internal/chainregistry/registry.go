@@ -0,0 +1,213 @@
+package chainregistry
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+// defaultChains are the chains known without configuration. Names are
+// DefiLlama's, so canonical names match /v2/chains and chainTvls keys.
+var defaultChains = []config.ChainDefinition{
+	{Name: "Ethereum", ChainID: 1, Aliases: []string{"eth", "mainnet", "ethereum mainnet"}},
+	{Name: "BSC", DisplayName: "BNB Chain", ChainID: 56, Aliases: []string{"bnb", "bnb chain", "bnb smart chain", "binance", "binance smart chain"}},
+	{Name: "Polygon", ChainID: 137, Aliases: []string{"matic", "polygon pos"}},
+	{Name: "Polygon zkEVM", ChainID: 1101},
+	{Name: "Arbitrum", ChainID: 42161, Aliases: []string{"arbitrum one", "arb"}},
+	{Name: "Optimism", DisplayName: "OP Mainnet", ChainID: 10, Aliases: []string{"op", "op mainnet"}},
+	{Name: "Avalanche", ChainID: 43114, Aliases: []string{"avax", "avalanche c-chain"}},
+	{Name: "Base", ChainID: 8453},
+	{Name: "Fantom", ChainID: 250, Aliases: []string{"ftm"}},
+	{Name: "Sonic", ChainID: 146},
+	{Name: "Linea", ChainID: 59144},
+	{Name: "Scroll", ChainID: 534352},
+	{Name: "zkSync Era", ChainID: 324, Aliases: []string{"zksync"}},
+	{Name: "Blast", ChainID: 81457},
+	{Name: "Mantle", ChainID: 5000},
+	{Name: "Mode", ChainID: 34443},
+	{Name: "Manta", ChainID: 169},
+	{Name: "Taiko", ChainID: 167000},
+	{Name: "Fraxtal", ChainID: 252},
+	{Name: "Berachain", ChainID: 80094},
+	{Name: "Core", ChainID: 1116},
+	{Name: "Kava", ChainID: 2222},
+	{Name: "Metis", ChainID: 1088},
+	{Name: "Cronos", ChainID: 25, Aliases: []string{"cro"}},
+	{Name: "Celo", ChainID: 42220},
+	{Name: "Moonbeam", ChainID: 1284},
+	{Name: "Ronin", ChainID: 2020},
+	{Name: "Aurora", ChainID: 1313161554},
+	{Name: "Solana", Aliases: []string{"sol"}},
+	{Name: "Eclipse"},
+	{Name: "Sui"},
+	{Name: "Aptos", Aliases: []string{"apt"}},
+	{Name: "Movement"},
+	{Name: "RENEC"},
+	{Name: "Sei"},
+	{Name: "Near", Aliases: []string{"near protocol"}},
+	{Name: "Tron", Aliases: []string{"trx"}},
+	{Name: "Starknet"},
+	{Name: "TON", Aliases: []string{"the open network"}},
+	{Name: "Cardano", Aliases: []string{"ada"}},
+	{Name: "Bitcoin", Aliases: []string{"btc"}},
+	{Name: "Injective", Aliases: []string{"inj"}},
+	{Name: "Osmosis"},
+	{Name: "Hyperliquid L1", DisplayName: "Hyperliquid", Aliases: []string{"hyperliquid"}},
+}
+
+// Chain is a canonical chain as resolved by a Registry.
+type Chain struct {
+	Name        string
+	DisplayName string
+	ChainID     int64
+}
+
+// Registry maps chain names and aliases to canonical chains. Lookups
+// ignore case, spaces, hyphens, underscores and dots. Names it does not know
+// are passed through trimmed and remembered for Unrecognized. A nil registry
+// passes every name through. It is safe for concurrent use.
+type Registry struct {
+	byKey map[string]*Chain
+
+	mu           sync.Mutex
+	unrecognized map[string]struct{}
+}
+
+// New returns the built-in registry extended by defs. A def whose name
+// resolves to a known chain adds its aliases to that chain and overrides its
+// display name and chain ID when set; an alias already used by another chain
+// moves to the def's chain, which is logged as chain_alias_reassigned. A nil
+// logger uses slog.Default.
+func New(defs []config.ChainDefinition, logger *slog.Logger) *Registry {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	r := &Registry{
+		byKey:        make(map[string]*Chain),
+		unrecognized: make(map[string]struct{}),
+	}
+	for _, def := range defaultChains {
+		r.add(def, nil)
+	}
+	for _, def := range defs {
+		r.add(def, logger)
+	}
+	return r
+}
+
+// add registers def. With logger set, aliases taken over from another chain
+// are logged.
+func (r *Registry) add(def config.ChainDefinition, logger *slog.Logger) {
+	name := strings.TrimSpace(def.Name)
+	key := chainKey(name)
+	if key == "" {
+		return
+	}
+
+	ref, ok := r.byKey[key]
+	if !ok {
+		ref = &Chain{Name: name, DisplayName: name}
+		r.byKey[key] = ref
+	}
+	if display := strings.TrimSpace(def.DisplayName); display != "" {
+		ref.DisplayName = display
+	}
+	if def.ChainID > 0 {
+		ref.ChainID = def.ChainID
+	}
+	for _, alias := range def.Aliases {
+		k := chainKey(alias)
+		if k == "" {
+			continue
+		}
+		if prev, ok := r.byKey[k]; ok && prev != ref && logger != nil {
+			logger.Warn("chain_alias_reassigned",
+				"alias", strings.TrimSpace(alias),
+				"from", prev.Name,
+				"to", ref.Name,
+			)
+		}
+		r.byKey[k] = ref
+	}
+}
+
+// Lookup returns the canonical chain for name.
+func (r *Registry) Lookup(name string) (Chain, bool) {
+	if r == nil {
+		return Chain{}, false
+	}
+	ref, ok := r.byKey[chainKey(name)]
+	if !ok {
+		return Chain{}, false
+	}
+	return *ref, true
+}
+
+// Canonical returns the canonical name for name, or name trimmed when it is
+// unknown. Unknown names are recorded for Unrecognized.
+func (r *Registry) Canonical(name string) string {
+	name = strings.TrimSpace(name)
+	if r == nil || name == "" {
+		return name
+	}
+	if ref, ok := r.Lookup(name); ok {
+		return ref.Name
+	}
+
+	r.mu.Lock()
+	r.unrecognized[name] = struct{}{}
+	r.mu.Unlock()
+	return name
+}
+
+// CanonicalList canonicalizes names, dropping blanks and names that resolve
+// to a chain already listed. Order is preserved; nil stays nil.
+func (r *Registry) CanonicalList(names []string) []string {
+	if names == nil {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(names))
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		canonical := r.Canonical(name)
+		if canonical == "" {
+			continue
+		}
+		if _, dup := seen[canonical]; dup {
+			continue
+		}
+		seen[canonical] = struct{}{}
+		result = append(result, canonical)
+	}
+	return result
+}
+
+// Unrecognized returns the unknown chain names seen by Canonical, sorted.
+func (r *Registry) Unrecognized() []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.unrecognized))
+	for name := range r.unrecognized {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// chainKey folds a chain name for lookup.
+func chainKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_', '.':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
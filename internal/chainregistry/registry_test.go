@@ -0,0 +1,118 @@
+package chainregistry
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+func TestRegistry_CanonicalResolvesAliases(t *testing.T) {
+	r := New(nil, nil)
+
+	tests := map[string]string{
+		"BSC":                 "BSC",
+		"Binance Smart Chain": "BSC",
+		"bnb-chain":           "BSC",
+		" SUI ":               "Sui",
+		"zksync_era":          "zkSync Era",
+		"Arbitrum One":        "Arbitrum",
+	}
+	for in, want := range tests {
+		if got := r.Canonical(in); got != want {
+			t.Errorf("Canonical(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := r.Unrecognized(); len(got) != 0 {
+		t.Fatalf("Unrecognized() = %v, want none", got)
+	}
+
+	ref, ok := r.Lookup("bnb")
+	if !ok || ref.DisplayName != "BNB Chain" || ref.ChainID != 56 {
+		t.Fatalf("Lookup(bnb) = %+v, %v", ref, ok)
+	}
+}
+
+func TestRegistry_ConfigExtendsDefaults(t *testing.T) {
+	r := New([]config.ChainDefinition{
+		{Name: "sui", DisplayName: "Sui Network", Aliases: []string{"sui mainnet"}},
+		{Name: "Fogo", ChainID: 9999, Aliases: []string{"fogo-mainnet"}},
+		{Name: "Binance", DisplayName: "BNB"},
+	}, nil)
+
+	if ref, _ := r.Lookup("Sui Mainnet"); ref.Name != "Sui" || ref.DisplayName != "Sui Network" {
+		t.Errorf("extended Sui = %+v", ref)
+	}
+	if ref, _ := r.Lookup("FOGO MAINNET"); ref.Name != "Fogo" || ref.ChainID != 9999 || ref.DisplayName != "Fogo" {
+		t.Errorf("new chain = %+v", ref)
+	}
+	if ref, _ := r.Lookup("BSC"); ref.DisplayName != "BNB" || ref.ChainID != 56 {
+		t.Errorf("def named by alias should extend BSC, got %+v", ref)
+	}
+}
+
+func TestRegistry_LogsReassignedAliases(t *testing.T) {
+	var buf bytes.Buffer
+	r := New([]config.ChainDefinition{
+		{Name: "Opal", Aliases: []string{"OP", "opal-mainnet"}},
+		{Name: "Optimism", Aliases: []string{"op mainnet"}},
+	}, slog.New(slog.NewTextHandler(&buf, nil)))
+
+	if ref, _ := r.Lookup("op"); ref.Name != "Opal" {
+		t.Fatalf("expected config alias to win, got %+v", ref)
+	}
+	if ref, _ := r.Lookup("OP Mainnet"); ref.Name != "Optimism" {
+		t.Fatalf("expected built-in alias kept, got %+v", ref)
+	}
+	if got := strings.Count(buf.String(), "chain_alias_reassigned"); got != 1 || !strings.Contains(buf.String(), "alias=OP from=Optimism to=Opal") {
+		t.Fatalf("expected one reassignment warning, got %s", buf.String())
+	}
+}
+
+func TestRegistry_ReportsUnrecognized(t *testing.T) {
+	r := New(nil, nil)
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"Zeta", "Solana", "Alpha", "Zeta"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			r.Canonical(name)
+		}(name)
+	}
+	wg.Wait()
+
+	if got, want := r.Unrecognized(), []string{"Alpha", "Zeta"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Unrecognized() = %v, want %v", got, want)
+	}
+}
+
+func TestRegistry_CanonicalList(t *testing.T) {
+	r := New(nil, nil)
+
+	got := r.CanonicalList([]string{"sol", "Ethereum", "", "Solana", "eth"})
+	if want := []string{"Solana", "Ethereum"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("CanonicalList = %v, want %v", got, want)
+	}
+	if r.CanonicalList(nil) != nil {
+		t.Fatalf("CanonicalList(nil) should stay nil")
+	}
+}
+
+func TestRegistry_NilPassesThrough(t *testing.T) {
+	var r *Registry
+
+	if got := r.Canonical(" bsc "); got != "bsc" {
+		t.Fatalf("Canonical = %q, want trimmed input", got)
+	}
+	if _, ok := r.Lookup("BSC"); ok {
+		t.Fatalf("nil registry should not resolve chains")
+	}
+	if got := r.Unrecognized(); got != nil {
+		t.Fatalf("Unrecognized = %v, want nil", got)
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,34 +17,148 @@ import (
 
 // Config holds all configuration sections loaded from YAML.
 type Config struct {
-	Oracle    OracleConfig    `yaml:"oracle"`
-	API       APIConfig       `yaml:"api"`
-	Output    OutputConfig    `yaml:"output"`
-	Scheduler SchedulerConfig `yaml:"scheduler"`
-	Logging   LoggingConfig   `yaml:"logging"`
-	TVL       TVLConfig       `yaml:"tvl"`
+	Oracle      OracleConfig      `yaml:"oracle"`
+	API         APIConfig         `yaml:"api"`
+	Output      OutputConfig      `yaml:"output"`
+	Scheduler   SchedulerConfig   `yaml:"scheduler"`
+	Logging     LoggingConfig     `yaml:"logging"`
+	TVL         TVLConfig         `yaml:"tvl"`
+	Source      SourceConfig      `yaml:"source"`
+	Aggregation AggregationConfig `yaml:"aggregation"`
+	Chains      ChainsConfig      `yaml:"chains"`
+	Categories  CategoriesConfig  `yaml:"categories"`
+	Anomalies   AnomaliesConfig   `yaml:"anomalies"`
+	Quality     QualityConfig     `yaml:"quality"`
 }
 
 type OracleConfig struct {
 	Name          string `yaml:"name"`
 	Website       string `yaml:"website"`
 	Documentation string `yaml:"documentation"`
+	// Names and Profiles switch to a multi-oracle run: one fetch is
+	// aggregated once per oracle, with outputs and state written to a
+	// subdirectory of output.directory per oracle. Profiles wins when both
+	// are set; Names is shorthand for profiles with only a name.
+	Names    []string        `yaml:"names"`
+	Profiles []OracleProfile `yaml:"profiles"`
+}
+
+// OracleProfile is one oracle of a multi-oracle run. Directory is the output
+// subdirectory (the slugified name when empty). CustomProtocolsPath and
+// CustomDataPath default to the tvl paths for the oracle named in
+// oracle.name, and to a Directory subfolder next to them for the others.
+type OracleProfile struct {
+	Name                string `yaml:"name"`
+	Website             string `yaml:"website"`
+	Documentation       string `yaml:"documentation"`
+	Directory           string `yaml:"directory"`
+	CustomProtocolsPath string `yaml:"custom_protocols_path"`
+	CustomDataPath      string `yaml:"custom_data_path"`
 }
 
 type APIConfig struct {
 	OraclesURL       string        `yaml:"oracles_url"`
 	ProtocolsURL     string        `yaml:"protocols_url"`
+	ProtocolTVLURL   string        `yaml:"protocol_tvl_url"`
+	ChainsURL        string        `yaml:"chains_url"`
+	ChainTVLURL      string        `yaml:"historical_chain_tvl_url"`
 	Timeout          time.Duration `yaml:"timeout"`
 	MaxRetries       int           `yaml:"max_retries"`
 	RetryDelay       time.Duration `yaml:"retry_delay"`
+	MaxRetryAfter    time.Duration `yaml:"max_retry_after"`
 	RandomizeHeaders bool          `yaml:"randomize_headers"`
+	ProtocolRPS      float64       `yaml:"protocol_rps"`
+	ProtocolBurst    int           `yaml:"protocol_burst"`
+	RecordDir        string        `yaml:"record_dir"`
+	ReplayDir        string        `yaml:"replay_dir"`
+	// Transport selects the HTTP stack: "browser" (Chrome TLS fingerprint,
+	// default) or "standard" (net/http). Both honor ProxyURL (http, https or
+	// socks5), CABundlePath and the optional mTLS client key pair.
+	Transport      string `yaml:"transport"`
+	ProxyURL       string `yaml:"proxy_url"`
+	CABundlePath   string `yaml:"ca_bundle_path"`
+	ClientCertPath string `yaml:"client_cert_path"`
+	ClientKeyPath  string `yaml:"client_key_path"`
+	// Pro locates the DefiLlama Pro API key substituted for {api_key} in
+	// Hosts base URLs. Hosts lists the base URLs requests may be sent to;
+	// Failover maps an endpoint class (oracles, protocols, protocol_tvl,
+	// chains, chain_tvl) to the host names to try in order, and classes not
+	// listed try every host in declaration order. With no hosts the endpoint
+	// URLs above are requested directly.
+	Pro      ProAPIConfig        `yaml:"pro"`
+	Hosts    []HostConfig        `yaml:"hosts"`
+	Failover map[string][]string `yaml:"failover"`
+	Cache    CacheConfig         `yaml:"cache"`
+	Schema   SchemaConfig        `yaml:"schema"`
+	Breaker  BreakerConfig       `yaml:"breaker"`
+}
+
+// ProAPIConfig locates the DefiLlama Pro API key: APIKeyFile wins over the
+// APIKeyEnv environment variable (DEFILLAMA_API_KEY when empty). The key is
+// never logged.
+type ProAPIConfig struct {
+	APIKeyFile string `yaml:"api_key_file"`
+	APIKeyEnv  string `yaml:"api_key_env"`
+}
+
+// HostConfig is one upstream base URL. The request path of each endpoint is
+// appended to BaseURL. RPS and Burst limit requests to this host on top of the
+// per-endpoint limits; zero RPS disables the host limit.
+type HostConfig struct {
+	Name    string  `yaml:"name"`
+	BaseURL string  `yaml:"base_url"`
+	RPS     float64 `yaml:"rps"`
+	Burst   int     `yaml:"burst"`
+}
+
+// CacheConfig controls the on-disk response cache. Entries younger than MaxAge
+// are served without a request; entries older than MaxStaleness are not used
+// as a fallback. Zero disables either bound.
+type CacheConfig struct {
+	Directory    string        `yaml:"directory"`
+	MaxAge       time.Duration `yaml:"max_age"`
+	MaxStaleness time.Duration `yaml:"max_staleness"`
+}
+
+// SchemaConfig controls upstream schema-drift detection. Raw responses are
+// compared to a field manifest (built in unless ManifestPath is set) and the
+// differences are written to ReportFile in the output directory. Strict fails
+// the cycle when an expected field is removed or changes type.
+type SchemaConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	Strict       bool   `yaml:"strict"`
+	ManifestPath string `yaml:"manifest_path"`
+	ReportFile   string `yaml:"report_file"`
+}
+
+// BreakerConfig controls the per-endpoint circuit breakers. After
+// FailureThreshold consecutive failed fetches a breaker opens and requests are
+// served from the cache for OpenDuration; half-open probes then need
+// HalfOpenSuccesses successes to close it. StateFile defaults to
+// breaker-state.json in the output directory.
+type BreakerConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	FailureThreshold  int           `yaml:"failure_threshold"`
+	OpenDuration      time.Duration `yaml:"open_duration"`
+	HalfOpenSuccesses int           `yaml:"half_open_successes"`
+	StateFile         string        `yaml:"state_file"`
 }
 
 type OutputConfig struct {
-	Directory   string `yaml:"directory"`
-	FullFile    string `yaml:"full_file"`
-	SummaryFile string `yaml:"summary_file"`
-	StateFile   string `yaml:"state_file"`
+	Directory     string `yaml:"directory"`
+	FullFile      string `yaml:"full_file"`
+	SummaryFile   string `yaml:"summary_file"`
+	StateFile     string `yaml:"state_file"`
+	LandscapeFile string `yaml:"landscape_file"`
+	// CategoryReportFile lists categories the taxonomy leaves unmapped; it
+	// is written only when a category taxonomy is configured.
+	CategoryReportFile string `yaml:"category_report_file"`
+	// ProtocolMatchReportFile lists protocols left without TVS because no
+	// oraclesTVS key, or several, matched them; empty disables it.
+	ProtocolMatchReportFile string `yaml:"protocol_match_report_file"`
+	// QualityReportFile records the data-quality rule results of each cycle;
+	// empty disables it.
+	QualityReportFile string `yaml:"quality_report_file"`
 }
 
 type SchedulerConfig struct {
@@ -57,6 +175,190 @@ type TVLConfig struct {
 	CustomProtocolsPath string `yaml:"custom_protocols_path"`
 	CustomDataPath      string `yaml:"custom_data_path"`
 	Enabled             bool   `yaml:"enabled"`
+	Concurrency         int    `yaml:"concurrency"`
+}
+
+// SourceConfig selects where DefiLlama datasets are read from: the live API,
+// a local directory in cassette layout, or a fixture server over that directory.
+type SourceConfig struct {
+	Type      string `yaml:"type"`
+	Directory string `yaml:"directory"`
+	Address   string `yaml:"address"`
+}
+
+// AggregationConfig controls optional aggregation stages. ChainShare fetches
+// DefiLlama's chain TVL totals to express the oracle's TVS on each chain as a
+// share of that chain's TVL; it costs one /v2/chains request plus one
+// historicalChainTvl request per active chain each cycle, so it is off by
+// default. Landscape ranks every oracle in the /oracles
+// response overall, per chain and per category and writes the result to
+// output.landscape_file. TVSPolicy lists the DefiLlama TVL components that
+// count toward TVS, matching the toggles on DefiLlama's UI; components not
+// listed are left out (see TVSComponents). ProtocolAliases maps a
+// protocols2 slug or name to the oraclesTVS key its TVS is reported under,
+// for protocols the automatic matching cannot resolve.
+type AggregationConfig struct {
+	ChainShare      bool              `yaml:"chain_share"`
+	Landscape       bool              `yaml:"landscape"`
+	TVSPolicy       []string          `yaml:"tvs_policy"`
+	ProtocolAliases map[string]string `yaml:"protocol_aliases"`
+}
+
+// TVSComponents are the DefiLlama TVL components aggregation.tvs_policy may
+// list. doublecounted and liquidstaking are part of plain TVL and are
+// subtracted when not listed; dcandlsoverlap is counted in both and is added
+// back when neither is listed, so it cannot be listed itself. The others are
+// reported on top of plain TVL and are added when listed.
+var TVSComponents = []string{"borrowed", "doublecounted", "liquidstaking", "offers", "pool2", "staking", "treasury", "vesting"}
+
+// DefaultTVSPolicy counts every component except borrowed, which is what the
+// extractor counted before aggregation.tvs_policy existed, so protocol TVS
+// totals do not change on upgrade.
+var DefaultTVSPolicy = []string{"doublecounted", "liquidstaking", "offers", "pool2", "staking", "treasury", "vesting"}
+
+// ChainsConfig extends the built-in chain registry that maps the chain names
+// found in DefiLlama data, custom protocols and custom-data files to one
+// canonical name. An entry whose name matches a built-in chain adds its
+// aliases to it and overrides its display name and chain ID when set; other
+// entries add new chains.
+type ChainsConfig struct {
+	Registry []ChainDefinition `yaml:"registry"`
+}
+
+// CategoriesConfig maps source categories to the reporting taxonomy used in
+// the mapped category breakdowns. Mapping lists, per reporting category, the
+// DefiLlama or custom-data categories it groups (case-insensitive); Overrides
+// assigns a reporting category to a protocol slug regardless of its category.
+// Both empty disables the mapped breakdowns and the category report.
+type CategoriesConfig struct {
+	Mapping   map[string][]string `yaml:"mapping"`
+	Overrides map[string]string   `yaml:"overrides"`
+}
+
+// Enabled reports whether a taxonomy is configured.
+func (c CategoriesConfig) Enabled() bool {
+	return len(c.Mapping) > 0 || len(c.Overrides) > 0
+}
+
+// AnomaliesConfig controls anomaly detection on TVS moves between cycles.
+// Each protocol's move, and the total's, is scored against the moves over the
+// last Window snapshots with Method "mad" (modified z-score on the median
+// absolute deviation) or "zscore" (standard score), and flagged when the score
+// exceeds Threshold and the move is at least MinChangePct percent. With
+// Quarantine on, a flagged protocol keeps its previously published TVS for up
+// to QuarantineCycles consecutive cycles; a move still flagged after that is
+// published.
+type AnomaliesConfig struct {
+	Enabled          bool    `yaml:"enabled"`
+	Method           string  `yaml:"method"`
+	Window           int     `yaml:"window"`
+	Threshold        float64 `yaml:"threshold"`
+	MinChangePct     float64 `yaml:"min_change_pct"`
+	Quarantine       bool    `yaml:"quarantine"`
+	QuarantineCycles int     `yaml:"quarantine_cycles"`
+}
+
+// AnomalyMethods are the scoring methods anomalies.method accepts.
+var AnomalyMethods = []string{"mad", "zscore"}
+
+// minAnomalyWindow is the smallest anomalies.window that leaves enough moves
+// to score against.
+const minAnomalyWindow = 6
+
+func (c *AnomaliesConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if !slices.Contains(AnomalyMethods, strings.ToLower(strings.TrimSpace(c.Method))) {
+		return fmt.Errorf("anomalies.method must be one of %s; got %q", strings.Join(AnomalyMethods, ", "), c.Method)
+	}
+	if c.Window < minAnomalyWindow {
+		return fmt.Errorf("anomalies.window must be at least %d, got %d", minAnomalyWindow, c.Window)
+	}
+	if c.Threshold <= 0 {
+		return fmt.Errorf("anomalies.threshold must be positive, got %v", c.Threshold)
+	}
+	if c.MinChangePct < 0 {
+		return fmt.Errorf("anomalies.min_change_pct must not be negative, got %v", c.MinChangePct)
+	}
+	if c.Quarantine && c.QuarantineCycles < 1 {
+		return fmt.Errorf("anomalies.quarantine_cycles must be at least 1, got %d", c.QuarantineCycles)
+	}
+	return nil
+}
+
+// QualityConfig configures the data-quality rules checked after aggregation,
+// before outputs are written. Each rule fails against its Threshold:
+// TVSSumVsReference when protocol TVS differs from the chart's latest total
+// by more than Threshold percent; MinProtocolCount below Threshold
+// protocols; MaxTVSDeltaPct when total TVS moved more than Threshold percent
+// since the last snapshot; MinTVSCoveragePct when under Threshold percent of
+// protocols have TVS; NoEmptyChainKeys on any blank chain name (Threshold is
+// unused).
+type QualityConfig struct {
+	TVSSumVsReference QualityRule `yaml:"tvs_sum_vs_reference"`
+	MinProtocolCount  QualityRule `yaml:"min_protocol_count"`
+	MaxTVSDeltaPct    QualityRule `yaml:"max_tvs_delta_pct"`
+	MinTVSCoveragePct QualityRule `yaml:"min_tvs_coverage_pct"`
+	NoEmptyChainKeys  QualityRule `yaml:"no_empty_chain_keys"`
+}
+
+// QualityRule is one data-quality rule. A failing rule with Severity "warn"
+// is logged and reported; "block_publish" also skips writing outputs for the
+// cycle and leaves the state's last-updated timestamp unchanged so the data
+// is retried (only the evaluated TVS is saved); "fail" does the same and
+// fails the cycle.
+type QualityRule struct {
+	Enabled   bool    `yaml:"enabled"`
+	Severity  string  `yaml:"severity"`
+	Threshold float64 `yaml:"threshold"`
+}
+
+// QualitySeverities are the severities a quality rule accepts.
+var QualitySeverities = []string{"warn", "block_publish", "fail"}
+
+// Rules returns the rules keyed by their YAML names.
+func (c QualityConfig) Rules() map[string]QualityRule {
+	return map[string]QualityRule{
+		"tvs_sum_vs_reference": c.TVSSumVsReference,
+		"min_protocol_count":   c.MinProtocolCount,
+		"max_tvs_delta_pct":    c.MaxTVSDeltaPct,
+		"min_tvs_coverage_pct": c.MinTVSCoveragePct,
+		"no_empty_chain_keys":  c.NoEmptyChainKeys,
+	}
+}
+
+func (c QualityConfig) validate() error {
+	rules := c.Rules()
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		rule := rules[name]
+		if !rule.Enabled {
+			continue
+		}
+		if !slices.Contains(QualitySeverities, strings.ToLower(strings.TrimSpace(rule.Severity))) {
+			return fmt.Errorf("quality.%s.severity must be one of %s; got %q", name, strings.Join(QualitySeverities, ", "), rule.Severity)
+		}
+		if rule.Threshold < 0 {
+			return fmt.Errorf("quality.%s.threshold must not be negative, got %v", name, rule.Threshold)
+		}
+	}
+	return nil
+}
+
+// ChainDefinition describes one chain: its canonical (DefiLlama) name, the
+// name shown to users, its numeric chain ID where it has one, and the other
+// spellings that refer to it.
+type ChainDefinition struct {
+	Name        string   `yaml:"name"`
+	DisplayName string   `yaml:"display_name"`
+	ChainID     int64    `yaml:"chain_id"`
+	Aliases     []string `yaml:"aliases"`
 }
 
 // applyEnvOverrides applies environment variable overrides to the provided config in place.
@@ -65,6 +367,24 @@ func applyEnvOverrides(cfg *Config) {
 		cfg.Oracle.Name = v
 	}
 
+	if v := os.Getenv("ORACLE_NAMES"); v != "" {
+		cfg.Oracle.Names = nil
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.Oracle.Names = append(cfg.Oracle.Names, name)
+			}
+		}
+	}
+
+	if v := os.Getenv("TVS_POLICY"); v != "" {
+		cfg.Aggregation.TVSPolicy = nil
+		for _, component := range strings.Split(v, ",") {
+			if component = strings.TrimSpace(component); component != "" {
+				cfg.Aggregation.TVSPolicy = append(cfg.Aggregation.TVSPolicy, component)
+			}
+		}
+	}
+
 	if v := os.Getenv("OUTPUT_DIR"); v != "" {
 		cfg.Output.Directory = v
 	}
@@ -102,11 +422,40 @@ func applyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("TVL_ENABLED"); v != "" {
 		cfg.TVL.Enabled = strings.ToLower(v) == "true"
 	}
+	if v := os.Getenv("API_CACHE_DIR"); v != "" {
+		cfg.API.Cache.Directory = v
+	}
+	if v := os.Getenv("TVL_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.TVL.Concurrency = n
+		} else {
+			log.Printf("warning: invalid TVL_CONCURRENCY %q, using YAML/default value", v)
+		}
+	}
 	if v := os.Getenv("API_RANDOMIZE_HEADERS"); v != "" {
 		cfg.API.RandomizeHeaders = strings.ToLower(v) == "true"
 	}
+	if v := os.Getenv("API_TRANSPORT"); v != "" {
+		cfg.API.Transport = v
+	}
+	if v := os.Getenv("API_PROXY_URL"); v != "" {
+		cfg.API.ProxyURL = v
+	}
+	if v := os.Getenv("API_SCHEMA_STRICT"); v != "" {
+		cfg.API.Schema.Strict = strings.ToLower(v) == "true"
+	}
+	if v := os.Getenv("SOURCE_TYPE"); v != "" {
+		cfg.Source.Type = v
+	}
+	if v := os.Getenv("SOURCE_DIRECTORY"); v != "" {
+		cfg.Source.Directory = v
+	}
 }
 
+// defaultBreakerStateFile is placed in the output directory unless
+// api.breaker.state_file is set.
+const defaultBreakerStateFile = "breaker-state.json"
+
 // defaultConfig returns configuration populated with documented defaults.
 func defaultConfig() Config {
 	return Config{
@@ -118,16 +467,42 @@ func defaultConfig() Config {
 		API: APIConfig{
 			OraclesURL:       "https://api.llama.fi/oracles",
 			ProtocolsURL:     "https://api.llama.fi/lite/protocols2?b=2",
+			ProtocolTVLURL:   "https://api.llama.fi/protocol/%s",
+			ChainsURL:        "https://api.llama.fi/v2/chains",
+			ChainTVLURL:      "https://api.llama.fi/v2/historicalChainTvl/%s",
 			Timeout:          30 * time.Second,
 			MaxRetries:       3,
 			RetryDelay:       1 * time.Second,
+			MaxRetryAfter:    60 * time.Second,
 			RandomizeHeaders: true,
+			Transport:        "browser",
+			ProtocolRPS:      5,
+			ProtocolBurst:    1,
+			Cache: CacheConfig{
+				Directory:    "api-cache",
+				MaxAge:       0,
+				MaxStaleness: 48 * time.Hour,
+			},
+			Schema: SchemaConfig{
+				Enabled:    true,
+				ReportFile: "schema-drift.json",
+			},
+			Breaker: BreakerConfig{
+				Enabled:           true,
+				FailureThreshold:  3,
+				OpenDuration:      15 * time.Minute,
+				HalfOpenSuccesses: 1,
+			},
 		},
 		Output: OutputConfig{
-			Directory:   "data",
-			FullFile:    "switchboard-oracle-data.json",
-			SummaryFile: "switchboard-summary.json",
-			StateFile:   "state.json",
+			Directory:               "data",
+			FullFile:                "switchboard-oracle-data.json",
+			SummaryFile:             "switchboard-summary.json",
+			StateFile:               "state.json",
+			LandscapeFile:           "oracle-landscape.json",
+			CategoryReportFile:      "category-report.json",
+			ProtocolMatchReportFile: "protocol-matches.json",
+			QualityReportFile:       "quality-report.json",
 		},
 		Scheduler: SchedulerConfig{
 			Interval:         2 * time.Hour,
@@ -141,6 +516,29 @@ func defaultConfig() Config {
 			CustomProtocolsPath: "config/custom-protocols.json",
 			CustomDataPath:      "custom-data",
 			Enabled:             true,
+			Concurrency:         4,
+		},
+		Source: SourceConfig{
+			Type: "live",
+		},
+		Aggregation: AggregationConfig{
+			Landscape: true,
+			TVSPolicy: slices.Clone(DefaultTVSPolicy),
+		},
+		Anomalies: AnomaliesConfig{
+			Enabled:          true,
+			Method:           "mad",
+			Window:           24,
+			Threshold:        3.5,
+			MinChangePct:     20,
+			QuarantineCycles: 3,
+		},
+		Quality: QualityConfig{
+			TVSSumVsReference: QualityRule{Enabled: true, Severity: "warn", Threshold: 5},
+			MinProtocolCount:  QualityRule{Enabled: true, Severity: "block_publish", Threshold: 1},
+			MaxTVSDeltaPct:    QualityRule{Enabled: true, Severity: "block_publish", Threshold: 50},
+			MinTVSCoveragePct: QualityRule{Enabled: true, Severity: "warn", Threshold: 50},
+			NoEmptyChainKeys:  QualityRule{Enabled: true, Severity: "block_publish"},
 		},
 	}
 }
@@ -163,6 +561,10 @@ func Load(path string) (*Config, error) {
 
 	applyEnvOverrides(&cfg)
 
+	if strings.TrimSpace(cfg.API.Breaker.StateFile) == "" {
+		cfg.API.Breaker.StateFile = filepath.Join(cfg.Output.Directory, defaultBreakerStateFile)
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
@@ -175,6 +577,9 @@ func (c *Config) Validate() error {
 	if strings.TrimSpace(c.Oracle.Name) == "" {
 		return errors.New("oracle.name must not be empty")
 	}
+	if err := c.validateOracleProfiles(); err != nil {
+		return err
+	}
 	if c.API.Timeout <= 0 {
 		return fmt.Errorf("api.timeout must be positive, got %s", c.API.Timeout)
 	}
@@ -184,6 +589,91 @@ func (c *Config) Validate() error {
 	if c.API.RetryDelay < 0 {
 		return fmt.Errorf("api.retry_delay must be non-negative, got %s", c.API.RetryDelay)
 	}
+	if c.API.MaxRetryAfter < 0 {
+		return fmt.Errorf("api.max_retry_after must be non-negative, got %s", c.API.MaxRetryAfter)
+	}
+	if c.API.Cache.MaxAge < 0 {
+		return fmt.Errorf("api.cache.max_age must be non-negative, got %s", c.API.Cache.MaxAge)
+	}
+	if c.API.Cache.MaxStaleness < 0 {
+		return fmt.Errorf("api.cache.max_staleness must be non-negative, got %s", c.API.Cache.MaxStaleness)
+	}
+	if c.API.ProtocolRPS < 0 {
+		return fmt.Errorf("api.protocol_rps must be non-negative, got %g", c.API.ProtocolRPS)
+	}
+	if c.API.ProtocolBurst < 0 {
+		return fmt.Errorf("api.protocol_burst must be non-negative, got %d", c.API.ProtocolBurst)
+	}
+	if c.API.Schema.Enabled && strings.TrimSpace(c.API.Schema.ReportFile) == "" {
+		return errors.New("api.schema.report_file must not be empty when api.schema.enabled is true")
+	}
+	if c.API.Schema.Strict && !c.API.Schema.Enabled {
+		return errors.New("api.schema.strict requires api.schema.enabled")
+	}
+	if c.API.Breaker.Enabled {
+		if c.API.Breaker.FailureThreshold < 1 {
+			return fmt.Errorf("api.breaker.failure_threshold must be at least 1, got %d", c.API.Breaker.FailureThreshold)
+		}
+		if c.API.Breaker.OpenDuration <= 0 {
+			return fmt.Errorf("api.breaker.open_duration must be positive, got %s", c.API.Breaker.OpenDuration)
+		}
+		if c.API.Breaker.HalfOpenSuccesses < 1 {
+			return fmt.Errorf("api.breaker.half_open_successes must be at least 1, got %d", c.API.Breaker.HalfOpenSuccesses)
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(c.API.Transport)) {
+	case "", "browser", "standard":
+	default:
+		return fmt.Errorf("api.transport must be one of standard, browser; got %q", c.API.Transport)
+	}
+	if raw := strings.TrimSpace(c.API.ProxyURL); raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("api.proxy_url must be an absolute URL, got %q", raw)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("api.proxy_url scheme must be one of http, https, socks5, socks5h; got %q", u.Scheme)
+		}
+	}
+	if (strings.TrimSpace(c.API.ClientCertPath) == "") != (strings.TrimSpace(c.API.ClientKeyPath) == "") {
+		return errors.New("api.client_cert_path and api.client_key_path must be set together")
+	}
+	if err := c.API.validateHosts(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(c.API.RecordDir) != "" && strings.TrimSpace(c.API.ReplayDir) != "" {
+		return errors.New("api.record_dir and api.replay_dir are mutually exclusive")
+	}
+	if c.Aggregation.Landscape && strings.TrimSpace(c.Output.LandscapeFile) == "" {
+		return errors.New("output.landscape_file must not be empty when aggregation.landscape is true")
+	}
+	for _, component := range c.Aggregation.TVSPolicy {
+		if !slices.Contains(TVSComponents, strings.ToLower(strings.TrimSpace(component))) {
+			return fmt.Errorf("aggregation.tvs_policy entries must be one of %s; got %q", strings.Join(TVSComponents, ", "), component)
+		}
+	}
+	for from, to := range c.Aggregation.ProtocolAliases {
+		if strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+			return fmt.Errorf("aggregation.protocol_aliases entries must not be empty; got %q: %q", from, to)
+		}
+	}
+	if err := c.Chains.validate(); err != nil {
+		return err
+	}
+	if err := c.Categories.validate(); err != nil {
+		return err
+	}
+	if err := c.Quality.validate(); err != nil {
+		return err
+	}
+	if err := c.Anomalies.validate(); err != nil {
+		return err
+	}
+	if c.Categories.Enabled() && strings.TrimSpace(c.Output.CategoryReportFile) == "" {
+		return errors.New("output.category_report_file must not be empty when categories are mapped")
+	}
 	if c.Scheduler.Interval <= 0 {
 		return fmt.Errorf("scheduler.interval must be positive, got %s", c.Scheduler.Interval)
 	}
@@ -212,6 +702,243 @@ func (c *Config) Validate() error {
 	if strings.TrimSpace(c.TVL.CustomDataPath) == "" {
 		return errors.New("tvl.custom_data_path must not be empty")
 	}
+	if c.TVL.Concurrency < 1 {
+		return fmt.Errorf("tvl.concurrency must be at least 1, got %d", c.TVL.Concurrency)
+	}
+
+	switch strings.ToLower(c.Source.Type) {
+	case "live":
+	case "directory", "fixture":
+		if strings.TrimSpace(c.Source.Directory) == "" {
+			return fmt.Errorf("source.directory must not be empty for source.type %q", c.Source.Type)
+		}
+	default:
+		return fmt.Errorf("source.type must be one of live, directory, fixture; got %q", c.Source.Type)
+	}
 
 	return nil
 }
+
+// validateOracleProfiles checks oracle.names and oracle.profiles: names must
+// be set and unique, and output subdirectories unique single path elements.
+func (c *Config) validateOracleProfiles() error {
+	field := "oracle.profiles"
+	if len(c.Oracle.Profiles) == 0 {
+		field = "oracle.names"
+	}
+
+	names := make(map[string]struct{})
+	dirs := make(map[string]struct{})
+	for i, p := range c.OracleProfiles() {
+		if p.Name == "" {
+			return fmt.Errorf("%s[%d] name must not be empty", field, i)
+		}
+		key := strings.ToLower(p.Name)
+		if _, ok := names[key]; ok {
+			return fmt.Errorf("%s[%d] name %q is duplicated", field, i, p.Name)
+		}
+		names[key] = struct{}{}
+
+		if p.Directory == "" || p.Directory == "." || p.Directory == ".." || strings.ContainsAny(p.Directory, `/\`) {
+			return fmt.Errorf("%s[%d] directory must be a single path element, got %q", field, i, p.Directory)
+		}
+		if _, ok := dirs[p.Directory]; ok {
+			return fmt.Errorf("%s[%d] directory %q is duplicated", field, i, p.Directory)
+		}
+		dirs[p.Directory] = struct{}{}
+	}
+	return nil
+}
+
+// MultiOracle reports whether oracle.names or oracle.profiles is set.
+func (c *Config) MultiOracle() bool {
+	return len(c.Oracle.Profiles) > 0 || len(c.Oracle.Names) > 0
+}
+
+// OracleProfiles returns the oracles of a multi-oracle run with defaults
+// applied, or nil when neither oracle.profiles nor oracle.names is set. A
+// name-only entry matching oracle.name inherits its website and
+// documentation.
+func (c *Config) OracleProfiles() []OracleProfile {
+	var profiles []OracleProfile
+	switch {
+	case len(c.Oracle.Profiles) > 0:
+		profiles = append(profiles, c.Oracle.Profiles...)
+	case len(c.Oracle.Names) > 0:
+		for _, name := range c.Oracle.Names {
+			profiles = append(profiles, OracleProfile{Name: name})
+		}
+	default:
+		return nil
+	}
+
+	for i := range profiles {
+		p := &profiles[i]
+		p.Name = strings.TrimSpace(p.Name)
+		primary := strings.EqualFold(p.Name, strings.TrimSpace(c.Oracle.Name))
+		if primary && p.Website == "" && p.Documentation == "" {
+			p.Website = c.Oracle.Website
+			p.Documentation = c.Oracle.Documentation
+		}
+		if p.Directory = strings.TrimSpace(p.Directory); p.Directory == "" {
+			p.Directory = slugify(p.Name)
+		}
+		if p.CustomProtocolsPath == "" {
+			p.CustomProtocolsPath = c.TVL.CustomProtocolsPath
+			if !primary {
+				p.CustomProtocolsPath = filepath.Join(filepath.Dir(c.TVL.CustomProtocolsPath), p.Directory, filepath.Base(c.TVL.CustomProtocolsPath))
+			}
+		}
+		if p.CustomDataPath == "" {
+			p.CustomDataPath = c.TVL.CustomDataPath
+			if !primary {
+				p.CustomDataPath = filepath.Join(c.TVL.CustomDataPath, p.Directory)
+			}
+		}
+	}
+	return profiles
+}
+
+// ForOracle returns a copy of c scoped to profile p: oracle settings come
+// from p, outputs and state move to p's subdirectory of output.directory and
+// the TVL pipeline reads p's custom protocol files.
+func (c *Config) ForOracle(p OracleProfile) *Config {
+	scoped := *c
+	scoped.Oracle = OracleConfig{
+		Name:          p.Name,
+		Website:       p.Website,
+		Documentation: p.Documentation,
+	}
+	scoped.Output.Directory = filepath.Join(c.Output.Directory, p.Directory)
+	scoped.TVL.CustomProtocolsPath = p.CustomProtocolsPath
+	scoped.TVL.CustomDataPath = p.CustomDataPath
+	return &scoped
+}
+
+// slugify lowercases name and replaces every run of characters other than
+// letters and digits with a single hyphen.
+func slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		if !hyphen && b.Len() > 0 {
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// endpointClasses are the keys accepted in api.failover.
+var endpointClasses = map[string]struct{}{
+	"oracles":      {},
+	"protocols":    {},
+	"protocol_tvl": {},
+	"chains":       {},
+	"chain_tvl":    {},
+}
+
+// validateHosts checks api.hosts and api.failover.
+func (a *APIConfig) validateHosts() error {
+	names := make(map[string]struct{}, len(a.Hosts))
+	for i, h := range a.Hosts {
+		name := strings.TrimSpace(h.Name)
+		if name == "" {
+			return fmt.Errorf("api.hosts[%d].name must not be empty", i)
+		}
+		if _, dup := names[name]; dup {
+			return fmt.Errorf("api.hosts[%d].name %q is duplicated", i, name)
+		}
+		names[name] = struct{}{}
+
+		u, err := url.Parse(strings.ReplaceAll(h.BaseURL, "{api_key}", "key"))
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("api.hosts[%d].base_url must be an absolute http(s) URL, got %q", i, h.BaseURL)
+		}
+		if h.RPS < 0 {
+			return fmt.Errorf("api.hosts[%d].rps must be non-negative, got %g", i, h.RPS)
+		}
+		if h.Burst < 0 {
+			return fmt.Errorf("api.hosts[%d].burst must be non-negative, got %d", i, h.Burst)
+		}
+	}
+
+	for class, hosts := range a.Failover {
+		if _, ok := endpointClasses[class]; !ok {
+			return fmt.Errorf("api.failover key must be one of oracles, protocols, protocol_tvl, chains, chain_tvl; got %q", class)
+		}
+		if len(hosts) == 0 {
+			return fmt.Errorf("api.failover.%s must list at least one host", class)
+		}
+		for _, name := range hosts {
+			if _, ok := names[name]; !ok {
+				return fmt.Errorf("api.failover.%s references unknown host %q", class, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validate checks that every registry entry is named and that no name or
+// alias (case-insensitive) refers to two entries.
+func (c *ChainsConfig) validate() error {
+	owners := make(map[string]int, len(c.Registry))
+	for i, def := range c.Registry {
+		if strings.TrimSpace(def.Name) == "" {
+			return fmt.Errorf("chains.registry[%d].name must not be empty", i)
+		}
+		if def.ChainID < 0 {
+			return fmt.Errorf("chains.registry[%d].chain_id must be non-negative, got %d", i, def.ChainID)
+		}
+		for _, name := range append([]string{def.Name}, def.Aliases...) {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if key == "" {
+				return fmt.Errorf("chains.registry[%d].aliases must not contain empty names", i)
+			}
+			if owner, dup := owners[key]; dup && owner != i {
+				return fmt.Errorf("chains.registry[%d] name or alias %q is already used by chains.registry[%d]", i, name, owner)
+			}
+			owners[key] = i
+		}
+	}
+	return nil
+}
+
+// validate checks that reporting categories are named and that no source
+// category (case-insensitive) is mapped to two reporting categories.
+func (c *CategoriesConfig) validate() error {
+	targets := make([]string, 0, len(c.Mapping))
+	for target := range c.Mapping {
+		targets = append(targets, target)
+	}
+	slices.Sort(targets)
+
+	owners := make(map[string]string)
+	for _, target := range targets {
+		if strings.TrimSpace(target) == "" {
+			return errors.New("categories.mapping keys must not be empty")
+		}
+		for _, source := range c.Mapping[target] {
+			key := strings.ToLower(strings.TrimSpace(source))
+			if key == "" {
+				return fmt.Errorf("categories.mapping.%s must not contain empty categories", target)
+			}
+			if owner, dup := owners[key]; dup && owner != target {
+				return fmt.Errorf("categories.mapping: %q is mapped to both %q and %q", source, owner, target)
+			}
+			owners[key] = target
+		}
+	}
+	for slug, category := range c.Overrides {
+		if strings.TrimSpace(slug) == "" || strings.TrimSpace(category) == "" {
+			return fmt.Errorf("categories.overrides entries need a slug and a category, got %q: %q", slug, category)
+		}
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"log"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -75,6 +76,18 @@ func TestLoad_MinimalDefaultsApplied(t *testing.T) {
 	if !cfg.TVL.Enabled {
 		t.Errorf("TVL.Enabled default = %v, want true", cfg.TVL.Enabled)
 	}
+	if want := filepath.Join("data", "breaker-state.json"); cfg.API.Breaker.StateFile != want {
+		t.Errorf("API.Breaker.StateFile default = %q, want %q", cfg.API.Breaker.StateFile, want)
+	}
+	if cfg.Aggregation.ChainShare {
+		t.Errorf("Aggregation.ChainShare default = %v, want false", cfg.Aggregation.ChainShare)
+	}
+	// The default must keep counting everything the extractor counted
+	// before tvs_policy existed: every component except borrowed.
+	wantPolicy := slices.DeleteFunc(slices.Clone(TVSComponents), func(c string) bool { return c == "borrowed" })
+	if !slices.Equal(cfg.Aggregation.TVSPolicy, wantPolicy) {
+		t.Errorf("Aggregation.TVSPolicy default = %v, want %v", cfg.Aggregation.TVSPolicy, wantPolicy)
+	}
 }
 
 func TestLoad_FileNotFound(t *testing.T) {
@@ -123,6 +136,215 @@ func TestValidate_InvalidValues(t *testing.T) {
 			mutate:  func(c *Config) { c.API.RetryDelay = -1 },
 			wantMsg: "api.retry_delay",
 		},
+		{
+			name:    "unknown transport",
+			mutate:  func(c *Config) { c.API.Transport = "curl" },
+			wantMsg: "api.transport",
+		},
+		{
+			name:    "relative proxy url",
+			mutate:  func(c *Config) { c.API.ProxyURL = "proxy.internal:3128" },
+			wantMsg: "api.proxy_url",
+		},
+		{
+			name:    "unsupported proxy scheme",
+			mutate:  func(c *Config) { c.API.ProxyURL = "ftp://proxy.internal:21" },
+			wantMsg: "api.proxy_url scheme",
+		},
+		{
+			name:    "client cert without key",
+			mutate:  func(c *Config) { c.API.ClientCertPath = "certs/client.pem" },
+			wantMsg: "api.client_cert_path and api.client_key_path",
+		},
+		{
+			name: "duplicate host name",
+			mutate: func(c *Config) {
+				c.API.Hosts = []HostConfig{
+					{Name: "pro", BaseURL: "https://pro-api.llama.fi/{api_key}"},
+					{Name: "pro", BaseURL: "https://api.llama.fi"},
+				}
+			},
+			wantMsg: "api.hosts[1].name",
+		},
+		{
+			name: "relative host base url",
+			mutate: func(c *Config) {
+				c.API.Hosts = []HostConfig{{Name: "public", BaseURL: "api.llama.fi"}}
+			},
+			wantMsg: "api.hosts[0].base_url",
+		},
+		{
+			name: "failover references unknown host",
+			mutate: func(c *Config) {
+				c.API.Hosts = []HostConfig{{Name: "public", BaseURL: "https://api.llama.fi"}}
+				c.API.Failover = map[string][]string{"protocols": {"pro", "public"}}
+			},
+			wantMsg: "api.failover.protocols references unknown host",
+		},
+		{
+			name: "failover unknown endpoint class",
+			mutate: func(c *Config) {
+				c.API.Hosts = []HostConfig{{Name: "public", BaseURL: "https://api.llama.fi"}}
+				c.API.Failover = map[string][]string{"yields": {"public"}}
+			},
+			wantMsg: "api.failover key",
+		},
+		{
+			name:    "duplicate oracle name",
+			mutate:  func(c *Config) { c.Oracle.Names = []string{"Pyth", "pyth"} },
+			wantMsg: "oracle.names[1] name",
+		},
+		{
+			name: "oracle profile directory with separator",
+			mutate: func(c *Config) {
+				c.Oracle.Profiles = []OracleProfile{{Name: "Pyth", Directory: "oracles/pyth"}}
+			},
+			wantMsg: "oracle.profiles[0] directory",
+		},
+		{
+			name:    "landscape without file",
+			mutate:  func(c *Config) { c.Output.LandscapeFile = " " },
+			wantMsg: "output.landscape_file",
+		},
+		{
+			name: "chain registry entry without name",
+			mutate: func(c *Config) {
+				c.Chains.Registry = []ChainDefinition{{DisplayName: "Nameless"}}
+			},
+			wantMsg: "chains.registry[0].name",
+		},
+		{
+			name: "chain alias used twice",
+			mutate: func(c *Config) {
+				c.Chains.Registry = []ChainDefinition{
+					{Name: "Fogo", Aliases: []string{"fogo-mainnet"}},
+					{Name: "Other", Aliases: []string{"FOGO-MAINNET"}},
+				}
+			},
+			wantMsg: "chains.registry[1]",
+		},
+		{
+			name: "category mapped twice",
+			mutate: func(c *Config) {
+				c.Categories.Mapping = map[string][]string{"Money Markets": {"Lending"}, "Yield": {"lending"}}
+			},
+			wantMsg: "categories.mapping",
+		},
+		{
+			name: "category report without file",
+			mutate: func(c *Config) {
+				c.Categories.Overrides = map[string]string{"kamino": "Yield"}
+				c.Output.CategoryReportFile = " "
+			},
+			wantMsg: "output.category_report_file",
+		},
+		{
+			name:    "unknown tvs policy component",
+			mutate:  func(c *Config) { c.Aggregation.TVSPolicy = []string{"staking", "govtokens"} },
+			wantMsg: "aggregation.tvs_policy",
+		},
+		{
+			name:    "unknown anomaly method",
+			mutate:  func(c *Config) { c.Anomalies.Method = "iqr" },
+			wantMsg: "anomalies.method",
+		},
+		{
+			name:    "anomaly window too short",
+			mutate:  func(c *Config) { c.Anomalies.Window = 3 },
+			wantMsg: "anomalies.window",
+		},
+		{
+			name: "quarantine without cycles",
+			mutate: func(c *Config) {
+				c.Anomalies.Quarantine = true
+				c.Anomalies.QuarantineCycles = 0
+			},
+			wantMsg: "anomalies.quarantine_cycles",
+		},
+		{
+			name: "quarantine cycles below one",
+			mutate: func(c *Config) {
+				c.Anomalies.Quarantine = true
+				c.Anomalies.QuarantineCycles = -1
+			},
+			wantMsg: "anomalies.quarantine_cycles",
+		},
+		{
+			name:    "unknown quality severity",
+			mutate:  func(c *Config) { c.Quality.MaxTVSDeltaPct.Severity = "block-publish" },
+			wantMsg: "quality.max_tvs_delta_pct.severity",
+		},
+		{
+			name:    "negative quality threshold",
+			mutate:  func(c *Config) { c.Quality.TVSSumVsReference.Threshold = -1 },
+			wantMsg: "quality.tvs_sum_vs_reference.threshold",
+		},
+		{
+			name:    "blank protocol alias target",
+			mutate:  func(c *Config) { c.Aggregation.ProtocolAliases = map[string]string{"kamino-lend": " "} },
+			wantMsg: "aggregation.protocol_aliases",
+		},
+		{
+			name: "record and replay both set",
+			mutate: func(c *Config) {
+				c.API.RecordDir = "cassettes/a"
+				c.API.ReplayDir = "cassettes/b"
+			},
+			wantMsg: "api.record_dir and api.replay_dir",
+		},
+		{
+			name:    "negative max retry after",
+			mutate:  func(c *Config) { c.API.MaxRetryAfter = -time.Second },
+			wantMsg: "api.max_retry_after",
+		},
+		{
+			name:    "negative cache max staleness",
+			mutate:  func(c *Config) { c.API.Cache.MaxStaleness = -time.Hour },
+			wantMsg: "api.cache.max_staleness",
+		},
+		{
+			name:    "schema enabled without report file",
+			mutate:  func(c *Config) { c.API.Schema.ReportFile = " " },
+			wantMsg: "api.schema.report_file",
+		},
+		{
+			name: "schema strict while disabled",
+			mutate: func(c *Config) {
+				c.API.Schema.Enabled = false
+				c.API.Schema.Strict = true
+			},
+			wantMsg: "api.schema.strict",
+		},
+		{
+			name:    "zero breaker failure threshold",
+			mutate:  func(c *Config) { c.API.Breaker.FailureThreshold = 0 },
+			wantMsg: "api.breaker.failure_threshold",
+		},
+		{
+			name:    "zero breaker open duration",
+			mutate:  func(c *Config) { c.API.Breaker.OpenDuration = 0 },
+			wantMsg: "api.breaker.open_duration",
+		},
+		{
+			name:    "negative protocol rps",
+			mutate:  func(c *Config) { c.API.ProtocolRPS = -1 },
+			wantMsg: "api.protocol_rps",
+		},
+		{
+			name:    "zero tvl concurrency",
+			mutate:  func(c *Config) { c.TVL.Concurrency = 0 },
+			wantMsg: "tvl.concurrency",
+		},
+		{
+			name:    "unknown source type",
+			mutate:  func(c *Config) { c.Source.Type = "s3" },
+			wantMsg: "source.type",
+		},
+		{
+			name:    "directory source without directory",
+			mutate:  func(c *Config) { c.Source.Type = "directory" },
+			wantMsg: "source.directory",
+		},
 		{
 			name:    "invalid log level",
 			mutate:  func(c *Config) { c.Logging.Level = "verbose" },
@@ -169,6 +391,88 @@ func TestValidate_ValidConfig(t *testing.T) {
 	}
 }
 
+func TestValidate_DetectionOnlyAnomaliesIgnoreQuarantineCycles(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Anomalies.Enabled = true
+	cfg.Anomalies.Quarantine = false
+	cfg.Anomalies.QuarantineCycles = 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate returned error for detection-only anomalies: %v", err)
+	}
+}
+
+func TestOracleProfiles_DefaultsAndScoping(t *testing.T) {
+	cfg := defaultConfig()
+	if cfg.MultiOracle() || cfg.OracleProfiles() != nil {
+		t.Fatalf("expected single-oracle mode by default")
+	}
+
+	cfg.Oracle.Website = "https://switchboard.xyz"
+	cfg.Oracle.Names = []string{"Switchboard", "Chainlink CCIP"}
+	profiles := cfg.OracleProfiles()
+	if len(profiles) != 2 {
+		t.Fatalf("expected 2 profiles, got %+v", profiles)
+	}
+
+	primary, other := profiles[0], profiles[1]
+	if primary.Directory != "switchboard" || primary.Website != "https://switchboard.xyz" {
+		t.Errorf("primary profile = %+v", primary)
+	}
+	if primary.CustomProtocolsPath != cfg.TVL.CustomProtocolsPath || primary.CustomDataPath != cfg.TVL.CustomDataPath {
+		t.Errorf("primary profile should keep tvl paths, got %+v", primary)
+	}
+	if other.Directory != "chainlink-ccip" || other.Website != "" {
+		t.Errorf("secondary profile = %+v", other)
+	}
+	if want := filepath.Join("config", "chainlink-ccip", "custom-protocols.json"); other.CustomProtocolsPath != want {
+		t.Errorf("CustomProtocolsPath = %q, want %q", other.CustomProtocolsPath, want)
+	}
+	if want := filepath.Join("custom-data", "chainlink-ccip"); other.CustomDataPath != want {
+		t.Errorf("CustomDataPath = %q, want %q", other.CustomDataPath, want)
+	}
+
+	scoped := cfg.ForOracle(other)
+	if scoped.Oracle.Name != "Chainlink CCIP" || scoped.MultiOracle() {
+		t.Errorf("scoped oracle = %+v", scoped.Oracle)
+	}
+	if want := filepath.Join("data", "chainlink-ccip"); scoped.Output.Directory != want {
+		t.Errorf("scoped Output.Directory = %q, want %q", scoped.Output.Directory, want)
+	}
+	if scoped.TVL.CustomDataPath != other.CustomDataPath || cfg.Output.Directory != "data" {
+		t.Errorf("ForOracle must scope a copy, got %+v / %+v", scoped.TVL, cfg.Output)
+	}
+
+	cfg.Oracle.Profiles = []OracleProfile{{Name: "Pyth", Directory: "pyth-network", CustomDataPath: "pyth-data"}}
+	profiles = cfg.OracleProfiles()
+	if len(profiles) != 1 || profiles[0].Directory != "pyth-network" || profiles[0].CustomDataPath != "pyth-data" {
+		t.Errorf("profiles should win over names, got %+v", profiles)
+	}
+}
+
+func TestLoad_EnvOverrides_OracleNames(t *testing.T) {
+	t.Setenv("ORACLE_NAMES", "Switchboard, Pyth,,RedStone")
+
+	cfg, err := Load(filepath.Join("testdata", "config_minimal.yaml"))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if got := strings.Join(cfg.Oracle.Names, "|"); got != "Switchboard|Pyth|RedStone" {
+		t.Fatalf("Oracle.Names = %q", got)
+	}
+}
+
+func TestLoad_EnvOverrides_TVSPolicy(t *testing.T) {
+	t.Setenv("TVS_POLICY", "staking, borrowed,,pool2")
+
+	cfg, err := Load(filepath.Join("testdata", "config_minimal.yaml"))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if got := strings.Join(cfg.Aggregation.TVSPolicy, "|"); got != "staking|borrowed|pool2" {
+		t.Fatalf("Aggregation.TVSPolicy = %q", got)
+	}
+}
+
 func TestLoad_EnvOverrides_StringAndDuration(t *testing.T) {
 	path := filepath.Join("testdata", "config_minimal.yaml")
 
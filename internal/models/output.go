@@ -10,11 +10,16 @@ type OracleInfo struct {
 }
 
 // OutputMetadata captures provenance details for generated outputs.
+// DataSource is "cache" when any input was served from the local response
+// cache, in which case CacheAgeSeconds reports the oldest entry used.
+// TVSPolicy lists the DefiLlama TVL components counted toward TVS.
 type OutputMetadata struct {
-	LastUpdated      string `json:"last_updated"`
-	DataSource       string `json:"data_source"`
-	UpdateFrequency  string `json:"update_frequency"`
-	ExtractorVersion string `json:"extractor_version"`
+	LastUpdated      string   `json:"last_updated"`
+	DataSource       string   `json:"data_source"`
+	CacheAgeSeconds  int64    `json:"cache_age_seconds,omitempty"`
+	UpdateFrequency  string   `json:"update_frequency"`
+	ExtractorVersion string   `json:"extractor_version"`
+	TVSPolicy        []string `json:"tvs_policy"`
 }
 
 // Summary aggregates high-level metrics for dashboards.
@@ -25,20 +30,27 @@ type Summary struct {
 	Categories        []string `json:"categories"`
 }
 
-// Metrics surfaces change and growth indicators for dashboards.
+// Metrics surfaces change and growth indicators for dashboards. Analytics
+// holds rolling statistics over the chart history.
 type Metrics struct {
-	CurrentTVS             float64  `json:"current_tvs"`
-	Change24h              *float64 `json:"change_24h,omitempty"`
-	Change7d               *float64 `json:"change_7d,omitempty"`
-	Change30d              *float64 `json:"change_30d,omitempty"`
-	ProtocolCountChange7d  *int     `json:"protocol_count_change_7d,omitempty"`
-	ProtocolCountChange30d *int     `json:"protocol_count_change_30d,omitempty"`
+	CurrentTVS             float64               `json:"current_tvs"`
+	Change24h              *float64              `json:"change_24h,omitempty"`
+	Change7d               *float64              `json:"change_7d,omitempty"`
+	Change30d              *float64              `json:"change_30d,omitempty"`
+	ProtocolCountChange7d  *int                  `json:"protocol_count_change_7d,omitempty"`
+	ProtocolCountChange30d *int                  `json:"protocol_count_change_30d,omitempty"`
+	Analytics              *aggregator.Analytics `json:"analytics,omitempty"`
 }
 
-// Breakdown provides per-chain and per-category details.
+// Breakdown provides per-chain and per-category details. ByCategory uses the
+// source categories; ByCategoryMapped the reporting taxonomy, when configured.
+// Concentration summarises how concentrated TVS is across protocols, chains
+// and categories.
 type Breakdown struct {
-	ByChain    []aggregator.ChainBreakdown    `json:"by_chain"`
-	ByCategory []aggregator.CategoryBreakdown `json:"by_category"`
+	ByChain          []aggregator.ChainBreakdown      `json:"by_chain"`
+	ByCategory       []aggregator.CategoryBreakdown   `json:"by_category"`
+	ByCategoryMapped []aggregator.CategoryBreakdown   `json:"by_category_mapped,omitempty"`
+	Concentration    *aggregator.ConcentrationMetrics `json:"concentration,omitempty"`
 }
 
 // FullOutput is the complete output including historical snapshots.
@@ -51,7 +63,12 @@ type FullOutput struct {
 	Breakdown    Breakdown                       `json:"breakdown"`
 	Protocols    []aggregator.AggregatedProtocol `json:"protocols"`
 	ChartHistory []aggregator.ChartDataPoint     `json:"chart_history"`
-	Historical   []aggregator.Snapshot           `json:"historical"`
+	// ChainShareHistory is the daily share of each chain's total TVL secured
+	// by the oracle; omitted when chain TVL data is unavailable.
+	ChainShareHistory map[string][]aggregator.ChainSharePoint `json:"chain_share_history,omitempty"`
+	Historical        []aggregator.Snapshot                   `json:"historical"`
+	// Anomalies lists the TVS moves flagged this cycle.
+	Anomalies []aggregator.Anomaly `json:"anomalies,omitempty"`
 }
 
 // SummaryOutput is the compact snapshot-only output.
@@ -63,4 +80,5 @@ type SummaryOutput struct {
 	Metrics      Metrics                         `json:"metrics"`
 	Breakdown    Breakdown                       `json:"breakdown"`
 	TopProtocols []aggregator.AggregatedProtocol `json:"top_protocols"`
+	Anomalies    []aggregator.Anomaly            `json:"anomalies,omitempty"`
 }
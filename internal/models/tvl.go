@@ -49,33 +49,58 @@ type TVLHistoryItem struct {
 type TVLOutput struct {
 	Version   string                       `json:"version"`
 	Metadata  TVLOutputMetadata            `json:"metadata"`
+	Breakdown TVLBreakdown                 `json:"breakdown"`
 	Protocols map[string]TVLOutputProtocol `json:"protocols"`
 }
 
+// TVLBreakdown splits current TVL by source category and, when a category
+// taxonomy is configured, by reporting category.
+type TVLBreakdown struct {
+	ByCategory       []TVLCategoryBreakdown `json:"by_category"`
+	ByCategoryMapped []TVLCategoryBreakdown `json:"by_category_mapped,omitempty"`
+}
+
+// TVLCategoryBreakdown is one category's share of current TVL. RawCategories
+// lists the source categories grouped into a reporting category.
+type TVLCategoryBreakdown struct {
+	Category      string   `json:"category"`
+	TVL           float64  `json:"tvl"`
+	Percentage    float64  `json:"percentage"`
+	ProtocolCount int      `json:"protocol_count"`
+	RawCategories []string `json:"raw_categories,omitempty"`
+}
+
 // TVLOutputMetadata captures top-level counts and the generation timestamp in
 // RFC3339 format. Counts enable quick summaries without scanning the map.
 type TVLOutputMetadata struct {
 	LastUpdated         string `json:"last_updated"`
 	ProtocolCount       int    `json:"protocol_count"`
 	CustomProtocolCount int    `json:"custom_protocol_count"`
+	// UnmappedCategories lists source categories the taxonomy does not map.
+	UnmappedCategories []string `json:"unmapped_categories,omitempty"`
 }
 
 // TVLOutputProtocol is the contract for per-protocol entries in tvl-data.json.
 // IntegrationDate is nullable to preserve the distinction between missing and
-// zero values. TVLHistory must contain the full, unfiltered history.
+// zero values. TVLHistory is never filtered by date. ChainTVLHistory holds the
+// per-chain series; when ChainScope is set, it and CurrentTVL/TVLHistory cover
+// only those chains, and are empty when DefiLlama reports none of them.
 type TVLOutputProtocol struct {
-	Name            string           `json:"name"`
-	Slug            string           `json:"slug"`
-	Source          string           `json:"source"`
-	IsOngoing       bool             `json:"is_ongoing"`
-	URL             string           `json:"url"`
-	SimpleTVSRatio  float64          `json:"simple_tvs_ratio"`
-	IntegrationDate *int64           `json:"integration_date"`
-	DocsProof       *string          `json:"docs_proof"`
-	GitHubProof     *string          `json:"github_proof"`
-	IsDefillama     bool             `json:"is_defillama"` // True if listed in DefiLlama's /oracles endpoint
-	CurrentTVL      float64          `json:"current_tvl"`
-	TVLHistory      []TVLHistoryItem `json:"tvl_history"`
+	Name            string                      `json:"name"`
+	Slug            string                      `json:"slug"`
+	Source          string                      `json:"source"`
+	IsOngoing       bool                        `json:"is_ongoing"`
+	URL             string                      `json:"url"`
+	SimpleTVSRatio  float64                     `json:"simple_tvs_ratio"`
+	IntegrationDate *int64                      `json:"integration_date"`
+	DocsProof       *string                     `json:"docs_proof"`
+	GitHubProof     *string                     `json:"github_proof"`
+	IsDefillama     bool                        `json:"is_defillama"` // True if listed in DefiLlama's /oracles endpoint
+	MappedCategory  string                      `json:"mapped_category,omitempty"`
+	CurrentTVL      float64                     `json:"current_tvl"`
+	TVLHistory      []TVLHistoryItem            `json:"tvl_history"`
+	ChainScope      []string                    `json:"chain_scope,omitempty"`
+	ChainTVLHistory map[string][]TVLHistoryItem `json:"chain_tvl_history,omitempty"`
 }
 
 // CustomDataOutput captures protocols supplied via custom-data files. These
@@ -85,29 +110,36 @@ type TVLOutputProtocol struct {
 type CustomDataOutput struct {
 	Version   string                           `json:"version"`
 	Metadata  CustomDataOutputMetadata         `json:"metadata"`
+	Breakdown TVLBreakdown                     `json:"breakdown"`
 	Protocols map[string]CustomDataOutputEntry `json:"protocols"`
 }
 
 // CustomDataOutputMetadata provides high-level counts and timestamps.
+// UnmappedCategories lists source categories the taxonomy does not map.
 type CustomDataOutputMetadata struct {
-	LastUpdated   string `json:"last_updated"`
-	ProtocolCount int    `json:"protocol_count"`
+	LastUpdated        string   `json:"last_updated"`
+	ProtocolCount      int      `json:"protocol_count"`
+	UnmappedCategories []string `json:"unmapped_categories,omitempty"`
 }
 
 // CustomDataOutputEntry mirrors TVLOutputProtocol but includes Category/Chains.
+// TVLHistory stays as merged from custom data; ChainTVLHistory is limited to
+// Chains when the protocol's chains were supplied by custom config.
 type CustomDataOutputEntry struct {
-	Name            string           `json:"name"`
-	Slug            string           `json:"slug"`
-	Source          string           `json:"source"`
-	IsOngoing       bool             `json:"is_ongoing"`
-	URL             string           `json:"url"`
-	SimpleTVSRatio  float64          `json:"simple_tvs_ratio"`
-	IntegrationDate *int64           `json:"integration_date"`
-	DocsProof       *string          `json:"docs_proof"`
-	GitHubProof     *string          `json:"github_proof"`
-	IsDefillama     bool             `json:"is_defillama"`
-	Category        string           `json:"category,omitempty"`
-	Chains          []string         `json:"chains,omitempty"`
-	CurrentTVL      float64          `json:"current_tvl"`
-	TVLHistory      []TVLHistoryItem `json:"tvl_history"`
+	Name            string                      `json:"name"`
+	Slug            string                      `json:"slug"`
+	Source          string                      `json:"source"`
+	IsOngoing       bool                        `json:"is_ongoing"`
+	URL             string                      `json:"url"`
+	SimpleTVSRatio  float64                     `json:"simple_tvs_ratio"`
+	IntegrationDate *int64                      `json:"integration_date"`
+	DocsProof       *string                     `json:"docs_proof"`
+	GitHubProof     *string                     `json:"github_proof"`
+	IsDefillama     bool                        `json:"is_defillama"`
+	Category        string                      `json:"category,omitempty"`
+	MappedCategory  string                      `json:"mapped_category,omitempty"`
+	Chains          []string                    `json:"chains,omitempty"`
+	CurrentTVL      float64                     `json:"current_tvl"`
+	TVLHistory      []TVLHistoryItem            `json:"tvl_history"`
+	ChainTVLHistory map[string][]TVLHistoryItem `json:"chain_tvl_history,omitempty"`
 }
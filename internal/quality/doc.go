@@ -0,0 +1,3 @@
+// Package quality checks aggregation results against configurable data-quality
+// rules before outputs are published.
+package quality
@@ -0,0 +1,241 @@
+package quality
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/aggregator"
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+// Rule names, matching their keys under the quality config section.
+const (
+	RuleTVSSumVsReference = "tvs_sum_vs_reference"
+	RuleMinProtocolCount  = "min_protocol_count"
+	RuleMaxTVSDeltaPct    = "max_tvs_delta_pct"
+	RuleMinTVSCoveragePct = "min_tvs_coverage_pct"
+	RuleNoEmptyChainKeys  = "no_empty_chain_keys"
+)
+
+// Rule severities.
+const (
+	SeverityWarn         = "warn"
+	SeverityBlockPublish = "block_publish"
+	SeverityFail         = "fail"
+)
+
+// Rule statuses. A rule is skipped when the data it needs is missing.
+const (
+	StatusPass    = "pass"
+	StatusFail    = "fail"
+	StatusSkipped = "skipped"
+)
+
+// Report outcomes. OutcomeBlocked means a block_publish rule failed and
+// OutcomeFailed that a fail rule did; fail wins over block.
+const (
+	OutcomePublish = "publish"
+	OutcomeBlocked = "blocked"
+	OutcomeFailed  = "failed"
+)
+
+// Input is what the rules are checked against. ReferenceTVS is the chart's
+// latest total value secured; History holds the stored snapshots.
+type Input struct {
+	Result       *aggregator.AggregationResult
+	ReferenceTVS float64
+	History      []aggregator.Snapshot
+	// LastEvaluatedTVS and LastEvaluatedAt are the total and timestamp seen
+	// by the previous evaluation, which may not have been published. Zero
+	// means unknown.
+	LastEvaluatedTVS float64
+	LastEvaluatedAt  int64
+}
+
+// Result is the outcome of one rule. Value is what the rule measured, in the
+// same unit as Threshold.
+type Result struct {
+	Rule      string  `json:"rule"`
+	Severity  string  `json:"severity"`
+	Status    string  `json:"status"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Message   string  `json:"message,omitempty"`
+}
+
+// Report is the machine-readable outcome of a quality check, written as the
+// quality report.
+type Report struct {
+	Timestamp int64    `json:"timestamp"`
+	Outcome   string   `json:"outcome"`
+	Results   []Result `json:"results"`
+}
+
+// Blocked reports whether outputs must not be published.
+func (r *Report) Blocked() bool {
+	return r != nil && r.Outcome != OutcomePublish
+}
+
+// Failed reports whether the cycle must fail.
+func (r *Report) Failed() bool {
+	return r != nil && r.Outcome == OutcomeFailed
+}
+
+// Failures returns the failed results with the given severity.
+func (r *Report) Failures(severity string) []Result {
+	if r == nil {
+		return nil
+	}
+	var out []Result
+	for _, res := range r.Results {
+		if res.Status == StatusFail && res.Severity == severity {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+type check func(in Input, threshold float64) Result
+
+var checks = map[string]check{
+	RuleTVSSumVsReference: checkTVSSumVsReference,
+	RuleMinProtocolCount:  checkMinProtocolCount,
+	RuleMaxTVSDeltaPct:    checkMaxTVSDeltaPct,
+	RuleMinTVSCoveragePct: checkMinTVSCoveragePct,
+	RuleNoEmptyChainKeys:  checkNoEmptyChainKeys,
+}
+
+// Evaluate checks in against the enabled rules, in rule name order. It
+// returns nil when the result is nil.
+func Evaluate(cfg config.QualityConfig, in Input) *Report {
+	if in.Result == nil {
+		return nil
+	}
+
+	rules := cfg.Rules()
+	names := make([]string, 0, len(rules))
+	for name, rule := range rules {
+		if rule.Enabled && checks[name] != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	report := &Report{Timestamp: in.Result.Timestamp, Outcome: OutcomePublish, Results: []Result{}}
+	for _, name := range names {
+		rule := rules[name]
+		res := checks[name](in, rule.Threshold)
+		res.Rule = name
+		res.Severity = strings.ToLower(strings.TrimSpace(rule.Severity))
+		res.Threshold = rule.Threshold
+		report.Results = append(report.Results, res)
+
+		if res.Status != StatusFail {
+			continue
+		}
+		switch res.Severity {
+		case SeverityFail:
+			report.Outcome = OutcomeFailed
+		case SeverityBlockPublish:
+			if report.Outcome == OutcomePublish {
+				report.Outcome = OutcomeBlocked
+			}
+		}
+	}
+	return report
+}
+
+func checkTVSSumVsReference(in Input, threshold float64) Result {
+	if in.ReferenceTVS <= 0 {
+		return Result{Status: StatusSkipped, Message: "no reference total_value_secured"}
+	}
+	var sum float64
+	for _, p := range in.Result.Protocols {
+		sum += p.TVS
+	}
+	diffPct := math.Abs(sum-in.ReferenceTVS) / in.ReferenceTVS * 100
+	return Result{
+		Status:  statusOf(diffPct <= threshold),
+		Value:   diffPct,
+		Message: fmt.Sprintf("protocol TVS %.2f vs reference %.2f", sum, in.ReferenceTVS),
+	}
+}
+
+func checkMinProtocolCount(in Input, threshold float64) Result {
+	count := float64(in.Result.TotalProtocols)
+	return Result{Status: statusOf(count >= threshold), Value: count}
+}
+
+func checkMaxTVSDeltaPct(in Input, threshold float64) Result {
+	var previous *aggregator.Snapshot
+	for i := range in.History {
+		s := &in.History[i]
+		if s.Timestamp < in.Result.Timestamp && (previous == nil || s.Timestamp > previous.Timestamp) {
+			previous = s
+		}
+	}
+	if previous == nil || previous.TVS <= 0 {
+		return Result{Status: StatusSkipped, Message: "no previous snapshot with TVS"}
+	}
+	deltaPct := math.Abs(aggregator.CalculatePercentageChange(previous.TVS, in.Result.TotalTVS))
+	if deltaPct > threshold && in.LastEvaluatedTVS > 0 && in.LastEvaluatedAt > 0 && in.LastEvaluatedAt < in.Result.Timestamp {
+		// A move that newer upstream data shows again is real, not a glitch.
+		// Without this, a blocked cycle never replaces the stale snapshot and
+		// every later cycle would be blocked by the same move. Re-evaluating
+		// the same timestamp is not a confirmation.
+		if math.Abs(aggregator.CalculatePercentageChange(in.LastEvaluatedTVS, in.Result.TotalTVS)) <= threshold {
+			return Result{
+				Status:  StatusPass,
+				Value:   deltaPct,
+				Message: fmt.Sprintf("TVS %.2f vs previous %.2f, confirmed by last evaluated %.2f", in.Result.TotalTVS, previous.TVS, in.LastEvaluatedTVS),
+			}
+		}
+	}
+	return Result{
+		Status:  statusOf(deltaPct <= threshold),
+		Value:   deltaPct,
+		Message: fmt.Sprintf("TVS %.2f vs previous %.2f", in.Result.TotalTVS, previous.TVS),
+	}
+}
+
+func checkMinTVSCoveragePct(in Input, threshold float64) Result {
+	if in.Result.TotalProtocols == 0 {
+		return Result{Status: StatusSkipped, Message: "no protocols"}
+	}
+	coverage := float64(in.Result.ProtocolsWithTVS) / float64(in.Result.TotalProtocols) * 100
+	return Result{
+		Status:  statusOf(coverage >= threshold),
+		Value:   coverage,
+		Message: fmt.Sprintf("%d of %d protocols have TVS", in.Result.ProtocolsWithTVS, in.Result.TotalProtocols),
+	}
+}
+
+func checkNoEmptyChainKeys(in Input, _ float64) Result {
+	var found []string
+	for _, c := range in.Result.ChainBreakdown {
+		if strings.TrimSpace(c.Chain) == "" {
+			found = append(found, "chain_breakdown")
+		}
+	}
+	for _, p := range in.Result.Protocols {
+		for chain := range p.TVSByChain {
+			if strings.TrimSpace(chain) == "" {
+				found = append(found, p.Slug)
+			}
+		}
+	}
+	res := Result{Status: statusOf(len(found) == 0), Value: float64(len(found))}
+	if len(found) > 0 {
+		res.Message = "empty chain key in " + strings.Join(found, ", ")
+	}
+	return res
+}
+
+func statusOf(ok bool) string {
+	if ok {
+		return StatusPass
+	}
+	return StatusFail
+}
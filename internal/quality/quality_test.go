@@ -0,0 +1,183 @@
+package quality
+
+import (
+	"testing"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/aggregator"
+	"github.com/switchboard-xyz/defillama-extract/internal/config"
+)
+
+func allRules(severity string) config.QualityConfig {
+	return config.QualityConfig{
+		TVSSumVsReference: config.QualityRule{Enabled: true, Severity: severity, Threshold: 5},
+		MinProtocolCount:  config.QualityRule{Enabled: true, Severity: severity, Threshold: 2},
+		MaxTVSDeltaPct:    config.QualityRule{Enabled: true, Severity: severity, Threshold: 50},
+		MinTVSCoveragePct: config.QualityRule{Enabled: true, Severity: severity, Threshold: 50},
+		NoEmptyChainKeys:  config.QualityRule{Enabled: true, Severity: severity},
+	}
+}
+
+func resultsByRule(report *Report) map[string]Result {
+	out := make(map[string]Result, len(report.Results))
+	for _, r := range report.Results {
+		out[r.Rule] = r
+	}
+	return out
+}
+
+func TestEvaluate_AllPass(t *testing.T) {
+	in := Input{
+		Result: &aggregator.AggregationResult{
+			Timestamp:        200,
+			TotalProtocols:   2,
+			ProtocolsWithTVS: 2,
+			TotalTVS:         100,
+			Protocols: []aggregator.AggregatedProtocol{
+				{Slug: "a", TVS: 60, TVSByChain: map[string]float64{"Solana": 60}},
+				{Slug: "b", TVS: 40},
+			},
+			ChainBreakdown: []aggregator.ChainBreakdown{{Chain: "Solana", TVS: 100}},
+		},
+		ReferenceTVS: 102,
+		History:      []aggregator.Snapshot{{Timestamp: 100, TVS: 80}},
+	}
+
+	report := Evaluate(allRules(SeverityFail), in)
+
+	if report.Outcome != OutcomePublish || report.Blocked() || report.Failed() {
+		t.Fatalf("expected publish, got %+v", report)
+	}
+	if len(report.Results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(report.Results))
+	}
+	for _, r := range report.Results {
+		if r.Status != StatusPass {
+			t.Errorf("%s: status %s (%s)", r.Rule, r.Status, r.Message)
+		}
+	}
+	if got := resultsByRule(report)[RuleMaxTVSDeltaPct].Value; got != 25 {
+		t.Errorf("max_tvs_delta_pct value = %v, want 25", got)
+	}
+}
+
+func TestEvaluate_Failures(t *testing.T) {
+	in := Input{
+		Result: &aggregator.AggregationResult{
+			Timestamp:        200,
+			TotalProtocols:   1,
+			ProtocolsWithTVS: 0,
+			TotalTVS:         300,
+			Protocols: []aggregator.AggregatedProtocol{
+				{Slug: "a", TVS: 300, TVSByChain: map[string]float64{" ": 300}},
+			},
+			ChainBreakdown: []aggregator.ChainBreakdown{{Chain: "", TVS: 300}},
+		},
+		ReferenceTVS: 100,
+		History:      []aggregator.Snapshot{{Timestamp: 50, TVS: 10}, {Timestamp: 100, TVS: 100}, {Timestamp: 300, TVS: 1}},
+	}
+
+	report := Evaluate(allRules(SeverityWarn), in)
+
+	if report.Outcome != OutcomePublish {
+		t.Fatalf("warn failures must still publish, got %s", report.Outcome)
+	}
+	results := resultsByRule(report)
+	want := map[string]float64{
+		RuleTVSSumVsReference: 200,
+		RuleMinProtocolCount:  1,
+		RuleMaxTVSDeltaPct:    200,
+		RuleMinTVSCoveragePct: 0,
+		RuleNoEmptyChainKeys:  2,
+	}
+	for rule, value := range want {
+		r := results[rule]
+		if r.Status != StatusFail || r.Value != value || r.Severity != SeverityWarn {
+			t.Errorf("%s: got %+v, want fail with value %v", rule, r, value)
+		}
+	}
+	if got := len(report.Failures(SeverityWarn)); got != 5 {
+		t.Errorf("Failures(warn) = %d, want 5", got)
+	}
+}
+
+func TestEvaluate_Outcome(t *testing.T) {
+	cfg := config.QualityConfig{
+		MinProtocolCount:  config.QualityRule{Enabled: true, Severity: "block_publish", Threshold: 5},
+		MinTVSCoveragePct: config.QualityRule{Enabled: true, Severity: "warn", Threshold: 50},
+	}
+	in := Input{Result: &aggregator.AggregationResult{TotalProtocols: 2}}
+
+	report := Evaluate(cfg, in)
+	if report.Outcome != OutcomeBlocked || !report.Blocked() || report.Failed() {
+		t.Fatalf("expected blocked, got %+v", report)
+	}
+
+	cfg.MinTVSCoveragePct.Severity = "FAIL"
+	report = Evaluate(cfg, in)
+	if report.Outcome != OutcomeFailed || !report.Blocked() || !report.Failed() {
+		t.Fatalf("expected failed, got %+v", report)
+	}
+}
+
+func TestEvaluate_MaxTVSDeltaConfirmedByLastEvaluated(t *testing.T) {
+	cfg := config.QualityConfig{
+		MaxTVSDeltaPct: config.QualityRule{Enabled: true, Severity: "block_publish", Threshold: 50},
+	}
+	history := []aggregator.Snapshot{{Timestamp: 100, TVS: 100}}
+
+	tests := []struct {
+		name            string
+		lastEvaluated   float64
+		lastEvaluatedAt int64
+		want            string
+	}{
+		{name: "first sighting blocks", want: StatusFail},
+		{name: "sustained move passes", lastEvaluated: 290, lastEvaluatedAt: 150, want: StatusPass},
+		{name: "same timestamp is not a confirmation", lastEvaluated: 300, lastEvaluatedAt: 200, want: StatusFail},
+		{name: "further jump still blocks", lastEvaluated: 150, lastEvaluatedAt: 150, want: StatusFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := Input{
+				Result:           &aggregator.AggregationResult{Timestamp: 200, TotalTVS: 300},
+				History:          history,
+				LastEvaluatedTVS: tt.lastEvaluated,
+				LastEvaluatedAt:  tt.lastEvaluatedAt,
+			}
+			got := resultsByRule(Evaluate(cfg, in))[RuleMaxTVSDeltaPct]
+			if got.Status != tt.want {
+				t.Errorf("status = %s, want %s (%s)", got.Status, tt.want, got.Message)
+			}
+			if got.Value != 200 {
+				t.Errorf("value = %v, want 200", got.Value)
+			}
+		})
+	}
+}
+
+func TestEvaluate_SkipsWithoutData(t *testing.T) {
+	in := Input{Result: &aggregator.AggregationResult{Timestamp: 100}}
+
+	report := Evaluate(allRules(SeverityFail), in)
+
+	results := resultsByRule(report)
+	for _, rule := range []string{RuleTVSSumVsReference, RuleMaxTVSDeltaPct, RuleMinTVSCoveragePct} {
+		if results[rule].Status != StatusSkipped {
+			t.Errorf("%s: status %s, want skipped", rule, results[rule].Status)
+		}
+	}
+	if results[RuleMinProtocolCount].Status != StatusFail {
+		t.Errorf("min_protocol_count should fail on an empty result")
+	}
+}
+
+func TestEvaluate_DisabledRulesAndNilResult(t *testing.T) {
+	if Evaluate(allRules(SeverityFail), Input{}) != nil {
+		t.Fatalf("expected nil report for nil result")
+	}
+	report := Evaluate(config.QualityConfig{}, Input{Result: &aggregator.AggregationResult{}})
+	if len(report.Results) != 0 || report.Outcome != OutcomePublish {
+		t.Fatalf("expected empty publishing report, got %+v", report)
+	}
+}
@@ -22,7 +22,8 @@ type outputHistoryExtract struct {
 
 // CreateSnapshot builds a Snapshot from an AggregationResult for historical tracking.
 // It maps aggregation output fields to the snapshot structure, ensuring TVSByChain
-// is always initialized for safe JSON marshaling.
+// is always initialized for safe JSON marshaling, and records each protocol's TVS
+// and rank by slug along with the concentration metrics.
 func CreateSnapshot(result *aggregator.AggregationResult) aggregator.Snapshot {
 	if result == nil {
 		return aggregator.Snapshot{
@@ -37,6 +38,16 @@ func CreateSnapshot(result *aggregator.AggregationResult) aggregator.Snapshot {
 
 	chainCount := len(result.ActiveChains)
 
+	var protocols map[string]aggregator.ProtocolSnapshot
+	if len(result.Protocols) > 0 {
+		protocols = make(map[string]aggregator.ProtocolSnapshot, len(result.Protocols))
+		for _, p := range result.Protocols {
+			if p.Slug != "" {
+				protocols[p.Slug] = aggregator.ProtocolSnapshot{TVS: p.TVS, Rank: p.Rank, QuarantineCycles: p.QuarantineCycles}
+			}
+		}
+	}
+
 	return aggregator.Snapshot{
 		Timestamp:     result.Timestamp,
 		Date:          time.Unix(result.Timestamp, 0).UTC().Format("2006-01-02"),
@@ -44,6 +55,8 @@ func CreateSnapshot(result *aggregator.AggregationResult) aggregator.Snapshot {
 		TVSByChain:    tvsByChain,
 		ProtocolCount: result.TotalProtocols,
 		ChainCount:    chainCount,
+		Protocols:     protocols,
+		Concentration: result.Concentration,
 	}
 }
 
@@ -56,6 +56,30 @@ func TestCreateSnapshot_PopulatesFields(t *testing.T) {
 	}
 }
 
+func TestCreateSnapshot_RecordsProtocols(t *testing.T) {
+	result := &aggregator.AggregationResult{
+		Timestamp: 1700000000,
+		Protocols: []aggregator.AggregatedProtocol{
+			{Slug: "kamino", TVS: 500, Rank: 1},
+			{Slug: "drift", TVS: 200, Rank: 2},
+		},
+		Concentration: &aggregator.ConcentrationMetrics{Protocols: aggregator.Concentration{Count: 2, HHI: 5918.37}},
+	}
+
+	snapshot := CreateSnapshot(result)
+
+	want := map[string]aggregator.ProtocolSnapshot{
+		"kamino": {TVS: 500, Rank: 1},
+		"drift":  {TVS: 200, Rank: 2},
+	}
+	if !reflect.DeepEqual(snapshot.Protocols, want) {
+		t.Fatalf("protocols mismatch: got %+v want %+v", snapshot.Protocols, want)
+	}
+	if snapshot.Concentration != result.Concentration {
+		t.Fatalf("concentration not recorded: %+v", snapshot.Concentration)
+	}
+}
+
 func TestCreateSnapshot_EmptyChainBreakdown(t *testing.T) {
 	result := &aggregator.AggregationResult{
 		TotalTVS:       0,
@@ -24,6 +24,11 @@ type State struct {
 	SnapshotCount     int     `json:"snapshot_count"`
 	OldestSnapshot    int64   `json:"oldest_snapshot"`
 	NewestSnapshot    int64   `json:"newest_snapshot"`
+	// LastEvaluatedTVS and LastEvaluatedAt are the TVS and upstream
+	// timestamp of the most recent cycle the quality rules saw, including
+	// cycles that were blocked from publishing.
+	LastEvaluatedTVS float64 `json:"last_evaluated_tvs,omitempty"`
+	LastEvaluatedAt  int64   `json:"last_evaluated_at,omitempty"`
 }
 
 // StateManager handles state and history operations for incremental extraction.
@@ -146,6 +151,8 @@ func (sm *StateManager) UpdateState(oracleName string, ts int64, count int, tvs
 		LastProtocolCount: count,
 		LastTVS:           tvs,
 		SnapshotCount:     len(snapshots),
+		LastEvaluatedTVS:  tvs,
+		LastEvaluatedAt:   ts,
 	}
 
 	if len(snapshots) > 0 {
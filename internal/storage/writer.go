@@ -62,6 +62,7 @@ func GenerateFullOutput(result *aggregator.AggregationResult, history []aggregat
 			DataSource:       dataSource,
 			UpdateFrequency:  schedulerInterval(cfg),
 			ExtractorVersion: extractorVersion,
+			TVSPolicy:        aggregator.NewTVSPolicy(cfg.Aggregation.TVSPolicy).Components(),
 		},
 		Summary: models.Summary{
 			TotalValueSecured: result.TotalTVS,
@@ -76,14 +77,19 @@ func GenerateFullOutput(result *aggregator.AggregationResult, history []aggregat
 			Change30d:              result.ChangeMetrics.Change30d,
 			ProtocolCountChange7d:  result.ChangeMetrics.ProtocolCountChange7d,
 			ProtocolCountChange30d: result.ChangeMetrics.ProtocolCountChange30d,
+			Analytics:              result.Analytics,
 		},
 		Breakdown: models.Breakdown{
-			ByChain:    result.ChainBreakdown,
-			ByCategory: result.CategoryBreakdown,
+			ByChain:          result.ChainBreakdown,
+			ByCategory:       result.CategoryBreakdown,
+			ByCategoryMapped: result.MappedCategoryBreakdown,
+			Concentration:    result.Concentration,
 		},
-		Protocols:    result.Protocols,
-		ChartHistory: chartHistory,
-		Historical:   history,
+		Protocols:         result.Protocols,
+		ChartHistory:      chartHistory,
+		ChainShareHistory: result.ChainShareHistory,
+		Historical:        history,
+		Anomalies:         result.Anomalies,
 	}
 }
 
@@ -114,6 +120,7 @@ func GenerateSummaryOutput(result *aggregator.AggregationResult, cfg *config.Con
 			DataSource:       dataSource,
 			UpdateFrequency:  schedulerInterval(cfg),
 			ExtractorVersion: extractorVersion,
+			TVSPolicy:        aggregator.NewTVSPolicy(cfg.Aggregation.TVSPolicy).Components(),
 		},
 		Summary: models.Summary{
 			TotalValueSecured: result.TotalTVS,
@@ -128,12 +135,16 @@ func GenerateSummaryOutput(result *aggregator.AggregationResult, cfg *config.Con
 			Change30d:              result.ChangeMetrics.Change30d,
 			ProtocolCountChange7d:  result.ChangeMetrics.ProtocolCountChange7d,
 			ProtocolCountChange30d: result.ChangeMetrics.ProtocolCountChange30d,
+			Analytics:              result.Analytics,
 		},
 		Breakdown: models.Breakdown{
-			ByChain:    result.ChainBreakdown,
-			ByCategory: result.CategoryBreakdown,
+			ByChain:          result.ChainBreakdown,
+			ByCategory:       result.CategoryBreakdown,
+			ByCategoryMapped: result.MappedCategoryBreakdown,
+			Concentration:    result.Concentration,
 		},
 		TopProtocols: topProtocols,
+		Anomalies:    result.Anomalies,
 	}
 }
 
@@ -305,6 +305,33 @@ func TestUpdateFrequency_UsesSchedulerInterval(t *testing.T) {
 	}
 }
 
+func TestMetadata_RecordsTVSPolicy(t *testing.T) {
+	cfg := sampleConfig()
+	cfg.Aggregation.TVSPolicy = []string{"Staking", "borrowed"}
+	want := []string{"borrowed", "staking"}
+
+	full := GenerateFullOutput(sampleAggregationResult(), nil, chartHistorySample(), cfg)
+	if !reflect.DeepEqual(full.Metadata.TVSPolicy, want) {
+		t.Fatalf("full tvs_policy = %v, want %v", full.Metadata.TVSPolicy, want)
+	}
+
+	summary := GenerateSummaryOutput(sampleAggregationResult(), cfg)
+	if !reflect.DeepEqual(summary.Metadata.TVSPolicy, want) {
+		t.Fatalf("summary tvs_policy = %v, want %v", summary.Metadata.TVSPolicy, want)
+	}
+}
+
+func TestMetrics_CarryAnalytics(t *testing.T) {
+	result := sampleAggregationResult()
+	result.Analytics = aggregator.CalculateAnalytics(chartHistorySample())
+
+	full := GenerateFullOutput(result, nil, chartHistorySample(), sampleConfig())
+	summary := GenerateSummaryOutput(result, sampleConfig())
+	if full.Metrics.Analytics == nil || full.Metrics.Analytics != summary.Metrics.Analytics {
+		t.Fatalf("analytics not carried: full %+v summary %+v", full.Metrics.Analytics, summary.Metrics.Analytics)
+	}
+}
+
 func TestWriteAtomic_Success(t *testing.T) {
 	dir := t.TempDir()
 	target := filepath.Join(dir, "state.json")
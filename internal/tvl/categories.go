@@ -0,0 +1,94 @@
+package tvl
+
+import (
+	"sort"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/aggregator"
+	"github.com/switchboard-xyz/defillama-extract/internal/models"
+)
+
+// applyTVLCategories fills tvl-data.json's category breakdowns from the merged
+// protocols' categories and their current TVL, and sets each entry's mapped
+// category when taxonomy is non-nil.
+func applyTVLCategories(output *models.TVLOutput, protocols []models.MergedProtocol, taxonomy *aggregator.CategoryTaxonomy) {
+	items := make([]aggregator.AggregatedProtocol, 0, len(output.Protocols))
+	for _, p := range protocols {
+		if entry, ok := output.Protocols[p.Slug]; ok {
+			items = append(items, aggregator.AggregatedProtocol{Slug: p.Slug, Category: p.Category, TVS: entry.CurrentTVL})
+		}
+	}
+
+	output.Breakdown, output.Metadata.UnmappedCategories = categoryBreakdowns(items, taxonomy)
+	for _, item := range items {
+		entry := output.Protocols[item.Slug]
+		entry.MappedCategory = item.MappedCategory
+		output.Protocols[item.Slug] = entry
+	}
+}
+
+// applyCustomDataCategories fills custom-data.json's category breakdowns from
+// each entry's category and current TVL, and sets each entry's mapped
+// category when taxonomy is non-nil.
+func applyCustomDataCategories(output *models.CustomDataOutput, taxonomy *aggregator.CategoryTaxonomy) {
+	items := make([]aggregator.AggregatedProtocol, 0, len(output.Protocols))
+	for slug, entry := range output.Protocols {
+		items = append(items, aggregator.AggregatedProtocol{Slug: slug, Category: entry.Category, TVS: entry.CurrentTVL})
+	}
+
+	output.Breakdown, output.Metadata.UnmappedCategories = categoryBreakdowns(items, taxonomy)
+	for _, item := range items {
+		entry := output.Protocols[item.Slug]
+		entry.MappedCategory = item.MappedCategory
+		output.Protocols[item.Slug] = entry
+	}
+}
+
+// categoryBreakdowns maps items in place and returns the raw and mapped
+// breakdowns of their TVL (carried in TVS) with the unmapped category names.
+func categoryBreakdowns(items []aggregator.AggregatedProtocol, taxonomy *aggregator.CategoryTaxonomy) (models.TVLBreakdown, []string) {
+	aggregator.MapProtocolCategories(items, taxonomy)
+
+	breakdown := models.TVLBreakdown{
+		ByCategory: toTVLCategoryBreakdown(aggregator.CalculateCategoryBreakdown(items)),
+	}
+	if taxonomy == nil {
+		return breakdown, nil
+	}
+
+	breakdown.ByCategoryMapped = toTVLCategoryBreakdown(aggregator.CalculateMappedCategoryBreakdown(items))
+	var unmapped []string
+	for _, u := range aggregator.FindUnmappedCategories(items, taxonomy) {
+		unmapped = append(unmapped, u.Category)
+	}
+	return breakdown, unmapped
+}
+
+func toTVLCategoryBreakdown(breakdown []aggregator.CategoryBreakdown) []models.TVLCategoryBreakdown {
+	result := make([]models.TVLCategoryBreakdown, 0, len(breakdown))
+	for _, b := range breakdown {
+		result = append(result, models.TVLCategoryBreakdown{
+			Category:      b.Category,
+			TVL:           b.TVS,
+			Percentage:    b.Percentage,
+			ProtocolCount: b.ProtocolCount,
+			RawCategories: b.RawCategories,
+		})
+	}
+	return result
+}
+
+// mergeUnmapped returns the sorted union of unmapped category lists.
+func mergeUnmapped(lists ...[]string) []string {
+	seen := make(map[string]struct{})
+	for _, list := range lists {
+		for _, category := range list {
+			seen[category] = struct{}{}
+		}
+	}
+	merged := make([]string, 0, len(seen))
+	for category := range seen {
+		merged = append(merged, category)
+	}
+	sort.Strings(merged)
+	return merged
+}
@@ -0,0 +1,57 @@
+package tvl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/aggregator"
+	"github.com/switchboard-xyz/defillama-extract/internal/models"
+)
+
+func TestApplyTVLCategories(t *testing.T) {
+	output := &models.TVLOutput{Protocols: map[string]models.TVLOutputProtocol{
+		"kamino": {Slug: "kamino", CurrentTVL: 300},
+		"orca":   {Slug: "orca", CurrentTVL: 100},
+	}}
+	protocols := []models.MergedProtocol{
+		{Slug: "kamino", Category: "Lending"},
+		{Slug: "orca", Category: "Dexs"},
+		{Slug: "missing", Category: "Lending"},
+	}
+	taxonomy := aggregator.NewCategoryTaxonomy(map[string][]string{"Money Markets": {"lending"}}, nil)
+
+	applyTVLCategories(output, protocols, taxonomy)
+
+	if len(output.Breakdown.ByCategory) != 2 || output.Breakdown.ByCategory[0].Category != "Lending" || output.Breakdown.ByCategory[0].TVL != 300 {
+		t.Fatalf("raw breakdown = %+v", output.Breakdown.ByCategory)
+	}
+	mapped := output.Breakdown.ByCategoryMapped
+	if len(mapped) != 2 || mapped[0].Category != "Money Markets" || mapped[0].Percentage != 75 || !reflect.DeepEqual(mapped[0].RawCategories, []string{"Lending"}) {
+		t.Fatalf("mapped breakdown = %+v", mapped)
+	}
+	if got := output.Metadata.UnmappedCategories; !reflect.DeepEqual(got, []string{"Dexs"}) {
+		t.Fatalf("unmapped = %v", got)
+	}
+	if output.Protocols["kamino"].MappedCategory != "Money Markets" || output.Protocols["orca"].MappedCategory != "Dexs" {
+		t.Fatalf("entries = %+v", output.Protocols)
+	}
+}
+
+func TestApplyCustomDataCategories_WithoutTaxonomy(t *testing.T) {
+	output := &models.CustomDataOutput{Protocols: map[string]models.CustomDataOutputEntry{
+		"fogo-lend": {Slug: "fogo-lend", Category: "lending protocol", CurrentTVL: 40},
+		"blank":     {Slug: "blank", CurrentTVL: 10},
+	}}
+
+	applyCustomDataCategories(output, nil)
+
+	if len(output.Breakdown.ByCategory) != 2 || output.Breakdown.ByCategory[1].Category != "Uncategorized" {
+		t.Fatalf("raw breakdown = %+v", output.Breakdown.ByCategory)
+	}
+	if output.Breakdown.ByCategoryMapped != nil || output.Metadata.UnmappedCategories != nil {
+		t.Fatalf("expected no mapped output without a taxonomy, got %+v", output.Breakdown)
+	}
+	if output.Protocols["fogo-lend"].MappedCategory != "" {
+		t.Fatalf("mapped category should stay empty, got %+v", output.Protocols["fogo-lend"])
+	}
+}
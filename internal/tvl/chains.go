@@ -0,0 +1,135 @@
+package tvl
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/switchboard-xyz/defillama-extract/internal/api"
+	"github.com/switchboard-xyz/defillama-extract/internal/chainregistry"
+	"github.com/switchboard-xyz/defillama-extract/internal/models"
+)
+
+// dedupeTVLPoints converts DefiLlama TVL points into history items sorted by
+// timestamp. DefiLlama returns daily snapshots plus a real-time value, which
+// can produce two entries for the same date; the latest per date is kept.
+func dedupeTVLPoints(points []api.TVLDataPoint) []models.TVLHistoryItem {
+	dateMap := make(map[string]models.TVLHistoryItem, len(points))
+	for _, point := range points {
+		dateStr := time.Unix(point.Date, 0).UTC().Format("2006-01-02")
+		existing, exists := dateMap[dateStr]
+		if !exists || point.Date > existing.Timestamp {
+			dateMap[dateStr] = models.TVLHistoryItem{
+				Date:      dateStr,
+				Timestamp: point.Date,
+				TVL:       point.TotalLiquidityUSD,
+			}
+		}
+	}
+
+	history := make([]models.TVLHistoryItem, 0, len(dateMap))
+	for _, item := range dateMap {
+		history = append(history, item)
+	}
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Timestamp < history[j].Timestamp
+	})
+
+	return history
+}
+
+// chainScope returns the chains a protocol's output is limited to: the chains
+// configured for custom protocols, or none for auto-detected protocols whose
+// chain list is simply DefiLlama's.
+func chainScope(protocol models.MergedProtocol) []string {
+	if protocol.Source == "auto" {
+		return nil
+	}
+	return protocol.Chains
+}
+
+// chainHistories builds per-chain histories from chainTvls keyed by canonical
+// chain name in chains, skipping DefiLlama's breakdown keys (borrowed,
+// staking, ...). Series reported under two names for one chain are summed.
+// When scope is non-empty only chains named in it (case-insensitive, after
+// canonicalization) are kept. Nil is returned when no chain series remain.
+func chainHistories(tvl *api.ProtocolTVLResponse, scope []string, chains *chainregistry.Registry) map[string][]models.TVLHistoryItem {
+	if tvl == nil || len(tvl.ChainTvls) == 0 {
+		return nil
+	}
+
+	allowed := make(map[string]struct{}, len(scope))
+	for _, chain := range scope {
+		allowed[strings.ToLower(chains.Canonical(chain))] = struct{}{}
+	}
+
+	result := make(map[string][]models.TVLHistoryItem)
+	for key, series := range tvl.ChainTvls {
+		chain, extra := api.SplitChainTVLKey(key)
+		if extra != "" || chain == "" {
+			continue
+		}
+		chain = chains.Canonical(chain)
+		if len(allowed) > 0 {
+			if _, ok := allowed[strings.ToLower(chain)]; !ok {
+				continue
+			}
+		}
+		history := dedupeTVLPoints(series.TVL)
+		if existing, ok := result[chain]; ok {
+			history = sumChainHistories(map[string][]models.TVLHistoryItem{"existing": existing, "added": history})
+		}
+		result[chain] = history
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// sumChainHistories adds per-chain histories by date into one series sorted by
+// timestamp, keeping the latest timestamp seen for each date.
+func sumChainHistories(chains map[string][]models.TVLHistoryItem) []models.TVLHistoryItem {
+	byDate := make(map[string]models.TVLHistoryItem)
+	for _, history := range chains {
+		for _, item := range history {
+			total := byDate[item.Date]
+			total.Date = item.Date
+			total.TVL += item.TVL
+			if item.Timestamp > total.Timestamp {
+				total.Timestamp = item.Timestamp
+			}
+			byDate[item.Date] = total
+		}
+	}
+
+	summed := make([]models.TVLHistoryItem, 0, len(byDate))
+	for _, item := range byDate {
+		summed = append(summed, item)
+	}
+	sort.Slice(summed, func(i, j int) bool {
+		return summed[i].Timestamp < summed[j].Timestamp
+	})
+
+	return summed
+}
+
+// logUnmatchedChainScopes warns about protocols whose configured chains match
+// none of DefiLlama's per-chain series, so their scoped TVL is empty.
+func logUnmatchedChainScopes(logger *slog.Logger, output *models.TVLOutput) {
+	slugs := make([]string, 0)
+	for slug, p := range output.Protocols {
+		if len(p.ChainScope) > 0 && len(p.ChainTVLHistory) == 0 {
+			slugs = append(slugs, slug)
+		}
+	}
+	sort.Strings(slugs)
+	for _, slug := range slugs {
+		logger.Warn("tvl_chain_scope_unmatched",
+			"protocol", slug,
+			"chain_scope", output.Protocols[slug].ChainScope,
+		)
+	}
+}
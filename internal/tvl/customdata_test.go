@@ -381,6 +381,9 @@ func TestMergeCustomTVLData_PreservesAPIName(t *testing.T) {
 		TVL: []api.TVLDataPoint{
 			{Date: 10, TotalLiquidityUSD: 1},
 		},
+		ChainTvls: map[string]api.ChainTVL{
+			"Solana": {TVL: []api.TVLDataPoint{{Date: 10, TotalLiquidityUSD: 1}}},
+		},
 	}
 	custom := map[string][]models.TVLHistoryItem{
 		"slug": {
@@ -392,6 +395,9 @@ func TestMergeCustomTVLData_PreservesAPIName(t *testing.T) {
 	if result["slug"].Name != "ApiName" {
 		t.Fatalf("expected API name preserved, got %q", result["slug"].Name)
 	}
+	if len(result["slug"].ChainTvls["Solana"].TVL) != 1 {
+		t.Fatalf("expected API chain series preserved, got %+v", result["slug"].ChainTvls)
+	}
 }
 
 func writeFile(t *testing.T, path string, content string) {
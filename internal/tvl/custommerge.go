@@ -57,6 +57,7 @@ func mergeCustomTVLData(tvlData map[string]*api.ProtocolTVLResponse, custom map[
 
 		name := slug
 		currentChains := map[string]float64{}
+		var chainTvls map[string]api.ChainTVL
 		if existingResp != nil {
 			if existingResp.Name != "" {
 				name = existingResp.Name
@@ -64,12 +65,14 @@ func mergeCustomTVLData(tvlData map[string]*api.ProtocolTVLResponse, custom map[
 			if existingResp.CurrentChainTvls != nil {
 				currentChains = existingResp.CurrentChainTvls
 			}
+			chainTvls = existingResp.ChainTvls
 		}
 
 		result[slug] = &api.ProtocolTVLResponse{
 			Name:             name,
 			TVL:              mergedPoints,
 			CurrentChainTvls: currentChains,
+			ChainTvls:        chainTvls,
 		}
 
 		stats.ProtocolsWithCustomData++
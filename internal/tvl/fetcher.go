@@ -4,21 +4,27 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/switchboard-xyz/defillama-extract/internal/api"
 )
 
-// TVLClient defines the subset of api.Client used by the TVL pipeline to fetch
-// per-protocol TVL history. The real client already satisfies this interface.
-type TVLClient interface {
-	FetchProtocolTVL(ctx context.Context, slug string) (*api.ProtocolTVLResponse, error)
-}
+// TVLClient is the per-protocol TVL history source used by the TVL pipeline.
+// Every api.Source (live client, directory, fixture server) satisfies it.
+type TVLClient = api.ProtocolTVLSource
 
-// FetchAllTVL iterates protocol slugs sequentially, honoring the client's
-// built-in rate limiting (200ms). It returns a map of successful responses and
-// captures statistics for logging (AC8).
+// FetchAllTVL fetches protocol slugs one at a time. It is equivalent to
+// FetchAllTVLConcurrent with a concurrency of 1.
 func FetchAllTVL(ctx context.Context, client TVLClient, slugs []string, logger *slog.Logger) (map[string]*api.ProtocolTVLResponse, error) {
+	return FetchAllTVLConcurrent(ctx, client, slugs, 1, logger)
+}
+
+// FetchAllTVLConcurrent fetches protocol slugs with a bounded pool of workers.
+// Request pacing is left to the client, whose token-bucket limiter is shared by
+// every worker. It returns a map of successful responses, the error of the
+// earliest failing slug, and captures statistics for logging (AC8).
+func FetchAllTVLConcurrent(ctx context.Context, client TVLClient, slugs []string, concurrency int, logger *slog.Logger) (map[string]*api.ProtocolTVLResponse, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -28,9 +34,16 @@ func FetchAllTVL(ctx context.Context, client TVLClient, slugs []string, logger *
 	if client == nil {
 		return nil, fmt.Errorf("nil TVL client")
 	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(slugs) && len(slugs) > 0 {
+		concurrency = len(slugs)
+	}
 
 	results := make(map[string]*api.ProtocolTVLResponse, len(slugs))
-	var firstErr error
+	errs := make([]error, len(slugs))
+	var mu sync.Mutex
 	stats := struct {
 		total    int
 		success  int
@@ -40,28 +53,55 @@ func FetchAllTVL(ctx context.Context, client TVLClient, slugs []string, logger *
 	}{}
 
 	start := time.Now()
-	for _, slug := range slugs {
-		if err := ctx.Err(); err != nil {
-			return results, err
-		}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				slug := slugs[idx]
+				resp, err := client.FetchProtocolTVL(ctx, slug)
 
-		stats.total++
-		resp, err := client.FetchProtocolTVL(ctx, slug)
-		switch {
-		case err != nil:
-			stats.failed++
-			if firstErr == nil {
-				firstErr = err
+				mu.Lock()
+				stats.total++
+				switch {
+				case err != nil:
+					stats.failed++
+					errs[idx] = err
+					logger.Error("tvl_fetch_failed", "slug", slug, "error", err)
+				case resp == nil:
+					stats.notFound++
+					logger.Warn("tvl_protocol_not_found", "slug", slug)
+				default:
+					stats.success++
+					results[slug] = resp
+				}
+				mu.Unlock()
 			}
-			logger.Error("tvl_fetch_failed", "slug", slug, "error", err)
-		case resp == nil:
-			stats.notFound++
-			logger.Warn("tvl_protocol_not_found", "slug", slug)
-		default:
-			stats.success++
-			results[slug] = resp
+		}()
+	}
+
+	var ctxErr error
+dispatch:
+	for idx := range slugs {
+		if err := ctx.Err(); err != nil {
+			ctxErr = err
+			break
+		}
+		select {
+		case jobs <- idx:
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+			break dispatch
 		}
 	}
+	close(jobs)
+	wg.Wait()
+
+	if ctxErr != nil {
+		return results, ctxErr
+	}
 	stats.duration = time.Since(start)
 
 	logger.Info("tvl_fetch_complete",
@@ -69,8 +109,17 @@ func FetchAllTVL(ctx context.Context, client TVLClient, slugs []string, logger *
 		"success", stats.success,
 		"not_found", stats.notFound,
 		"failed", stats.failed,
+		"concurrency", concurrency,
 		"duration_ms", stats.duration.Milliseconds(),
 	)
 
+	var firstErr error
+	for _, err := range errs {
+		if err != nil {
+			firstErr = err
+			break
+		}
+	}
+
 	return results, firstErr
 }
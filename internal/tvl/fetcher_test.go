@@ -5,6 +5,7 @@ import (
 	"errors"
 	"log/slog"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -76,3 +77,58 @@ func TestFetchAllTVLSequentialRate(t *testing.T) {
 		t.Fatalf("expected sequential fetch taking >= %v, got %v", minExpected, elapsed)
 	}
 }
+
+type countingTVLClient struct {
+	delay    time.Duration
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (c *countingTVLClient) FetchProtocolTVL(ctx context.Context, slug string) (*api.ProtocolTVLResponse, error) {
+	c.mu.Lock()
+	c.inFlight++
+	if c.inFlight > c.peak {
+		c.peak = c.inFlight
+	}
+	c.mu.Unlock()
+
+	time.Sleep(c.delay)
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+	return &api.ProtocolTVLResponse{Name: slug}, nil
+}
+
+func TestFetchAllTVLConcurrentBoundsWorkers(t *testing.T) {
+	client := &countingTVLClient{delay: 30 * time.Millisecond}
+	slugs := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+	result, err := FetchAllTVLConcurrent(context.Background(), client, slugs, 3, slog.Default())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result) != len(slugs) {
+		t.Fatalf("expected %d results, got %d", len(slugs), len(result))
+	}
+	if client.peak > 3 {
+		t.Fatalf("expected at most 3 concurrent fetches, saw %d", client.peak)
+	}
+	if client.peak < 2 {
+		t.Fatalf("expected fetches to overlap, peak concurrency %d", client.peak)
+	}
+}
+
+func TestFetchAllTVLConcurrentReturnsEarliestError(t *testing.T) {
+	client := stubTVLClient{responses: map[string]*stubTVLResult{
+		"a": {resp: &api.ProtocolTVLResponse{Name: "A"}},
+		"b": {err: errors.New("first")},
+		"c": {err: errors.New("second")},
+	}}
+
+	_, err := FetchAllTVLConcurrent(context.Background(), client, []string{"a", "b", "c"}, 3, slog.Default())
+	if err == nil || err.Error() != "first" {
+		t.Fatalf("expected error of earliest slug, got %v", err)
+	}
+}
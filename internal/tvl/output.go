@@ -4,10 +4,10 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
-	"sort"
 	"time"
 
 	"github.com/switchboard-xyz/defillama-extract/internal/api"
+	"github.com/switchboard-xyz/defillama-extract/internal/chainregistry"
 	"github.com/switchboard-xyz/defillama-extract/internal/models"
 	"github.com/switchboard-xyz/defillama-extract/internal/storage"
 )
@@ -15,37 +15,29 @@ import (
 // MapToOutputProtocol converts a merged protocol plus its TVL data into the
 // output contract used by tvl-data.json. IntegrationDate is passed through
 // unchanged (nil for auto or missing custom dates) and the full TVL history is
-// preserved without filtering.
-func MapToOutputProtocol(protocol models.MergedProtocol, tvl *api.ProtocolTVLResponse) models.TVLOutputProtocol {
+// preserved without filtering. Per-chain history comes from chainTvls; for
+// custom protocols with configured chains, both the chain history and the
+// totals are limited to those chains, and are empty when no chainTvls series
+// matches them. Chain names are canonicalized by chains.
+func MapToOutputProtocol(protocol models.MergedProtocol, tvl *api.ProtocolTVLResponse, chains *chainregistry.Registry) models.TVLOutputProtocol {
 	history := make([]models.TVLHistoryItem, 0)
 	currentTVL := 0.0
+	var chainHistory map[string][]models.TVLHistoryItem
+	var scoped []string
 
 	if tvl != nil {
-		// Dedupe by date: DefiLlama returns daily snapshots plus a real-time value,
-		// which can produce two entries for the same date. Keep the latest per date.
-		dateMap := make(map[string]models.TVLHistoryItem)
-		for _, point := range tvl.TVL {
-			dateStr := time.Unix(point.Date, 0).UTC().Format("2006-01-02")
-			existing, exists := dateMap[dateStr]
-			if !exists || point.Date > existing.Timestamp {
-				dateMap[dateStr] = models.TVLHistoryItem{
-					Date:      dateStr,
-					Timestamp: point.Date,
-					TVL:       point.TotalLiquidityUSD,
-				}
+		history = dedupeTVLPoints(tvl.TVL)
+
+		scope := chains.CanonicalList(chainScope(protocol))
+		chainHistory = chainHistories(tvl, scope, chains)
+		if len(scope) > 0 {
+			history = make([]models.TVLHistoryItem, 0)
+			if chainHistory != nil {
+				history = sumChainHistories(chainHistory)
 			}
+			scoped = scope
 		}
 
-		// Build sorted history from deduped map
-		history = make([]models.TVLHistoryItem, 0, len(dateMap))
-		for _, item := range dateMap {
-			history = append(history, item)
-		}
-		// Sort by timestamp ascending
-		sort.Slice(history, func(i, j int) bool {
-			return history[i].Timestamp < history[j].Timestamp
-		})
-
 		if len(history) > 0 {
 			currentTVL = history[len(history)-1].TVL
 		}
@@ -68,59 +60,51 @@ func MapToOutputProtocol(protocol models.MergedProtocol, tvl *api.ProtocolTVLRes
 		IsDefillama:     protocol.IsDefillama,
 		CurrentTVL:      currentTVL,
 		TVLHistory:      history,
+		ChainScope:      scoped,
+		ChainTVLHistory: chainHistory,
 	}
 }
 
 // MapToCustomOutputProtocol mirrors MapToOutputProtocol but includes category
-// and chains provided by custom-data files.
-func MapToCustomOutputProtocol(protocol models.MergedProtocol, tvl *api.ProtocolTVLResponse, attrs CustomDataAttributes) models.CustomDataOutputEntry {
+// and chains provided by custom-data files. The merged TVL history is kept as
+// is since custom data is authoritative; only the per-chain history is limited
+// to the configured chains. Chain names are canonicalized by chains.
+func MapToCustomOutputProtocol(protocol models.MergedProtocol, tvl *api.ProtocolTVLResponse, attrs CustomDataAttributes, chains *chainregistry.Registry) models.CustomDataOutputEntry {
 	history := make([]models.TVLHistoryItem, 0)
 	currentTVL := 0.0
 
-	if tvl != nil {
-		dateMap := make(map[string]models.TVLHistoryItem)
-		for _, point := range tvl.TVL {
-			dateStr := time.Unix(point.Date, 0).UTC().Format("2006-01-02")
-			existing, exists := dateMap[dateStr]
-			if !exists || point.Date > existing.Timestamp {
-				dateMap[dateStr] = models.TVLHistoryItem{
-					Date:      dateStr,
-					Timestamp: point.Date,
-					TVL:       point.TotalLiquidityUSD,
-				}
-			}
-		}
+	category := protocol.Category
+	if category == "" {
+		category = attrs.Category
+	}
+	protocolChains := protocol.Chains
+	scope := chainScope(protocol)
+	if len(protocolChains) == 0 {
+		protocolChains = attrs.Chains
+		scope = attrs.Chains
+	}
+	protocolChains = chains.CanonicalList(protocolChains)
+	scope = chains.CanonicalList(scope)
+	url := protocol.URL
+	if url == "" {
+		url = attrs.URL
+	}
 
-		history = make([]models.TVLHistoryItem, 0, len(dateMap))
-		for _, item := range dateMap {
-			history = append(history, item)
-		}
-		sort.Slice(history, func(i, j int) bool {
-			return history[i].Timestamp < history[j].Timestamp
-		})
+	var chainHistory map[string][]models.TVLHistoryItem
+	if tvl != nil {
+		history = dedupeTVLPoints(tvl.TVL)
 
 		if len(history) > 0 {
 			currentTVL = history[len(history)-1].TVL
 		}
 
+		chainHistory = chainHistories(tvl, scope, chains)
+
 		if protocol.Name == "" && tvl.Name != "" {
 			protocol.Name = tvl.Name
 		}
 	}
 
-	category := protocol.Category
-	if category == "" {
-		category = attrs.Category
-	}
-	chains := protocol.Chains
-	if len(chains) == 0 {
-		chains = attrs.Chains
-	}
-	url := protocol.URL
-	if url == "" {
-		url = attrs.URL
-	}
-
 	return models.CustomDataOutputEntry{
 		Name:            protocol.Name,
 		Slug:            protocol.Slug,
@@ -133,16 +117,17 @@ func MapToCustomOutputProtocol(protocol models.MergedProtocol, tvl *api.Protocol
 		GitHubProof:     protocol.GitHubProof,
 		IsDefillama:     protocol.IsDefillama,
 		Category:        category,
-		Chains:          chains,
+		Chains:          protocolChains,
 		CurrentTVL:      currentTVL,
 		TVLHistory:      history,
+		ChainTVLHistory: chainHistory,
 	}
 }
 
 // GenerateTVLOutput builds the root tvl-data.json document from merged
 // protocols and their associated TVL responses. The protocols map is keyed by
 // slug to satisfy AC3.
-func GenerateTVLOutput(protocols []models.MergedProtocol, tvlData map[string]*api.ProtocolTVLResponse) *models.TVLOutput {
+func GenerateTVLOutput(protocols []models.MergedProtocol, tvlData map[string]*api.ProtocolTVLResponse, chains *chainregistry.Registry) *models.TVLOutput {
 	result := &models.TVLOutput{
 		Version:   "1.0.0",
 		Metadata:  models.TVLOutputMetadata{},
@@ -154,7 +139,7 @@ func GenerateTVLOutput(protocols []models.MergedProtocol, tvlData map[string]*ap
 			continue
 		}
 
-		mapped := MapToOutputProtocol(p, tvlData[p.Slug])
+		mapped := MapToOutputProtocol(p, tvlData[p.Slug], chains)
 		result.Protocols[p.Slug] = mapped
 		result.Metadata.ProtocolCount++
 		if p.Source == "custom" {
@@ -170,7 +155,7 @@ func GenerateTVLOutput(protocols []models.MergedProtocol, tvlData map[string]*ap
 // GenerateCustomDataOutput builds custom-data.json for protocols supplied via
 // custom-data files. Category/chains metadata from the custom-data file is
 // preserved when present.
-func GenerateCustomDataOutput(protocols []models.MergedProtocol, tvlData map[string]*api.ProtocolTVLResponse, attrs map[string]CustomDataAttributes) *models.CustomDataOutput {
+func GenerateCustomDataOutput(protocols []models.MergedProtocol, tvlData map[string]*api.ProtocolTVLResponse, attrs map[string]CustomDataAttributes, chains *chainregistry.Registry) *models.CustomDataOutput {
 	result := &models.CustomDataOutput{
 		Version:   "1.0.0",
 		Metadata:  models.CustomDataOutputMetadata{},
@@ -181,7 +166,7 @@ func GenerateCustomDataOutput(protocols []models.MergedProtocol, tvlData map[str
 		if p.Slug == "" {
 			continue
 		}
-		entry := MapToCustomOutputProtocol(p, tvlData[p.Slug], attrs[p.Slug])
+		entry := MapToCustomOutputProtocol(p, tvlData[p.Slug], attrs[p.Slug], chains)
 		result.Protocols[p.Slug] = entry
 		result.Metadata.ProtocolCount++
 	}
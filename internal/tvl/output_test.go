@@ -1,8 +1,10 @@
 package tvl
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,6 +12,7 @@ import (
 	"time"
 
 	"github.com/switchboard-xyz/defillama-extract/internal/api"
+	"github.com/switchboard-xyz/defillama-extract/internal/chainregistry"
 	"github.com/switchboard-xyz/defillama-extract/internal/models"
 )
 
@@ -31,7 +34,7 @@ func TestMapToOutputProtocol_CustomWithDate(t *testing.T) {
 		},
 	}
 
-	out := MapToOutputProtocol(merged, tvl)
+	out := MapToOutputProtocol(merged, tvl, nil)
 
 	if out.IntegrationDate == nil || *out.IntegrationDate != date {
 		t.Fatalf("expected integration_date %d, got %v", date, out.IntegrationDate)
@@ -67,7 +70,7 @@ func TestMapToOutputProtocol_CustomWithoutDate_NullJSON(t *testing.T) {
 		},
 	}
 
-	out := MapToOutputProtocol(merged, tvl)
+	out := MapToOutputProtocol(merged, tvl, nil)
 
 	if out.IntegrationDate != nil {
 		t.Fatalf("expected integration_date to be nil, got %v", out.IntegrationDate)
@@ -92,7 +95,7 @@ func TestMapToOutputProtocol_AutoProtocolIntegrationDateNil(t *testing.T) {
 		SimpleTVSRatio: 1.0,
 	}
 
-	out := MapToOutputProtocol(merged, nil)
+	out := MapToOutputProtocol(merged, nil, nil)
 
 	if out.IntegrationDate != nil {
 		t.Fatalf("expected nil integration_date for auto protocol, got %v", out.IntegrationDate)
@@ -113,7 +116,7 @@ func TestMapToOutputProtocol_FullHistoryPreserved(t *testing.T) {
 		},
 	}
 
-	out := MapToOutputProtocol(merged, tvl)
+	out := MapToOutputProtocol(merged, tvl, nil)
 
 	if len(out.TVLHistory) != 2 {
 		t.Fatalf("expected 2 history items, got %d", len(out.TVLHistory))
@@ -140,7 +143,7 @@ func TestGenerateTVLOutput_MixedProtocols(t *testing.T) {
 		"auto-1": {Name: "Auto One", TVL: []api.TVLDataPoint{{Date: 1, TotalLiquidityUSD: 10}}},
 	}
 
-	out := GenerateTVLOutput(protocols, tvlData)
+	out := GenerateTVLOutput(protocols, tvlData, nil)
 
 	if out.Version != "1.0.0" {
 		t.Fatalf("version mismatch: %s", out.Version)
@@ -168,7 +171,7 @@ func TestGenerateTVLOutput_MixedProtocols(t *testing.T) {
 }
 
 func TestGenerateTVLOutput_EmptyProtocols(t *testing.T) {
-	out := GenerateTVLOutput(nil, nil)
+	out := GenerateTVLOutput(nil, nil, nil)
 
 	if out.Metadata.ProtocolCount != 0 || out.Metadata.CustomProtocolCount != 0 {
 		t.Fatalf("expected zero counts, got %+v", out.Metadata)
@@ -251,7 +254,7 @@ func TestMapToOutputProtocol_DedupesSameDateEntries(t *testing.T) {
 		},
 	}
 
-	out := MapToOutputProtocol(merged, tvl)
+	out := MapToOutputProtocol(merged, tvl, nil)
 
 	// Should have only 2 entries (one per unique date)
 	if len(out.TVLHistory) != 2 {
@@ -284,3 +287,127 @@ func TestMapToOutputProtocol_DedupesSameDateEntries(t *testing.T) {
 		t.Fatalf("expected CurrentTVL 250, got %f", out.CurrentTVL)
 	}
 }
+
+func multichainTVL() *api.ProtocolTVLResponse {
+	return &api.ProtocolTVLResponse{
+		Name: "Multichain",
+		TVL: []api.TVLDataPoint{
+			{Date: 1704067200, TotalLiquidityUSD: 300},
+			{Date: 1704153600, TotalLiquidityUSD: 330},
+		},
+		ChainTvls: map[string]api.ChainTVL{
+			"Solana": {TVL: []api.TVLDataPoint{
+				{Date: 1704067200, TotalLiquidityUSD: 100},
+				{Date: 1704153600, TotalLiquidityUSD: 110},
+			}},
+			"Ethereum": {TVL: []api.TVLDataPoint{
+				{Date: 1704067200, TotalLiquidityUSD: 200},
+				{Date: 1704153600, TotalLiquidityUSD: 220},
+			}},
+			"Solana-borrowed": {TVL: []api.TVLDataPoint{{Date: 1704153600, TotalLiquidityUSD: 50}}},
+			"borrowed":        {TVL: []api.TVLDataPoint{{Date: 1704153600, TotalLiquidityUSD: 50}}},
+		},
+	}
+}
+
+func TestMapToOutputProtocol_ChainHistory(t *testing.T) {
+	merged := models.MergedProtocol{Slug: "multi", Source: "auto", Chains: []string{"Solana", "Ethereum"}}
+
+	out := MapToOutputProtocol(merged, multichainTVL(), nil)
+
+	if len(out.ChainTVLHistory) != 2 {
+		t.Fatalf("expected Solana and Ethereum histories only, got %v", out.ChainTVLHistory)
+	}
+	if got := out.ChainTVLHistory["Ethereum"]; len(got) != 2 || got[1].TVL != 220 {
+		t.Fatalf("unexpected Ethereum history: %+v", got)
+	}
+	if out.CurrentTVL != 330 || out.ChainScope != nil {
+		t.Fatalf("expected unscoped totals for auto protocol, got current=%f scope=%v", out.CurrentTVL, out.ChainScope)
+	}
+}
+
+func TestMapToOutputProtocol_CustomChainsLimitOutput(t *testing.T) {
+	merged := models.MergedProtocol{Slug: "multi", Source: "custom", Chains: []string{"solana"}}
+
+	out := MapToOutputProtocol(merged, multichainTVL(), nil)
+
+	if _, ok := out.ChainTVLHistory["Ethereum"]; ok || len(out.ChainTVLHistory) != 1 {
+		t.Fatalf("expected only Solana history, got %v", out.ChainTVLHistory)
+	}
+	if out.CurrentTVL != 110 {
+		t.Fatalf("expected current TVL limited to Solana (110), got %f", out.CurrentTVL)
+	}
+	if len(out.TVLHistory) != 2 || out.TVLHistory[0].TVL != 100 {
+		t.Fatalf("expected Solana-only TVL history, got %+v", out.TVLHistory)
+	}
+	if len(out.ChainScope) != 1 || out.ChainScope[0] != "solana" {
+		t.Fatalf("expected chain_scope [solana], got %v", out.ChainScope)
+	}
+
+	// Without a matching chain series the scoped totals are empty rather than
+	// the whole protocol's.
+	noChains := &api.ProtocolTVLResponse{TVL: []api.TVLDataPoint{{Date: 1704067200, TotalLiquidityUSD: 300}}}
+	out = MapToOutputProtocol(merged, noChains, nil)
+	if out.CurrentTVL != 0 || len(out.TVLHistory) != 0 || out.TVLHistory == nil || len(out.ChainScope) != 1 {
+		t.Fatalf("expected empty scoped output, got current=%f history=%v scope=%v", out.CurrentTVL, out.TVLHistory, out.ChainScope)
+	}
+	otherChains := MapToOutputProtocol(models.MergedProtocol{Slug: "multi", Source: "custom", Chains: []string{"Aptos"}}, multichainTVL(), nil)
+	if otherChains.CurrentTVL != 0 || len(otherChains.TVLHistory) != 0 || otherChains.ChainTVLHistory != nil {
+		t.Fatalf("expected empty output for unmatched scope, got %+v", otherChains)
+	}
+}
+
+func TestLogUnmatchedChainScopes(t *testing.T) {
+	var buf bytes.Buffer
+	output := GenerateTVLOutput([]models.MergedProtocol{
+		{Slug: "scoped", Source: "custom", Chains: []string{"Aptos"}},
+		{Slug: "matched", Source: "custom", Chains: []string{"Solana"}},
+		{Slug: "auto", Source: "auto", Chains: []string{"Aptos"}},
+	}, map[string]*api.ProtocolTVLResponse{
+		"scoped":  multichainTVL(),
+		"matched": multichainTVL(),
+		"auto":    multichainTVL(),
+	}, nil)
+
+	logUnmatchedChainScopes(slog.New(slog.NewTextHandler(&buf, nil)), output)
+
+	if got := strings.Count(buf.String(), "tvl_chain_scope_unmatched"); got != 1 || !strings.Contains(buf.String(), "protocol=scoped chain_scope=[Aptos]") {
+		t.Fatalf("expected one warning for the unmatched scope, got %s", buf.String())
+	}
+}
+
+func TestMapToOutputProtocol_CanonicalChainNames(t *testing.T) {
+	tvl := &api.ProtocolTVLResponse{
+		ChainTvls: map[string]api.ChainTVL{
+			"BSC":     {TVL: []api.TVLDataPoint{{Date: 1704067200, TotalLiquidityUSD: 100}}},
+			"Binance": {TVL: []api.TVLDataPoint{{Date: 1704067200, TotalLiquidityUSD: 40}}},
+			"Solana":  {TVL: []api.TVLDataPoint{{Date: 1704067200, TotalLiquidityUSD: 500}}},
+		},
+	}
+	merged := models.MergedProtocol{Slug: "multi", Source: "custom", Chains: []string{"bnb chain"}}
+
+	out := MapToOutputProtocol(merged, tvl, chainregistry.New(nil, nil))
+
+	if len(out.ChainTVLHistory) != 1 {
+		t.Fatalf("expected BSC history only, got %v", out.ChainTVLHistory)
+	}
+	if got := out.ChainTVLHistory["BSC"]; len(got) != 1 || got[0].TVL != 140 {
+		t.Fatalf("expected BSC aliases summed to 140, got %+v", got)
+	}
+	if len(out.ChainScope) != 1 || out.ChainScope[0] != "BSC" || out.CurrentTVL != 140 {
+		t.Fatalf("expected canonical scope [BSC] and 140 TVL, got scope=%v current=%f", out.ChainScope, out.CurrentTVL)
+	}
+}
+
+func TestMapToCustomOutputProtocol_ChainHistoryLimitedToAttrChains(t *testing.T) {
+	merged := models.MergedProtocol{Slug: "multi", Source: "custom-data"}
+
+	out := MapToCustomOutputProtocol(merged, multichainTVL(), CustomDataAttributes{Chains: []string{"Ethereum"}}, nil)
+
+	if len(out.ChainTVLHistory) != 1 || len(out.ChainTVLHistory["Ethereum"]) != 2 {
+		t.Fatalf("expected Ethereum-only chain history, got %v", out.ChainTVLHistory)
+	}
+	if out.CurrentTVL != 330 {
+		t.Fatalf("expected merged TVL history to stay authoritative, got %f", out.CurrentTVL)
+	}
+}
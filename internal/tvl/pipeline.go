@@ -2,10 +2,14 @@ package tvl
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"time"
 
+	"github.com/switchboard-xyz/defillama-extract/internal/aggregator"
 	"github.com/switchboard-xyz/defillama-extract/internal/api"
+	"github.com/switchboard-xyz/defillama-extract/internal/chainregistry"
 	"github.com/switchboard-xyz/defillama-extract/internal/config"
 	"github.com/switchboard-xyz/defillama-extract/internal/models"
 )
@@ -18,6 +22,8 @@ type RunnerDeps struct {
 	CustomDataLoader *CustomDataLoader
 	OutputDir        string
 	Now              func() time.Time
+	// Chains canonicalizes chain names; nil builds one from cfg.Chains.
+	Chains *chainregistry.Registry
 }
 
 // RunTVLPipeline orchestrates the TVL pipeline end-to-end. It is designed to
@@ -57,7 +63,14 @@ func RunTVLPipeline(ctx context.Context, cfg *config.Config, protocols []api.Pro
 
 	client := deps.Client
 	if client == nil {
-		client = api.NewClient(&cfg.API, tvlLogger)
+		source, err := api.NewSource(cfg.Source, &cfg.API, []string{cfg.Oracle.Name}, tvlLogger)
+		if err != nil {
+			return fmt.Errorf("create tvl data source: %w", err)
+		}
+		if closer, ok := source.(io.Closer); ok {
+			defer closer.Close()
+		}
+		client = source
 	}
 
 	loader := deps.Loader
@@ -65,6 +78,11 @@ func RunTVLPipeline(ctx context.Context, cfg *config.Config, protocols []api.Pro
 		loader = NewCustomLoader(cfg.TVL.CustomProtocolsPath, tvlLogger)
 	}
 
+	chains := deps.Chains
+	if chains == nil {
+		chains = chainregistry.New(cfg.Chains.Registry, tvlLogger)
+	}
+
 	customDataLoader := deps.CustomDataLoader
 	if customDataLoader == nil {
 		customDataLoader = NewCustomDataLoader(cfg.TVL.CustomDataPath, tvlLogger)
@@ -148,7 +166,7 @@ func RunTVLPipeline(ctx context.Context, cfg *config.Config, protocols []api.Pro
 		"fetch_targets", len(fetchSlugs),
 	)
 
-	tvlData, fetchErr := FetchAllTVL(ctx, client, fetchSlugs, tvlLogger)
+	tvlData, fetchErr := FetchAllTVLConcurrent(ctx, client, fetchSlugs, cfg.TVL.Concurrency, tvlLogger)
 	if fetchErr != nil {
 		tvlLogger.Warn("tvl_fetch_errors_present", "error", fetchErr)
 	}
@@ -196,8 +214,18 @@ func RunTVLPipeline(ctx context.Context, cfg *config.Config, protocols []api.Pro
 		}
 	}
 
-	output := GenerateTVLOutput(tvlProtocols, mergedTVLData)
-	customOutput := GenerateCustomDataOutput(customProtocols, mergedTVLData, customDataResult.Metadata)
+	output := GenerateTVLOutput(tvlProtocols, mergedTVLData, chains)
+	logUnmatchedChainScopes(tvlLogger, output)
+	customOutput := GenerateCustomDataOutput(customProtocols, mergedTVLData, customDataResult.Metadata, chains)
+	categories := aggregator.NewCategoryTaxonomy(cfg.Categories.Mapping, cfg.Categories.Overrides)
+	applyTVLCategories(output, tvlProtocols, categories)
+	applyCustomDataCategories(customOutput, categories)
+	if unmapped := mergeUnmapped(output.Metadata.UnmappedCategories, customOutput.Metadata.UnmappedCategories); len(unmapped) > 0 {
+		tvlLogger.Warn("categories_unmapped", "count", len(unmapped), "categories", unmapped)
+	}
+	if names := chains.Unrecognized(); len(names) > 0 {
+		tvlLogger.Warn("chains_unrecognized", "count", len(names), "chains", names)
+	}
 
 	if dryRun {
 		tvlLogger.Info("tvl_dry_run_skip_writes_and_state")